@@ -1,5 +1,7 @@
 package host
 
+import "context"
+
 const (
 	// Header_ContentType = "Content-Type"
 	Seperator_Route = "_"
@@ -17,4 +19,6 @@ const (
 
 type (
 	RequestHandler func(ctx IHttpContext)
+	// ShutdownHook 关闭钩子，ctx的截止时间为关闭超时时间
+	ShutdownHook func(ctx context.Context) error
 )
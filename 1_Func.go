@@ -10,8 +10,6 @@ import (
 	oauth2core "github.com/Lukiya/oauth2go/core"
 	"github.com/syncfuture/go/sconfig"
 	"github.com/syncfuture/go/sconv"
-	"github.com/syncfuture/go/sid"
-	"github.com/syncfuture/go/slog"
 	"github.com/syncfuture/go/srand"
 	"github.com/syncfuture/go/ssecurity"
 	"github.com/syncfuture/go/u"
@@ -95,13 +93,10 @@ func RedirectAuthorizeEndpoint(ctx IHttpContext, oauthOptions *OAuthOptions, ret
 // 	return _idGenerator.GenerateString()
 // }
 
+// HandleErr 立即将err写为application/problem+json，推荐使用ctx.Error(err)交给ErrorHandler处理
 func HandleErr(err error, ctx IHttpContext) bool {
 	if err != nil {
-		ctx.SetStatusCode(http.StatusInternalServerError)
-		errID := sid.GenerateID()
-		slog.Errorf("[%s] %+v", errID, err)
-		ctx.WriteJsonBytes(u.StrToBytes(`{"err":"` + errID + `"}`))
-
+		WriteProblem(ctx, ToProblem(err))
 		return true
 	}
 	return false
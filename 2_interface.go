@@ -1,10 +1,15 @@
 package host
 
 import (
+	"context"
+	"crypto/x509"
 	"embed"
 	"io"
+	"io/fs"
 	"mime/multipart"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/syncfuture/go/sconfig"
 	"github.com/syncfuture/go/sredis"
@@ -23,6 +28,11 @@ type (
 
 	IHost interface {
 		Run() error
+		// RunContext 运行直到ctx结束或收到SIGINT/SIGTERM，然后优雅关闭
+		RunContext(ctx context.Context) error
+		// Shutdown 停止接收新连接，等待处理中的请求完成（最多timeout），然后执行关闭钩子
+		Shutdown(timeout time.Duration) error
+		AddShutdownHooks(hooks ...ShutdownHook)
 	}
 
 	IBaseHost interface {
@@ -45,6 +55,8 @@ type (
 		OPTIONS(path string, handlers ...RequestHandler)
 		ServeFiles(webPath, physiblePath string)
 		ServeEmbedFiles(webPath, physiblePath string, emd embed.FS)
+		// ServeStatic 提供fsys中的文件，支持SPA回退、ETag、Cache-Control和Range，按Static配置，webPath需以/{filepath:*}结尾
+		ServeStatic(webPath string, fsys fs.FS)
 		AddGlobalPreHandlers(toTail bool, handlers ...RequestHandler)
 		AppendGlobalSufHandlers(toTail bool, handlers ...RequestHandler)
 		AddActionGroups(actionGroups ...*ActionGroup)
@@ -52,6 +64,39 @@ type (
 		AddAction(route, routeKey string, handlers ...RequestHandler)
 		RegisterActionsToRouter(action *Action)
 		NewFSHandler(root string, stripSlashes int) RequestHandler
+		// Group 创建带路径前缀和中间件的路由组
+		Group(prefix string, handlers ...RequestHandler) IRouteGroup
+		// WS 注册WebSocket端点，全局前置中间件和handlers在升级前执行，未调用Next则不升级
+		WS(path string, handler WebSocketHandler, handlers ...RequestHandler)
+		// AddHealthCheck 添加就绪检查，未配置Health时忽略
+		AddHealthCheck(name string, check HealthCheckFunc)
+		// OverrideCORS 覆盖路径前缀下的跨域策略，options为nil时禁用
+		OverrideCORS(pathPrefix string, options *CORSOptions)
+		// UseListener 在ln上提供服务而不监听ListenAddr，需在Run前调用
+		UseListener(ln net.Listener)
+		// Routes 已注册的路由，按路径和方法排序
+		Routes() []*RouteInfo
+		// RegisterHandler 注册可在路由配置中按名称引用的Handler，需在Run前调用
+		RegisterHandler(name string, handler RequestHandler)
+		// RegisterMiddleware 注册可在路由配置中按名称引用的中间件，需在Run前调用
+		RegisterMiddleware(name string, handler RequestHandler)
+	}
+
+	IRouteGroup interface {
+		GetPrefix() string
+		Group(prefix string, handlers ...RequestHandler) IRouteGroup
+		GET(path string, handlers ...RequestHandler)
+		POST(path string, handlers ...RequestHandler)
+		PUT(path string, handlers ...RequestHandler)
+		PATCH(path string, handlers ...RequestHandler)
+		DELETE(path string, handlers ...RequestHandler)
+		OPTIONS(path string, handlers ...RequestHandler)
+		WS(path string, handler WebSocketHandler, handlers ...RequestHandler)
+		AddActionGroups(actionGroups ...*ActionGroup)
+		AddActions(actions ...*Action)
+		AddAction(route, routeKey string, handlers ...RequestHandler)
+		// CORS 覆盖组前缀下的跨域策略，options为nil时禁用
+		CORS(options *CORSOptions)
 	}
 
 	IHttpContext interface {
@@ -64,6 +109,16 @@ type (
 		GetItemInt64(key string) int64
 
 		GetRouteKey() string
+		// Context 请求的context，超时、服务关闭或客户端断开时取消，用于发起出站调用，请求结束后不应再使用
+		// net/http后端在客户端断开时立即取消；fasthttp后端没有断开通知，在Linux、macOS和BSD上定期检查连接，
+		// 最多延迟约200毫秒取消，其它平台和内存中的连接只在服务关闭时取消
+		Context() context.Context
+		// SetContext 替换Context()返回的context，如TimeoutHandler设置截止时间、TracingHandler加入span
+		SetContext(c context.Context)
+		// GetCSPNonce 当前请求的CSP nonce，未启用SecurityHeaders.ContentSecurityPolicy的{nonce}时为空
+		GetCSPNonce() string
+		// GetPeerCertificate 双向TLS时客户端的证书，未启用TLS或客户端未提供证书时为nil
+		GetPeerCertificate() *x509.Certificate
 
 		SetCookieKV(key, value string, options ...func(*http.Cookie))
 		GetCookieString(key string) string
@@ -93,11 +148,21 @@ type (
 		ReadQuery(objPtr interface{}) error
 		ReadForm(objPtr interface{}) error
 		ReadFormMap() (map[string][]string, error)
+		// Bind 合并路由参数、Query和Body到objPtr并按validate标签校验，失败返回*ValidationError
+		Bind(objPtr interface{}) error
 
 		GetHeader(key string) string
 		SetHeader(key, value string)
 
 		SetStatusCode(statusCode int)
+		GetStatusCode() int
+		GetResponseHeader(key string) string
+		// GetResponseBody 已写入的响应Body，IsBodyStream为true时返回nil
+		GetResponseBody() []byte
+		// SetResponseBody 替换已写入的响应Body
+		SetResponseBody(body []byte)
+		// IsBodyStream 响应是否为流（SSE、WebSocket、CopyBodyAndStatusCode等）
+		IsBodyStream() bool
 		SetContentType(cType string)
 		WriteString(body string) (int, error)
 		WriteBytes(body []byte) (int, error)
@@ -105,10 +170,18 @@ type (
 
 		RequestURL() string
 		RequestPath() string
+		RequestMethod() string
+		// RequestURI 请求行中的URI，包含Query
+		RequestURI() string
+		RequestProtocol() string
 		GetRemoteIP() string
 
 		UserAgent() string
 
+		// SSE 以Server-Sent Events响应，handler在处理链执行完毕后运行
+		SSE(handler SSEHandler, options ...func(*SSEOptions))
+		// Error 记录错误，由ErrorHandler写为application/problem+json，调用后不应再写入Body
+		Error(err error)
 		Redirect(url string, statusCode int)
 		CopyBodyAndStatusCode(resp *http.Response)
 
@@ -1,8 +1,12 @@
 package host
 
 import (
+	"net"
+	"slices"
+
 	"github.com/gorilla/securecookie"
 	"github.com/syncfuture/go/sconfig"
+	"github.com/syncfuture/go/serr"
 	"github.com/syncfuture/go/slog"
 	"github.com/syncfuture/go/sredis"
 	"github.com/syncfuture/go/ssecurity"
@@ -82,12 +86,49 @@ func (x BaseHost) GetRouteProvider() ssecurity.IRouteProvider {
 
 type BaseWebHost struct {
 	// BaseHost
+	Lifecycle
 	ListenAddr        string
+	TLS               *TLSOptions
 	CORS              *CORSOptions
+	CORSPolicies      *CORSPolicies `json:"-"`
+	CSRF              *CSRFOptions
+	CSRFProtector     *CSRFProtector `json:"-"`
+	Compression       *CompressionOptions
+	SecurityHeaders   *SecurityHeadersOptions
+	AccessLog         *AccessLogOptions
+	Metrics           *MetricsOptions
+	Tracing           *TracingOptions
+	Health            *HealthOptions
+	RouteTable        *RouteTableOptions
+	OpenAPI           *OpenAPIOptions
+	RouteConfigs      []*RouteConfig `json:"Routes,omitempty"`
+	Static            *StaticOptions
+	Timeout           *TimeoutOptions
+	HealthChecker     *HealthChecker `json:"-"`
+	RateLimit         *RateLimitOptions
+	RateLimitStore    IRateLimitStore `json:"-"`
+	RateLimiter       *RateLimiter    `json:"-"`
 	CookieProtector   *securecookie.SecureCookie
 	GlobalPreHandlers []RequestHandler
 	GlobalSufHandlers []RequestHandler
 	Actions           map[string]*Action
+	redisConfig       *sredis.RedisConfig
+	cookieEncryptor   ssecurity.ICookieEncryptor
+	routeProvider     ssecurity.IRouteProvider
+	namedHandlers     map[string]RequestHandler
+	namedMiddleware   map[string]RequestHandler
+	routes            routeRegistry
+	listener          net.Listener
+}
+
+// UseRedisConfig 提供BaseHost.RedisConfig给需要Redis的组件（如限流存储），需在构建前调用
+func (x *BaseWebHost) UseRedisConfig(config *sredis.RedisConfig) {
+	x.redisConfig = config
+}
+
+// UseCookieEncryptor 提供后端使用的CookieEncryptor给需要加密Cookie的组件（如double_submit模式的CSRF），需在构建前调用
+func (x *BaseWebHost) UseCookieEncryptor(encryptor ssecurity.ICookieEncryptor) {
+	x.cookieEncryptor = encryptor
 }
 
 func (x *BaseWebHost) BuildBaseWebHost() {
@@ -96,6 +137,135 @@ func (x *BaseWebHost) BuildBaseWebHost() {
 	}
 
 	x.Actions = make(map[string]*Action)
+	x.RegisterMiddleware(Middleware_BindError, BindErrorHandler)
+	x.RegisterMiddleware(Middleware_JsonContentType, JsonConentTypeHandler)
+
+	////////// 错误处理
+	x.AddGlobalPreHandlers(false, ErrorHandler)
+
+	////////// CORS，在错误处理和限流之间，以便错误响应也带有CORS响应头
+	// 总是注册，以便未配置全局CORS时也能按路径前缀启用
+	x.CORSPolicies = NewCORSPolicies(x.CORS)
+	x.AddGlobalPreHandlers(true, x.CORSPolicies.Handler)
+
+	////////// CSRF，session模式需启用Session，double_submit模式需配置CookieEncryptor
+	if x.CSRF != nil {
+		if x.CSRF.Mode == CSRFMode_DoubleSubmit && x.cookieEncryptor == nil {
+			slog.Fatal("csrf double_submit mode requires a CookieEncryptor")
+		}
+		x.CSRFProtector = NewCSRFProtector(x.CSRF)
+		x.AddGlobalPreHandlers(true, x.CSRFProtector.Handler)
+	}
+
+	////////// 安全响应头，在错误处理外层，以便错误响应也带有
+	if x.SecurityHeaders != nil {
+		x.AddGlobalPreHandlers(false, NewSecurityHeadersHandler(x.SecurityHeaders))
+	}
+
+	////////// 限流
+	if x.RateLimit != nil {
+		if x.RateLimitStore == nil {
+			switch x.RateLimit.Store {
+			case "", RateLimitStore_Memory:
+				x.RateLimitStore = NewMemoryRateLimitStore()
+			case RateLimitStore_Redis:
+				if x.redisConfig == nil {
+					slog.Fatal("redis config is required for redis rate limit store")
+				}
+				x.RateLimitStore = NewRedisRateLimitStore(x.redisConfig)
+			default:
+				slog.Fatal("unsupported rate limit store: " + x.RateLimit.Store)
+			}
+		}
+		// 默认规则在BuildHandlerChain中插入到每个路由的最后一个Handler之前，以便按认证后的用户限流
+		x.RateLimiter = NewRateLimiter(x.RateLimit, x.RateLimitStore)
+	}
+
+	////////// 压缩，在最外层以便压缩错误响应
+	if x.Compression != nil {
+		x.AddGlobalPreHandlers(false, NewCompressionHandler(x.Compression))
+	}
+
+	////////// 访问日志，记录压缩后的大小
+	if x.AccessLog != nil {
+		x.AddGlobalPreHandlers(false, NewAccessLogHandler(x.AccessLog))
+	}
+
+	////////// 指标
+	if x.Metrics != nil {
+		x.AddGlobalPreHandlers(false, MetricsHandler)
+	}
+
+	////////// 请求ID，最先执行以便之后的中间件和日志都能取到
+	x.AddGlobalPreHandlers(false, RequestIDHandler)
+
+	////////// 链路追踪，请求span在BuildHandlerChain中包在最外层
+	x.buildTracing()
+
+	////////// 健康检查
+	if x.Health != nil {
+		x.HealthChecker = NewHealthChecker(x.Health, x.IsShuttingDown)
+		if x.redisConfig != nil {
+			x.HealthChecker.AddCheck("redis", RedisHealthCheck(sredis.NewClient(x.redisConfig)))
+		}
+	}
+}
+
+// OverrideCORS 覆盖路径前缀下的跨域策略，options为nil时禁用，需在构建后调用
+func (x *BaseWebHost) OverrideCORS(pathPrefix string, options *CORSOptions) {
+	x.CORSPolicies.Override(pathPrefix, options)
+}
+
+// AddHealthCheck 添加就绪检查，未配置Health时忽略
+func (x *BaseWebHost) AddHealthCheck(name string, check HealthCheckFunc) {
+	if x.HealthChecker != nil {
+		x.HealthChecker.AddCheck(name, check)
+	}
+}
+
+// BuildHandlerChain 组合全局前置中间件、handlers和全局后置中间件，启用Tracing时在最外层创建请求span
+// 配置了默认限流规则时，限流中间件插入到handlers的最后一个Handler之前，在全局和路由自身的认证等中间件之后执行
+func (x *BaseWebHost) BuildHandlerChain(handlers []RequestHandler) []RequestHandler {
+	if x.RateLimiter != nil && x.RateLimit != nil && x.RateLimit.Limit > 0 && len(handlers) > 0 {
+		handlers = slices.Insert(slices.Clone(handlers), len(handlers)-1, x.RateLimiter.Handler)
+	}
+	if len(x.GlobalPreHandlers) > 0 {
+		handlers = CombineHandlers(x.GlobalPreHandlers, handlers)
+	}
+	if len(x.GlobalSufHandlers) > 0 {
+		handlers = append(handlers, x.GlobalSufHandlers...)
+	}
+	if x.Tracing != nil {
+		handlers = x.Tracing.WrapHandlers(handlers)
+	}
+	return handlers
+}
+
+// UseListener 在ln上提供服务而不监听ListenAddr，如测试中的fasthttputil.InmemoryListener，需在Run前调用
+func (x *BaseWebHost) UseListener(ln net.Listener) {
+	x.listener = ln
+}
+
+// Listen 监听ListenAddr，已通过UseListener提供监听器时返回该监听器
+func (x *BaseWebHost) Listen(network string) (net.Listener, error) {
+	if x.listener != nil {
+		return x.listener, nil
+	}
+	ln, err := net.Listen(network, x.ListenAddr)
+	return ln, serr.WithStack(err)
+}
+
+// StartMetricsServer 配置了Metrics.ListenAddr时启动单独的指标服务，宿主关闭时一并关闭
+func (x *BaseWebHost) StartMetricsServer() error {
+	if x.Metrics == nil || x.Metrics.ListenAddr == "" {
+		return nil
+	}
+	hook, err := x.Metrics.StartServer()
+	if err != nil {
+		return err
+	}
+	x.AddShutdownHooks(hook)
+	return nil
 }
 
 // AddGlobalPreHandlers 添加全局前置中间件, toTail: 是否添加在已有全局前置中间件的尾部
@@ -120,20 +290,16 @@ func (x *BaseWebHost) AddActionGroups(actionGroups ...*ActionGroup) {
 	////////// 添加Actions
 	for _, actionGroup := range actionGroups {
 		for _, action := range actionGroup.Actions {
-			// 添加预先执行中间件
-			if len(actionGroup.PreHandlers) > 0 {
-				action.Handlers = append(actionGroup.PreHandlers, action.Handlers...)
+			// 添加路径前缀
+			if actionGroup.Prefix != "" {
+				method, path := SplitRoute(action.Route)
+				action.Route = method + JoinRoutePath(actionGroup.Prefix, path)
 			}
-			// 添加后执行中间件
-			if len(actionGroup.AfterHandlers) > 0 {
-				action.Handlers = append(action.Handlers, actionGroup.AfterHandlers...)
+			// 添加预先执行中间件和后执行中间件
+			if len(actionGroup.PreHandlers) > 0 || len(actionGroup.AfterHandlers) > 0 {
+				action.Handlers = CombineHandlers(actionGroup.PreHandlers, action.Handlers, actionGroup.AfterHandlers)
 			}
-
-			_, ok := x.Actions[action.Route]
-			if ok {
-				slog.Fatal("duplicated route found: " + action.Route)
-			}
-			x.Actions[action.Route] = action
+			x.addAction(action)
 		}
 	}
 }
@@ -141,22 +307,53 @@ func (x *BaseWebHost) AddActionGroups(actionGroups ...*ActionGroup) {
 func (x *BaseWebHost) AddActions(actions ...*Action) {
 	////////// 添加Actions
 	for _, action := range actions {
-		_, ok := x.Actions[action.Route]
-		if ok {
-			slog.Fatal("duplicated route found: " + action.Route)
-		}
-		x.Actions[action.Route] = action
+		x.addAction(action)
 	}
 }
 
 func (x *BaseWebHost) AddAction(route, routeKey string, handlers ...RequestHandler) {
 	////////// 添加Action
-	action := NewAction(route, routeKey, handlers...)
+	x.addAction(NewAction(route, routeKey, handlers...))
+}
+
+func (x *BaseWebHost) addAction(action *Action) {
+	if action.Doc != nil {
+		if err := CheckValidateTags(action.Doc.Request); err != nil {
+			slog.Fatal("route " + action.Route + ": " + err.Error())
+		}
+	}
+	x.applyRateLimit(action)
+	x.applyTimeout(action)
+	x.applyCSRF(action)
 	_, ok := x.Actions[action.Route]
 	if ok {
 		slog.Fatal("duplicated route found: " + action.Route)
 	}
 	x.Actions[action.Route] = action
+	x.RecordAction(action)
+}
+
+// applyRateLimit 将Action的限流中间件插入到最后一个Handler之前，以便在认证等中间件之后执行
+func (x *BaseWebHost) applyRateLimit(action *Action) {
+	if action.RateLimit == nil {
+		return
+	}
+	if x.RateLimiter == nil {
+		slog.Fatal("RateLimit options are required for route rate limit: " + action.Route)
+	}
+	if len(action.Handlers) == 0 {
+		slog.Fatal("handlers are missing for route rate limit: " + action.Route)
+	}
+
+	handler := x.RateLimiter.Override(action.RouteKey, action.RateLimit)
+	action.Handlers = slices.Insert(slices.Clone(action.Handlers), len(action.Handlers)-1, handler)
+}
+
+// applyCSRF Action.SkipCSRF时使其RouteKey不检查CSRF
+func (x *BaseWebHost) applyCSRF(action *Action) {
+	if action.SkipCSRF && x.CSRFProtector != nil {
+		x.CSRFProtector.Exempt(action.RouteKey)
+	}
 }
 
 type SecureCookieHost struct {
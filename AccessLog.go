@@ -0,0 +1,218 @@
+package host
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syncfuture/go/slog"
+	"github.com/syncfuture/go/u"
+)
+
+const (
+	AccessLogFormat_JSON     = "json"
+	AccessLogFormat_Logfmt   = "logfmt"
+	AccessLogFormat_Combined = "combined"
+
+	_combinedTimeLayout = "02/Jan/2006:15:04:05 -0700"
+)
+
+// AccessLogOptions 访问日志配置，宿主配置此项后自动添加访问日志中间件
+type AccessLogOptions struct {
+	// Format json(默认)、logfmt 或 combined(Apache combined)
+	Format string
+	// SampleRate 采样率(0,1]，默认1，慢请求和5xx总是记录
+	SampleRate float64
+	// ExcludePaths 不记录的路径，如健康检查，以*结尾表示前缀匹配
+	ExcludePaths []string
+	// SlowThresholdMs 耗时达到此值的请求以Warn级别记录，0不区分
+	SlowThresholdMs int
+	// Writer 指定时每行写入Writer，否则输出到slog，同一中间件的写入是串行的
+	Writer io.Writer `json:"-"`
+}
+
+// AccessLogEntry 一条访问日志
+type AccessLogEntry struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	RouteKey  string    `json:"route,omitempty"`
+	Status    int       `json:"status"`
+	Bytes     int       `json:"bytes"` // -1表示流式响应，大小未知
+	LatencyMs float64   `json:"latency_ms"`
+	RemoteIP  string    `json:"ip"`
+	UserID    string    `json:"user,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	Slow      bool      `json:"slow,omitempty"`
+	// 以下仅combined格式使用
+	URI       string `json:"-"`
+	Protocol  string `json:"-"`
+	Referer   string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// NewAccessLogHandler 创建访问日志中间件，在处理链执行完毕后记录
+func NewAccessLogHandler(options *AccessLogOptions) RequestHandler {
+	o := *options
+	if o.SampleRate <= 0 || o.SampleRate > 1 {
+		o.SampleRate = 1
+	}
+	format := o.formatter()
+	slowThreshold := time.Duration(o.SlowThresholdMs) * time.Millisecond
+	var mu sync.Mutex // 请求并发执行，串行写入Writer
+
+	return func(ctx IHttpContext) {
+		path := ctx.RequestPath()
+		if o.isExcluded(path) {
+			ctx.Next()
+			return
+		}
+
+		start := time.Now()
+		ctx.Next()
+		latency := time.Since(start)
+
+		entry := &AccessLogEntry{
+			Time:      start,
+			Method:    ctx.RequestMethod(),
+			Path:      path,
+			RouteKey:  ctx.GetRouteKey(),
+			Status:    ctx.GetStatusCode(),
+			Bytes:     getResponseSize(ctx),
+			LatencyMs: float64(latency.Microseconds()) / 1000,
+			RemoteIP:  ctx.GetRemoteIP(),
+			UserID:    ctx.GetItemString(Ctx_UserID),
+			RequestID: GetRequestID(ctx),
+			Slow:      slowThreshold > 0 && latency >= slowThreshold,
+			URI:       ctx.RequestURI(),
+			Protocol:  ctx.RequestProtocol(),
+			Referer:   ctx.GetHeader("Referer"),
+			UserAgent: ctx.UserAgent(),
+		}
+
+		if !entry.Slow && entry.Status < http.StatusInternalServerError && o.SampleRate < 1 && rand.Float64() >= o.SampleRate {
+			return
+		}
+
+		line := format(entry)
+		switch {
+		case o.Writer != nil:
+			mu.Lock()
+			_, err := io.WriteString(o.Writer, line+"\n")
+			mu.Unlock()
+			GetLogger(ctx).LogError(err)
+		case entry.Slow:
+			slog.Warn(line)
+		default:
+			slog.Info(line)
+		}
+	}
+}
+
+func (x *AccessLogOptions) isExcluded(path string) bool {
+	return slices.ContainsFunc(x.ExcludePaths, func(p string) bool {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			return strings.HasPrefix(path, prefix)
+		}
+		return path == p
+	})
+}
+
+func (x *AccessLogOptions) formatter() func(*AccessLogEntry) string {
+	switch x.Format {
+	case "", AccessLogFormat_JSON:
+		return FormatAccessLogJSON
+	case AccessLogFormat_Logfmt:
+		return FormatAccessLogLogfmt
+	case AccessLogFormat_Combined:
+		return FormatAccessLogCombined
+	default:
+		slog.Fatal("unsupported access log format: " + x.Format)
+		return nil
+	}
+}
+
+// getResponseSize 流式响应有Content-Length时使用，否则返回-1
+func getResponseSize(ctx IHttpContext) int {
+	if !ctx.IsBodyStream() {
+		return len(ctx.GetResponseBody())
+	}
+	if size, err := strconv.Atoi(ctx.GetResponseHeader("Content-Length")); err == nil {
+		return size
+	}
+	return -1
+}
+
+func FormatAccessLogJSON(entry *AccessLogEntry) string {
+	data, err := json.Marshal(entry)
+	if u.LogError(err) {
+		return ""
+	}
+	return u.BytesToStr(data)
+}
+
+func FormatAccessLogLogfmt(entry *AccessLogEntry) string {
+	var b strings.Builder
+	writeLogfmt(&b, "time", entry.Time.Format(time.RFC3339Nano))
+	writeLogfmt(&b, "method", entry.Method)
+	writeLogfmt(&b, "path", entry.Path)
+	writeLogfmt(&b, "route", entry.RouteKey)
+	writeLogfmt(&b, "status", strconv.Itoa(entry.Status))
+	writeLogfmt(&b, "bytes", strconv.Itoa(entry.Bytes))
+	writeLogfmt(&b, "latency_ms", strconv.FormatFloat(entry.LatencyMs, 'f', -1, 64))
+	writeLogfmt(&b, "ip", entry.RemoteIP)
+	writeLogfmt(&b, "user", entry.UserID)
+	writeLogfmt(&b, "request_id", entry.RequestID)
+	if entry.Slow {
+		writeLogfmt(&b, "slow", "true")
+	}
+	return b.String()
+}
+
+func writeLogfmt(b *strings.Builder, key, value string) {
+	if value == "" {
+		return
+	}
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(key)
+	b.WriteByte('=')
+	if strings.ContainsAny(value, " \"=\t\r\n") {
+		value = strconv.Quote(value)
+	}
+	b.WriteString(value)
+}
+
+// FormatAccessLogCombined Apache combined格式: %h - %u [%t] "%r" %>s %b "%{Referer}i" "%{User-agent}i"
+func FormatAccessLogCombined(entry *AccessLogEntry) string {
+	bytes := "-"
+	if entry.Bytes > 0 {
+		bytes = strconv.Itoa(entry.Bytes)
+	}
+
+	var b strings.Builder
+	b.WriteString(orDash(entry.RemoteIP))
+	b.WriteString(" - ")
+	b.WriteString(orDash(entry.UserID))
+	b.WriteString(" [" + entry.Time.Format(_combinedTimeLayout) + "] ")
+	b.WriteString(strconv.Quote(entry.Method + " " + entry.URI + " " + entry.Protocol))
+	b.WriteString(" " + strconv.Itoa(entry.Status) + " " + bytes + " ")
+	b.WriteString(strconv.Quote(orDash(entry.Referer)))
+	b.WriteByte(' ')
+	b.WriteString(strconv.Quote(orDash(entry.UserAgent)))
+	return b.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+package host_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syncfuture/host"
+	"github.com/syncfuture/host/hosttest"
+)
+
+func TestAccessLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	handler := host.NewAccessLogHandler(&host.AccessLogOptions{
+		SampleRate:      1e-9, // 正常请求几乎都不记录
+		SlowThresholdMs: 20,
+		ExcludePaths:    []string{"/healthz"},
+		Writer:          &buf,
+	})
+	run := func(path string, h host.RequestHandler) {
+		ctx := hosttest.NewMockHttpContext(http.MethodGet, path, nil)
+		ctx.Items[host.Ctx_RouteKey] = "route" + path
+		ctx.Items[host.Ctx_RequestID] = "req" + path
+		ctx.Items[host.Ctx_UserID] = "u1"
+		ctx.Run(handler, h)
+	}
+
+	run("/ok", func(ctx host.IHttpContext) {
+		ctx.WriteString("ok")
+	})
+	assert.Empty(t, buf.String())
+
+	// 5xx和慢请求不受采样影响
+	run("/fail", func(ctx host.IHttpContext) {
+		ctx.SetStatusCode(http.StatusInternalServerError)
+		ctx.WriteString("boom")
+	})
+	run("/slow", func(ctx host.IHttpContext) {
+		time.Sleep(25 * time.Millisecond)
+	})
+	run("/healthz", func(ctx host.IHttpContext) {
+		ctx.SetStatusCode(http.StatusServiceUnavailable)
+	})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var fail, slow host.AccessLogEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &fail))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &slow))
+	assert.Equal(t, http.MethodGet, fail.Method)
+	assert.Equal(t, "/fail", fail.Path)
+	assert.Equal(t, "route/fail", fail.RouteKey)
+	assert.Equal(t, http.StatusInternalServerError, fail.Status)
+	assert.Equal(t, 4, fail.Bytes)
+	assert.Equal(t, "192.0.2.1", fail.RemoteIP)
+	assert.Equal(t, "u1", fail.UserID)
+	assert.Equal(t, "req/fail", fail.RequestID)
+	assert.False(t, fail.Slow)
+	assert.Equal(t, "/slow", slow.Path)
+	assert.True(t, slow.Slow)
+	assert.GreaterOrEqual(t, slow.LatencyMs, 20.0)
+
+	// 并发请求逐行写入Writer
+	buf.Reset()
+	handler = host.NewAccessLogHandler(&host.AccessLogOptions{Format: host.AccessLogFormat_Logfmt, Writer: &buf})
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hosttest.NewMockHttpContext(http.MethodGet, "/concurrent", nil).Run(handler)
+		}()
+	}
+	wg.Wait()
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 50)
+	for _, line := range lines {
+		assert.True(t, strings.HasPrefix(line, "time="), line)
+		assert.True(t, strings.HasSuffix(line, "ip=192.0.2.1"), line)
+	}
+}
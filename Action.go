@@ -7,6 +7,8 @@ import (
 )
 
 type ActionGroup struct {
+	// Prefix 路径前缀，添加时拼接到组内每个Action的Route路径前
+	Prefix        string
 	PreHandlers   []RequestHandler
 	Actions       []*Action
 	AfterHandlers []RequestHandler
@@ -19,6 +21,14 @@ type Action struct {
 	Controller string
 	Action     string
 	Handlers   []RequestHandler
+	// RateLimit 覆盖此路由的限流规则，需配置宿主的RateLimit
+	RateLimit *RateLimitRule
+	// TimeoutMs 覆盖宿主Timeout.DefaultMs，负数表示不限制
+	TimeoutMs int
+	// SkipCSRF 此路由不检查CSRF，如接收第三方回调的接口
+	SkipCSRF bool
+	// Doc 文档信息，配置宿主的OpenAPI后用于生成OpenAPI文档
+	Doc *ActionDoc
 }
 
 func NewActionGroup(preHandlers []RequestHandler, actions []*Action, afterHandlers ...RequestHandler) *ActionGroup {
@@ -29,13 +39,19 @@ func NewActionGroup(preHandlers []RequestHandler, actions []*Action, afterHandle
 	}
 }
 
+func NewPrefixedActionGroup(prefix string, preHandlers []RequestHandler, actions []*Action, afterHandlers ...RequestHandler) *ActionGroup {
+	r := NewActionGroup(preHandlers, actions, afterHandlers...)
+	r.Prefix = prefix
+	return r
+}
+
 func NewAction(route, routeKey string, handlers ...RequestHandler) *Action {
 	if len(handlers) == 0 {
 		slog.Fatal("handlers are missing")
 	}
 
 	var area, controller, action string
-	routeArray := strings.Split("_", routeKey)
+	routeArray := strings.Split(routeKey, "_")
 	if len(routeArray) == 3 {
 		area = routeArray[0]
 		controller = routeArray[1]
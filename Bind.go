@@ -0,0 +1,544 @@
+package host
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gorilla/schema"
+	"github.com/syncfuture/go/serr"
+	"github.com/syncfuture/go/shttp"
+)
+
+const (
+	Ctx_BindError = "binderror"
+	Tag_Path      = "path"
+	Tag_Validate  = "validate"
+)
+
+var (
+	_bindDecoder = schema.NewDecoder()
+	_structRules sync.Map // map[reflect.Type]structRules
+)
+
+func init() {
+	_bindDecoder.IgnoreUnknownKeys(true)
+}
+
+type (
+	// FieldError 单个字段的校验错误
+	FieldError struct {
+		Field   string `json:"field"`
+		Rule    string `json:"rule"`
+		Message string `json:"message"`
+	}
+
+	// ValidationError Bind失败时返回，由BindErrorHandler转为400响应
+	ValidationError struct {
+		Message string        `json:"message"`
+		Errors  []*FieldError `json:"errors,omitempty"`
+	}
+)
+
+func (x *ValidationError) Error() string {
+	if len(x.Errors) == 0 {
+		return x.Message
+	}
+
+	msgs := make([]string, 0, len(x.Errors))
+	for _, e := range x.Errors {
+		msgs = append(msgs, e.Field+" "+e.Message)
+	}
+	return x.Message + ": " + strings.Join(msgs, "; ")
+}
+
+// Bind 依次合并Query、Body（按Content-Type选择解码方式）、路由参数（path标签）到objPtr，然后按validate标签校验
+// 失败时返回*ValidationError，并记录到Ctx_BindError供BindErrorHandler使用
+func Bind(ctx IHttpContext, objPtr interface{}) error {
+	err := bind(ctx, objPtr)
+	if err != nil {
+		ctx.SetItem(Ctx_BindError, err)
+	}
+	return err
+}
+
+func bind(ctx IHttpContext, objPtr interface{}) error {
+	v := reflect.ValueOf(objPtr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return serr.New("objPtr must be a non-nil pointer to struct")
+	}
+
+	////////// Query
+	if err := ctx.ReadQuery(objPtr); err != nil {
+		return newDecodeError("invalid query", err)
+	}
+
+	////////// Body
+	if err := bindBody(ctx, objPtr); err != nil {
+		return err
+	}
+
+	////////// 路由参数
+	if err := bindPathParams(ctx, v.Elem()); err != nil {
+		return err
+	}
+
+	return Validate(objPtr)
+}
+
+func bindBody(ctx IHttpContext, objPtr interface{}) error {
+	cType := ctx.GetHeader("Content-Type")
+	if cType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(cType)
+	if err != nil {
+		return &ValidationError{Message: "invalid content type"}
+	}
+
+	switch {
+	case mediaType == shttp.CTYPE_JSON || strings.HasSuffix(mediaType, "+json"):
+		if len(ctx.GetBodyBytes()) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(ctx.GetBodyBytes(), objPtr); err != nil {
+			return newDecodeError("invalid json body", err)
+		}
+	case mediaType == "application/x-www-form-urlencoded":
+		if err := ctx.ReadForm(objPtr); err != nil {
+			return newDecodeError("invalid form body", err)
+		}
+	case mediaType == "multipart/form-data":
+		form, err := ctx.GetMultipartForm()
+		if err != nil {
+			return newDecodeError("invalid multipart body", err)
+		}
+		if err := _bindDecoder.Decode(objPtr, form.Value); err != nil {
+			return newDecodeError("invalid multipart body", err)
+		}
+	}
+	return nil
+}
+
+func bindPathParams(ctx IHttpContext, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := sf.Tag.Get(Tag_Path)
+		if name == "" || !sf.IsExported() {
+			continue
+		}
+		str := ctx.GetParamString(name)
+		if str == "" {
+			continue
+		}
+		if err := setFieldString(v.Field(i), str); err != nil {
+			return &ValidationError{
+				Message: "invalid path parameter",
+				Errors:  []*FieldError{{Field: name, Rule: "type", Message: err.Error()}},
+			}
+		}
+	}
+	return nil
+}
+
+func setFieldString(f reflect.Value, str string) error {
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			f.Set(reflect.New(f.Type().Elem()))
+		}
+		f = f.Elem()
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(str)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(str, 10, f.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(str, 10, f.Type().Bits())
+		if err != nil {
+			return errors.New("must be a non-negative integer")
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(str, f.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		f.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		f.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %s", f.Type())
+	}
+	return nil
+}
+
+func newDecodeError(msg string, err error) *ValidationError {
+	r := &ValidationError{Message: msg}
+
+	var multiErr schema.MultiError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &multiErr):
+		for key, e := range multiErr {
+			r.Errors = append(r.Errors, &FieldError{Field: key, Rule: "type", Message: errorCause(e)})
+		}
+		slices.SortFunc(r.Errors, func(a, b *FieldError) int {
+			return strings.Compare(a.Field, b.Field)
+		})
+	case errors.As(err, &typeErr):
+		r.Errors = append(r.Errors, &FieldError{Field: typeErr.Field, Rule: "type", Message: "must be " + typeErr.Type.String()})
+	}
+	return r
+}
+
+func errorCause(err error) string {
+	var convErr schema.ConversionError
+	if errors.As(err, &convErr) {
+		return "must be " + convErr.Type.String()
+	}
+	return err.Error()
+}
+
+// BindErrorHandler 中间件，将Bind失败记录的*ValidationError转为400 problem响应
+func BindErrorHandler(ctx IHttpContext) {
+	ctx.Next()
+
+	if err, ok := ctx.GetItem(Ctx_BindError).(*ValidationError); ok && ctx.GetItem(Ctx_Error) == nil {
+		WriteProblem(ctx, ToProblem(err))
+	}
+}
+
+////////// 校验
+
+type (
+	// validateRule 解析后的validate规则
+	validateRule struct {
+		name    string
+		arg     string
+		limit   float64        // min、max
+		options []string       // enum
+		regex   *regexp.Regexp // regex
+	}
+
+	// structRules 结构体各字段解析后的规则，下标与字段对应，按类型解析一次后缓存
+	structRules [][]*validateRule
+)
+
+// Validate 按validate标签校验结构体，支持: required, min=N, max=N, enum=a|b|c, regex=pattern（需放在最后）
+// required: 字符串、切片、Map不能为空，指针不能为nil，数值和bool字段的零值无法与未提供区分，必须使用指针类型
+// min、max: 数值比较值大小，字符串、切片、Map比较长度
+// enum、regex: 空字符串视为未提供，不校验（由required判断），数值的零值同样校验
+// 标签有误时返回普通错误而不是*ValidationError，可用CheckValidateTags在启动时检查
+func Validate(objPtr interface{}) error {
+	v := reflect.ValueOf(objPtr)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs []*FieldError
+	if err := validateStruct(v, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Message: "validation failed", Errors: errs}
+	}
+	return nil
+}
+
+// CheckValidateTags 解析obj的类型及其嵌套结构体的validate标签，标签有误时返回错误
+// 宿主注册Action时检查ActionDoc.Request，其它传给Bind的类型可在启动时调用检查
+func CheckValidateTags(obj interface{}) error {
+	t := reflect.TypeOf(obj)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	_, err := loadStructRules(t, make(map[reflect.Type]bool))
+	return err
+}
+
+// loadStructRules 取得结构体的规则，未缓存时解析并检查嵌套的结构体，visiting用于避免递归类型无限循环
+func loadStructRules(t reflect.Type, visiting map[reflect.Type]bool) (structRules, error) {
+	if v, ok := _structRules.Load(t); ok {
+		return v.(structRules), nil
+	}
+	visiting[t] = true
+
+	r := make(structRules, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		if tag := sf.Tag.Get(Tag_Validate); tag != "" && tag != "-" {
+			rules, err := parseRules(sf.Type, tag)
+			if err != nil {
+				return nil, serr.New(t.String() + "." + sf.Name + ": " + err.Error())
+			}
+			r[i] = rules
+		}
+		if nested := nestedStructType(sf.Type); nested != nil && !visiting[nested] {
+			if _, err := loadStructRules(nested, visiting); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	_structRules.Store(t, r)
+	return r, nil
+}
+
+// nestedStructType 字段本身或其元素为结构体时返回该结构体类型
+func nestedStructType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		return t
+	}
+	return nil
+}
+
+func parseRules(t reflect.Type, tag string) ([]*validateRule, error) {
+	isPtr := t.Kind() == reflect.Ptr
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var r []*validateRule
+	for _, str := range splitRules(tag) {
+		name, arg, _ := strings.Cut(str, "=")
+		rule := &validateRule{name: name, arg: arg}
+		switch name {
+		case "required":
+			if !isPtr && (t.Kind() == reflect.Bool || isNumberKind(t.Kind())) {
+				return nil, errors.New("rule 'required' can never be satisfied by a zero " + t.String() + ", use a pointer field")
+			}
+		case "min", "max":
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return nil, errors.New("invalid " + name + " rule '" + str + "'")
+			}
+			rule.limit = limit
+		case "enum":
+			if arg == "" {
+				return nil, errors.New("enum rule '" + str + "' has no options")
+			}
+			rule.options = strings.Split(arg, "|")
+		case "regex":
+			regex, err := regexp.Compile(arg)
+			if err != nil {
+				return nil, errors.New("invalid regex rule '" + str + "': " + err.Error())
+			}
+			rule.regex = regex
+		default:
+			return nil, errors.New("unknown validate rule '" + str + "'")
+		}
+		r = append(r, rule)
+	}
+	return r, nil
+}
+
+func validateStruct(v reflect.Value, prefix string, errs *[]*FieldError) error {
+	rules, err := loadStructRules(v.Type(), make(map[reflect.Type]bool))
+	if err != nil {
+		return err
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := prefix + fieldName(sf)
+		f := v.Field(i)
+
+		if e := validateField(f, rules[i]); e != nil {
+			e.Field = name
+			*errs = append(*errs, e)
+			continue
+		}
+
+		if err := validateNested(f, name, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateNested(f reflect.Value, name string, errs *[]*FieldError) error {
+	for f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return nil
+		}
+		f = f.Elem()
+	}
+
+	switch f.Kind() {
+	case reflect.Struct:
+		return validateStruct(f, name+".", errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < f.Len(); i++ {
+			if err := validateNested(f.Index(i), name+"["+strconv.Itoa(i)+"]", errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateField(f reflect.Value, rules []*validateRule) *FieldError {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	// 空指针只校验required
+	if f.Kind() == reflect.Ptr && f.IsNil() {
+		for _, rule := range rules {
+			if rule.name == "required" {
+				return &FieldError{Rule: "required", Message: "is required"}
+			}
+		}
+		return nil
+	}
+	for f.Kind() == reflect.Ptr {
+		f = f.Elem()
+	}
+
+	for _, rule := range rules {
+		switch rule.name {
+		case "required":
+			if isEmpty(f) {
+				return &FieldError{Rule: rule.name, Message: "is required"}
+			}
+		case "min", "max":
+			n, isLen, ok := measure(f)
+			if !ok {
+				continue
+			}
+			if (rule.name == "min" && n < rule.limit) || (rule.name == "max" && n > rule.limit) {
+				return &FieldError{Rule: rule.name, Message: limitMessage(rule.name, rule.arg, isLen)}
+			}
+		case "enum":
+			if f.Kind() == reflect.String && f.Len() == 0 {
+				continue // 空字符串由required判断
+			}
+			if !slices.Contains(rule.options, fmt.Sprint(f.Interface())) {
+				return &FieldError{Rule: rule.name, Message: "must be one of [" + strings.Join(rule.options, ", ") + "]"}
+			}
+		case "regex":
+			if f.Kind() != reflect.String || f.Len() == 0 {
+				continue
+			}
+			if !rule.regex.MatchString(f.String()) {
+				return &FieldError{Rule: rule.name, Message: "must match pattern " + rule.arg}
+			}
+		}
+	}
+	return nil
+}
+
+// isEmpty required的判断，数值和bool只能通过非nil的指针到达，视为已提供
+func isEmpty(f reflect.Value) bool {
+	switch {
+	case f.Kind() == reflect.String, f.Kind() == reflect.Slice, f.Kind() == reflect.Array, f.Kind() == reflect.Map:
+		return f.Len() == 0
+	case f.Kind() == reflect.Bool, isNumberKind(f.Kind()):
+		return false
+	}
+	return f.IsZero()
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// splitRules 按逗号拆分规则，regex规则之后的内容全部作为正则表达式
+func splitRules(tag string) []string {
+	var r []string
+	for tag != "" {
+		if strings.HasPrefix(tag, "regex=") {
+			r = append(r, tag)
+			break
+		}
+		var rule string
+		rule, tag, _ = strings.Cut(tag, ",")
+		if rule = strings.TrimSpace(rule); rule != "" {
+			r = append(r, rule)
+		}
+	}
+	return r
+}
+
+func measure(f reflect.Value) (n float64, isLen, ok bool) {
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(f.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(f.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return f.Float(), false, true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(f.String())), true, true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(f.Len()), true, true
+	}
+	return 0, false, false
+}
+
+func limitMessage(rule, arg string, isLen bool) string {
+	switch {
+	case rule == "min" && isLen:
+		return "length must be at least " + arg
+	case rule == "min":
+		return "must be at least " + arg
+	case isLen:
+		return "length must be at most " + arg
+	default:
+		return "must be at most " + arg
+	}
+}
+
+// fieldName 错误信息中的字段名，依次取json、schema、path标签，没有则用字段名
+func fieldName(sf reflect.StructField) string {
+	for _, key := range []string{"json", "schema", Tag_Path} {
+		if name, _, _ := strings.Cut(sf.Tag.Get(key), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
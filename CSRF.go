@@ -0,0 +1,213 @@
+package host
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/syncfuture/go/slog"
+)
+
+const (
+	CSRFMode_Session      = "session"
+	CSRFMode_DoubleSubmit = "double_submit"
+
+	Header_CSRFToken = "X-CSRF-Token"
+	Ctx_CSRFToken    = "csrftoken"
+
+	_ctxCSRFProtector = "csrfprotector"
+
+	_defaultCSRFFormField  = "_csrf"
+	_defaultCSRFCookieName = "_csrf"
+	_defaultCSRFSessionKey = "csrf"
+	_csrfTokenSize         = 32
+)
+
+// CSRFOptions 跨站请求伪造防护配置，适用于以Cookie或Session认证浏览器的宿主
+type CSRFOptions struct {
+	// Mode session(默认，同步令牌存于Session) 或 double_submit(令牌以加密Cookie下发，需配置CookieEncryptor)
+	Mode string
+	// HeaderName 默认X-CSRF-Token，请求头中没有时从表单字段FormField读取
+	HeaderName string
+	// FormField 默认_csrf
+	FormField string
+	// CookieName double_submit模式使用的Cookie名称，默认_csrf
+	CookieName string
+	// SessionKey session模式使用的Session键，默认csrf
+	SessionKey string
+	// TrustedOrigins 除同源外允许的Origin，如https://admin.example.com
+	TrustedOrigins []string
+	// ExemptRoutes 不检查的RouteKey，如第三方回调，Action.SkipCSRF效果相同
+	ExemptRoutes []string
+}
+
+// CSRFProtector 校验非安全方法（POST、PUT、PATCH、DELETE等）请求的Origin/Referer和令牌
+// 页面通过GetCSRFToken(ctx)取得令牌，写入表单字段或请求头
+type CSRFProtector struct {
+	options *CSRFOptions
+	mu      sync.RWMutex
+	exempt  map[string]bool
+}
+
+func NewCSRFProtector(options *CSRFOptions) *CSRFProtector {
+	o := *options
+	if o.Mode == "" {
+		o.Mode = CSRFMode_Session
+	}
+	if o.Mode != CSRFMode_Session && o.Mode != CSRFMode_DoubleSubmit {
+		slog.Fatal("unsupported csrf mode: " + o.Mode)
+	}
+	if o.HeaderName == "" {
+		o.HeaderName = Header_CSRFToken
+	}
+	if o.FormField == "" {
+		o.FormField = _defaultCSRFFormField
+	}
+	if o.CookieName == "" {
+		o.CookieName = _defaultCSRFCookieName
+	}
+	if o.SessionKey == "" {
+		o.SessionKey = _defaultCSRFSessionKey
+	}
+	o.TrustedOrigins = make([]string, 0, len(options.TrustedOrigins))
+	for _, origin := range options.TrustedOrigins {
+		o.TrustedOrigins = append(o.TrustedOrigins, strings.ToLower(strings.TrimSuffix(origin, "/")))
+	}
+
+	r := &CSRFProtector{
+		options: &o,
+		exempt:  make(map[string]bool),
+	}
+	r.Exempt(o.ExemptRoutes...)
+	return r
+}
+
+// Exempt 使routeKeys不再检查CSRF
+func (x *CSRFProtector) Exempt(routeKeys ...string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, routeKey := range routeKeys {
+		x.exempt[routeKey] = true
+	}
+}
+
+func (x *CSRFProtector) isExempt(routeKey string) bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.exempt[routeKey]
+}
+
+// Handler 全局前置中间件，拒绝来源或令牌不正确的非安全方法请求
+// 令牌只在GetCSRFToken时生成，安全方法的请求不读写Session或Cookie
+func (x *CSRFProtector) Handler(ctx IHttpContext) {
+	ctx.SetItem(_ctxCSRFProtector, x)
+
+	if isSafeMethod(ctx.RequestMethod()) || x.isExempt(ctx.GetRouteKey()) {
+		ctx.Next()
+		return
+	}
+
+	if !x.isOriginAllowed(ctx) {
+		ctx.Error(NewProblem(http.StatusForbidden, "cross-origin request rejected"))
+		return
+	}
+
+	// 没有保存的令牌时提交的令牌不可能正确，不必生成
+	token := x.getToken(ctx)
+	submitted := ctx.GetHeader(x.options.HeaderName)
+	if submitted == "" {
+		submitted = ctx.GetFormString(x.options.FormField)
+	}
+	if token == "" || submitted == "" || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+		ctx.Error(NewProblem(http.StatusForbidden, "invalid csrf token"))
+		return
+	}
+	ctx.SetItem(Ctx_CSRFToken, token)
+
+	ctx.Next()
+}
+
+// getToken 取得保存的令牌，不存在时返回空
+func (x *CSRFProtector) getToken(ctx IHttpContext) string {
+	if x.options.Mode == CSRFMode_DoubleSubmit {
+		return ctx.GetEncryptedCookieString(x.options.CookieName)
+	}
+	return ctx.GetSessionString(x.options.SessionKey)
+}
+
+// loadToken 取得当前令牌，不存在时生成并保存
+func (x *CSRFProtector) loadToken(ctx IHttpContext) string {
+	token := x.getToken(ctx)
+	if token != "" {
+		return token
+	}
+
+	token = newCSRFToken()
+	if x.options.Mode == CSRFMode_DoubleSubmit {
+		ctx.SetEncryptedCookieKV(x.options.CookieName, token, func(c *http.Cookie) {
+			c.Path = "/"
+			c.HttpOnly = true
+			c.SameSite = http.SameSiteLaxMode
+		})
+	} else {
+		ctx.SetSession(x.options.SessionKey, token)
+	}
+	return token
+}
+
+// isOriginAllowed Origin（没有时取Referer）须与请求的Host相同或在TrustedOrigins中，都没有时只校验令牌
+func (x *CSRFProtector) isOriginAllowed(ctx IHttpContext) bool {
+	origin := ctx.GetHeader(Header_Origin)
+	if origin == "" {
+		referer := ctx.GetHeader("Referer")
+		if referer == "" {
+			return true
+		}
+		origin = referer
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if slices.Contains(x.options.TrustedOrigins, strings.ToLower(u.Scheme+"://"+u.Host)) {
+		return true
+	}
+
+	// 只比较Host，HTTPS在代理处终止时请求的scheme与Origin不同
+	requestURL, err := url.Parse(ctx.RequestURL())
+	return err == nil && strings.EqualFold(u.Host, requestURL.Host)
+}
+
+// GetCSRFToken 当前请求的CSRF令牌，不存在时生成并保存，需启用宿主的CSRF，未启用时返回空
+func GetCSRFToken(ctx IHttpContext) string {
+	if token := ctx.GetItemString(Ctx_CSRFToken); token != "" {
+		return token
+	}
+	x, ok := ctx.GetItem(_ctxCSRFProtector).(*CSRFProtector)
+	if !ok {
+		return ""
+	}
+	token := x.loadToken(ctx)
+	ctx.SetItem(Ctx_CSRFToken, token)
+	return token
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func newCSRFToken() string {
+	b := make([]byte, _csrfTokenSize)
+	_, _ = rand.Read(b) // crypto/rand.Read不会返回错误
+	return base64.RawURLEncoding.EncodeToString(b)
+}
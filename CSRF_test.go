@@ -0,0 +1,48 @@
+package host_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncfuture/host"
+	"github.com/syncfuture/host/hosttest"
+)
+
+func TestCSRFProtector(t *testing.T) {
+	protector := host.NewCSRFProtector(&host.CSRFOptions{})
+
+	// 不取令牌时不写Session
+	ctx := hosttest.NewMockHttpContext(http.MethodGet, "/plain", nil).Run(protector.Handler)
+	assert.True(t, ctx.NextCalled())
+	assert.NotContains(t, ctx.Session, "csrf")
+
+	ctx = hosttest.NewMockHttpContext(http.MethodGet, "/form", nil).Run(protector.Handler)
+	token := host.GetCSRFToken(ctx)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, token, ctx.Session["csrf"])
+	assert.Equal(t, token, host.GetCSRFToken(ctx))
+	assert.Empty(t, host.GetCSRFToken(hosttest.NewMockHttpContext(http.MethodGet, "/form", nil))) // 未启用CSRF
+
+	form := url.Values{"_csrf": {token}}
+	ctx = hosttest.NewMockHttpContext(http.MethodPost, "/submit", strings.NewReader(form.Encode()))
+	ctx.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx.Session["csrf"] = token
+	ctx.Run(protector.Handler)
+	assert.True(t, ctx.NextCalled())
+
+	ctx = hosttest.NewMockHttpContext(http.MethodPost, "/submit", nil)
+	ctx.Session["csrf"] = token
+	ctx.Run(protector.Handler)
+	assert.False(t, ctx.NextCalled())
+	assert.Equal(t, http.StatusForbidden, host.ToProblem(ctx.GetError()).Status)
+
+	// 没有保存的令牌时不生成，直接拒绝
+	ctx = hosttest.NewMockHttpContext(http.MethodPost, "/submit", nil)
+	ctx.Request.Header.Set(host.Header_CSRFToken, token)
+	ctx.Run(protector.Handler)
+	assert.False(t, ctx.NextCalled())
+	assert.NotContains(t, ctx.Session, "csrf")
+}
@@ -0,0 +1,268 @@
+package host
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/syncfuture/go/serr"
+	"github.com/syncfuture/go/u"
+)
+
+const (
+	Encoding_Brotli  = "br"
+	Encoding_Zstd    = "zstd"
+	Encoding_Gzip    = "gzip"
+	Encoding_Deflate = "deflate"
+
+	Header_AcceptEncoding  = "Accept-Encoding"
+	Header_ContentEncoding = "Content-Encoding"
+	Header_Vary            = "Vary"
+
+	_defaultCompressMinSize = 1024
+)
+
+var (
+	_defaultEncodings    = []string{Encoding_Brotli, Encoding_Zstd, Encoding_Gzip, Encoding_Deflate}
+	_defaultContentTypes = []string{
+		"text/",
+		"application/json",
+		"application/javascript",
+		"application/xml",
+		"application/wasm",
+		"image/svg+xml",
+		"+json",
+		"+xml",
+	}
+	// 预压缩文件的扩展名，按服务端优先顺序
+	_precompressedExts = []struct{ encoding, ext string }{
+		{Encoding_Brotli, ".br"},
+		{Encoding_Gzip, ".gz"},
+	}
+
+	_gzipWriterPool = sync.Pool{New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}}
+	_zlibWriterPool = sync.Pool{New: func() interface{} {
+		return zlib.NewWriter(nil)
+	}}
+	_brotliWriterPool = sync.Pool{New: func() interface{} {
+		return brotli.NewWriter(nil)
+	}}
+	_zstdEncoder, _ = zstd.NewWriter(nil) // EncodeAll可并发调用
+)
+
+// CompressionOptions 响应压缩配置，宿主配置此项后自动添加压缩中间件
+type CompressionOptions struct {
+	// Encodings 支持的编码，按服务端优先顺序，默认: br, zstd, gzip, deflate
+	Encodings []string
+	// MinSize 小于此大小的响应不压缩，默认1024
+	MinSize int
+	// ContentTypes 允许压缩的Content-Type，以/结尾表示前缀匹配，以+开头表示后缀匹配
+	ContentTypes []string
+	// Precompressed ServeFiles/ServeEmbedFiles优先返回同名的.br/.gz文件
+	Precompressed bool
+}
+
+// NewCompressionHandler 创建压缩中间件，在处理链执行完毕后按Accept-Encoding压缩响应
+func NewCompressionHandler(options *CompressionOptions) RequestHandler {
+	o := options.withDefaults()
+
+	return func(ctx IHttpContext) {
+		ctx.Next()
+
+		status := ctx.GetStatusCode()
+		if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusPartialContent || status == http.StatusNotModified ||
+			ctx.IsBodyStream() || ctx.GetResponseHeader(Header_ContentEncoding) != "" ||
+			!o.IsCompressible(ctx.GetResponseHeader("Content-Type")) {
+			return
+		}
+
+		AddVary(ctx, Header_AcceptEncoding)
+
+		body := ctx.GetResponseBody()
+		if len(body) < o.MinSize {
+			return
+		}
+
+		encoding := NegotiateEncoding(ctx.GetHeader(Header_AcceptEncoding), o.Encodings)
+		if encoding == "" {
+			return
+		}
+
+		compressed, err := Compress(encoding, body)
+		if u.LogError(err) || len(compressed) >= len(body) {
+			return
+		}
+
+		ctx.SetHeader(Header_ContentEncoding, encoding)
+		ctx.SetResponseBody(compressed)
+	}
+}
+
+func (x *CompressionOptions) withDefaults() *CompressionOptions {
+	r := new(CompressionOptions)
+	if x != nil {
+		*r = *x
+	}
+	if len(r.Encodings) == 0 {
+		r.Encodings = _defaultEncodings
+	}
+	if r.MinSize <= 0 {
+		r.MinSize = _defaultCompressMinSize
+	}
+	if len(r.ContentTypes) == 0 {
+		r.ContentTypes = _defaultContentTypes
+	}
+	return r
+}
+
+// IsCompressible Content-Type是否在允许压缩的列表中
+func (x *CompressionOptions) IsCompressible(cType string) bool {
+	if cType == "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(cType)
+	if err != nil {
+		return false
+	}
+
+	contentTypes := _defaultContentTypes
+	if x != nil && len(x.ContentTypes) > 0 {
+		contentTypes = x.ContentTypes
+	}
+	for _, t := range contentTypes {
+		switch {
+		case strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t),
+			strings.HasPrefix(t, "+") && strings.HasSuffix(mediaType, t),
+			mediaType == t:
+			return true
+		}
+	}
+	return false
+}
+
+// OpenStaticFile 打开静态文件，启用Precompressed且客户端支持时优先打开name.br、name.gz
+// encoding不为空时需设置Content-Encoding，Content-Type仍按name判断
+func (x *CompressionOptions) OpenStaticFile(fsys fs.FS, name, acceptEncoding string) (file fs.File, encoding string, err error) {
+	if x != nil && x.Precompressed && acceptEncoding != "" {
+		accepted := parseAcceptEncoding(acceptEncoding)
+		for _, pc := range _precompressedExts {
+			if q, ok := accepted.get(pc.encoding); !ok || q <= 0 {
+				continue
+			}
+			if file, err = fsys.Open(name + pc.ext); err == nil {
+				return file, pc.encoding, nil
+			}
+		}
+	}
+
+	file, err = fsys.Open(name)
+	return file, "", err
+}
+
+// NegotiateEncoding 按Accept-Encoding的q值选择编码，q值相同时按supported的顺序，没有可用编码返回空
+func NegotiateEncoding(acceptEncoding string, supported []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	accepted := parseAcceptEncoding(acceptEncoding)
+	var best string
+	var bestQ float64
+	for _, encoding := range supported {
+		if q, ok := accepted.get(encoding); ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+type acceptEncodings map[string]float64
+
+func parseAcceptEncoding(acceptEncoding string) acceptEncodings {
+	r := make(acceptEncodings)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+		r[name] = q
+	}
+	return r
+}
+
+func (x acceptEncodings) get(encoding string) (float64, bool) {
+	if q, ok := x[encoding]; ok {
+		return q, true
+	}
+	q, ok := x["*"]
+	return q, ok
+}
+
+// Compress 使用指定编码压缩数据
+func Compress(encoding string, data []byte) ([]byte, error) {
+	if encoding == Encoding_Zstd {
+		return _zstdEncoder.EncodeAll(data, make([]byte, 0, len(data)/2)), nil
+	}
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case Encoding_Gzip:
+		gw := _gzipWriterPool.Get().(*gzip.Writer)
+		defer _gzipWriterPool.Put(gw)
+		gw.Reset(&buf)
+		w = gw
+	case Encoding_Deflate:
+		zw := _zlibWriterPool.Get().(*zlib.Writer)
+		defer _zlibWriterPool.Put(zw)
+		zw.Reset(&buf)
+		w = zw
+	case Encoding_Brotli:
+		bw := _brotliWriterPool.Get().(*brotli.Writer)
+		defer _brotliWriterPool.Put(bw)
+		bw.Reset(&buf)
+		w = bw
+	default:
+		return nil, serr.Errorf("unsupported encoding '%s'", encoding)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, serr.WithStack(err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, serr.WithStack(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// AddVary 向Vary响应头追加值，已存在时忽略
+func AddVary(ctx IHttpContext, value string) {
+	vary := ctx.GetResponseHeader(Header_Vary)
+	for _, v := range strings.Split(vary, ",") {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return
+		}
+	}
+	if vary != "" {
+		value = vary + ", " + value
+	}
+	ctx.SetHeader(Header_Vary, value)
+}
@@ -0,0 +1,263 @@
+package host
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/syncfuture/go/serr"
+	"github.com/syncfuture/go/u"
+)
+
+const (
+	HealthStatus_Up   = "up"
+	HealthStatus_Down = "down"
+
+	_defaultLivePath           = "/healthz"
+	_defaultReadyPath          = "/readyz"
+	_defaultHealthCheckTimeout = 3 * time.Second
+	_defaultHealthCheckCache   = time.Second
+)
+
+type (
+	// HealthCheckFunc 健康检查，返回nil表示健康，应在ctx结束时返回
+	HealthCheckFunc func(ctx context.Context) error
+
+	// HealthOptions 健康检查配置，宿主配置此项后暴露存活(/healthz)和就绪(/readyz)端点
+	HealthOptions struct {
+		LivePath  string
+		ReadyPath string
+		// TimeoutMs 单个检查的超时时间，默认3000
+		TimeoutMs int
+		// CacheMs 检查结果的缓存时间，默认1000，负数不缓存
+		CacheMs int
+	}
+
+	HealthCheckResult struct {
+		Status     string  `json:"status"`
+		Error      string  `json:"error,omitempty"`
+		DurationMs float64 `json:"duration_ms"`
+	}
+
+	// HealthReport 所有检查都健康时Status为up
+	HealthReport struct {
+		Status string                        `json:"status"`
+		Checks map[string]*HealthCheckResult `json:"checks,omitempty"`
+	}
+
+	// HealthChecker 运行已注册的检查，就绪检查在宿主关闭期间总是失败，以便负载均衡摘除实例
+	HealthChecker struct {
+		timeout      time.Duration
+		cache        time.Duration
+		shuttingDown func() bool
+		mu           sync.RWMutex
+		liveChecks   []*healthCheck
+		readyChecks  []*healthCheck
+	}
+
+	healthCheck struct {
+		name    string
+		check   HealthCheckFunc
+		mu      sync.Mutex
+		result  *HealthCheckResult
+		expires time.Time
+	}
+)
+
+func (x *HealthOptions) GetLivePath() string {
+	if x.LivePath == "" {
+		return _defaultLivePath
+	}
+	return x.LivePath
+}
+
+func (x *HealthOptions) GetReadyPath() string {
+	if x.ReadyPath == "" {
+		return _defaultReadyPath
+	}
+	return x.ReadyPath
+}
+
+// NewHealthChecker shuttingDown通常为Lifecycle.IsShuttingDown，可为nil
+func NewHealthChecker(options *HealthOptions, shuttingDown func() bool) *HealthChecker {
+	r := &HealthChecker{
+		timeout:      time.Duration(options.TimeoutMs) * time.Millisecond,
+		cache:        time.Duration(options.CacheMs) * time.Millisecond,
+		shuttingDown: shuttingDown,
+	}
+	if r.timeout <= 0 {
+		r.timeout = _defaultHealthCheckTimeout
+	}
+	if options.CacheMs == 0 {
+		r.cache = _defaultHealthCheckCache
+	}
+	return r
+}
+
+// AddCheck 添加就绪检查，如数据库、Redis、依赖的服务
+func (x *HealthChecker) AddCheck(name string, check HealthCheckFunc) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.readyChecks = append(x.readyChecks, &healthCheck{name: name, check: check})
+}
+
+// AddLivenessCheck 添加存活检查，失败时应重启进程，因此不应检查外部依赖
+func (x *HealthChecker) AddLivenessCheck(name string, check HealthCheckFunc) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.liveChecks = append(x.liveChecks, &healthCheck{name: name, check: check})
+}
+
+func (x *HealthChecker) Live(ctx context.Context) *HealthReport {
+	x.mu.RLock()
+	checks := x.liveChecks
+	x.mu.RUnlock()
+	return x.run(ctx, checks)
+}
+
+func (x *HealthChecker) Ready(ctx context.Context) *HealthReport {
+	if x.shuttingDown != nil && x.shuttingDown() {
+		return &HealthReport{
+			Status: HealthStatus_Down,
+			Checks: map[string]*HealthCheckResult{
+				"shutdown": {Status: HealthStatus_Down, Error: "shutting down"},
+			},
+		}
+	}
+
+	x.mu.RLock()
+	checks := x.readyChecks
+	x.mu.RUnlock()
+	return x.run(ctx, checks)
+}
+
+// run 并发执行检查
+func (x *HealthChecker) run(ctx context.Context, checks []*healthCheck) *HealthReport {
+	r := &HealthReport{
+		Status: HealthStatus_Up,
+		Checks: make(map[string]*HealthCheckResult, len(checks)),
+	}
+
+	results := make([]*HealthCheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = check.run(ctx, x.timeout, x.cache)
+		}()
+	}
+	wg.Wait()
+
+	for i, check := range checks {
+		r.Checks[check.name] = results[i]
+		if results[i].Status != HealthStatus_Up {
+			r.Status = HealthStatus_Down
+		}
+	}
+	return r
+}
+
+// run 缓存未过期时返回上次结果，同一检查同时只执行一次
+func (x *healthCheck) run(ctx context.Context, timeout, cache time.Duration) *HealthCheckResult {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	now := time.Now()
+	if x.result != nil && now.Before(x.expires) {
+		return x.result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- serr.Errorf("health check panic: %v", p)
+			}
+		}()
+		done <- x.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err() // 检查未响应ctx时不再等待
+	}
+
+	r := &HealthCheckResult{
+		Status:     HealthStatus_Up,
+		DurationMs: float64(time.Since(now).Microseconds()) / 1000,
+	}
+	if err != nil {
+		r.Status = HealthStatus_Down
+		r.Error = err.Error()
+	}
+
+	x.result = r
+	x.expires = time.Now().Add(cache)
+	return r
+}
+
+// LiveHttpHandler 输出存活检查的JSON，健康时200，否则503
+func (x *HealthChecker) LiveHttpHandler() http.Handler {
+	return healthHttpHandler(x.Live)
+}
+
+// ReadyHttpHandler 输出就绪检查的JSON，就绪时200，否则503
+func (x *HealthChecker) ReadyHttpHandler() http.Handler {
+	return healthHttpHandler(x.Ready)
+}
+
+func healthHttpHandler(report func(ctx context.Context) *HealthReport) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 不随请求取消，避免客户端断开导致缓存失败的结果，检查本身有超时
+		result := report(context.WithoutCancel(r.Context()))
+		data, err := json.Marshal(result)
+		if u.LogError(err) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if result.Status != HealthStatus_Up {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write(data)
+	})
+}
+
+////////// 内置检查
+
+// RedisHealthCheck PING Redis
+func RedisHealthCheck(client redis.UniversalClient) HealthCheckFunc {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
+// HttpHealthCheck 检查url是否可达，如OAuth令牌端点，状态码小于500即视为可达
+func HttpHealthCheck(url string) HealthCheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return serr.WithStack(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return serr.WithStack(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return serr.Errorf("%s responded %d", url, resp.StatusCode)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,133 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/syncfuture/go/serr"
+	"github.com/syncfuture/go/slog"
+)
+
+const _defaultShutdownTimeout = 30 * time.Second
+
+// Lifecycle 宿主生命周期: 监听退出信号、优雅关闭、执行关闭钩子
+type Lifecycle struct {
+	ShutdownTimeoutSeconds int
+	hooks                  []ShutdownHook
+	hooksLock              sync.Mutex
+	shuttingDown           atomic.Bool
+	initOnce               sync.Once
+	shutdownOnce           sync.Once
+	shutdownStart          chan struct{}
+	shutdownDone           chan struct{}
+	shutdownErr            error
+}
+
+func (x *Lifecycle) init() {
+	x.initOnce.Do(func() {
+		x.shutdownStart = make(chan struct{})
+		x.shutdownDone = make(chan struct{})
+	})
+}
+
+// AddShutdownHooks 添加关闭钩子，服务停止后按添加顺序的倒序执行
+func (x *Lifecycle) AddShutdownHooks(hooks ...ShutdownHook) {
+	x.hooksLock.Lock()
+	defer x.hooksLock.Unlock()
+	x.hooks = append(x.hooks, hooks...)
+}
+
+// IsShuttingDown 是否已开始关闭
+func (x *Lifecycle) IsShuttingDown() bool {
+	return x.shuttingDown.Load()
+}
+
+// ShuttingDown 开始关闭时关闭此通道，长连接（SSE等）可据此提前结束
+func (x *Lifecycle) ShuttingDown() <-chan struct{} {
+	x.init()
+	return x.shutdownStart
+}
+
+func (x *Lifecycle) GetShutdownTimeout() time.Duration {
+	if x.ShutdownTimeoutSeconds > 0 {
+		return time.Duration(x.ShutdownTimeoutSeconds) * time.Second
+	}
+	return _defaultShutdownTimeout
+}
+
+// RunUntilSignal 执行serve直到ctx结束或收到SIGINT/SIGTERM，然后调用shutdown优雅关闭
+func (x *Lifecycle) RunUntilSignal(ctx context.Context, serve func() error, shutdown func(timeout time.Duration) error) error {
+	x.init()
+	if x.IsShuttingDown() {
+		return serr.New("host has been shut down")
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !x.IsShuttingDown() {
+			// 监听失败等，未经过Shutdown
+			return err
+		}
+		// 其他地方调用了Shutdown，等待其完成
+		<-x.shutdownDone
+		return x.shutdownErr
+	case <-ctx.Done():
+		slog.Info("shutting down...")
+		err := shutdown(x.GetShutdownTimeout())
+		return errors.Join(err, <-serveErr)
+	}
+}
+
+// GracefulShutdown 调用stop停止服务，然后执行关闭钩子，多次调用只执行一次
+// timeout <= 0 时使用ShutdownTimeoutSeconds
+func (x *Lifecycle) GracefulShutdown(timeout time.Duration, stop func(ctx context.Context) error) error {
+	x.init()
+	x.shutdownOnce.Do(func() {
+		x.shuttingDown.Store(true)
+		close(x.shutdownStart)
+		defer close(x.shutdownDone)
+
+		if timeout <= 0 {
+			timeout = x.GetShutdownTimeout()
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		var errs []error
+		if err := stop(ctx); err != nil {
+			errs = append(errs, err)
+		}
+
+		x.hooksLock.Lock()
+		hooks := x.hooks
+		x.hooksLock.Unlock()
+		for i := len(hooks) - 1; i >= 0; i-- {
+			if err := hooks[i](ctx); err != nil {
+				errs = append(errs, serr.WithStack(err))
+			}
+		}
+
+		x.shutdownErr = errors.Join(errs...)
+		if x.shutdownErr != nil {
+			slog.Errorf("shutdown: %+v", x.shutdownErr)
+		} else {
+			slog.Info("shutdown completed")
+		}
+	})
+
+	<-x.shutdownDone
+	return x.shutdownErr
+}
@@ -0,0 +1,113 @@
+package host
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/syncfuture/go/serr"
+	"github.com/syncfuture/go/slog"
+)
+
+const (
+	_defaultMetricsPath = "/metrics"
+)
+
+var (
+	// MetricsRegistry 宿主所有指标的注册表，自定义指标也可注册到这里
+	MetricsRegistry = prometheus.NewRegistry()
+
+	_httpRequestsTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total number of HTTP requests by route key, method and status.",
+	}, []string{"route", "method", "status"})
+	_httpRequestDuration = promauto.With(MetricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by route key, method and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+	_httpResponseSize = promauto.With(MetricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_response_size_bytes",
+		Help:    "HTTP response body size by route key, method and status, streamed responses are not observed.",
+		Buckets: prometheus.ExponentialBuckets(128, 4, 8),
+	}, []string{"route", "method", "status"})
+	_httpRequestsInFlight = promauto.With(MetricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Number of HTTP requests being served by route key.",
+	}, []string{"route"})
+)
+
+func init() {
+	MetricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// MetricsOptions 指标配置，宿主配置此项后统计每个RouteKey的请求数、耗时、并发数和响应大小
+type MetricsOptions struct {
+	// Path 指标路径，默认/metrics
+	Path string
+	// ListenAddr 指定时在单独的管理端口上暴露指标，否则注册到宿主的路由
+	ListenAddr string
+}
+
+func (x *MetricsOptions) GetPath() string {
+	if x.Path == "" {
+		return _defaultMetricsPath
+	}
+	return x.Path
+}
+
+// StartServer 在ListenAddr上启动指标服务，返回的关闭钩子需添加到宿主
+func (x *MetricsOptions) StartServer() (ShutdownHook, error) {
+	mux := http.NewServeMux()
+	mux.Handle("GET "+x.GetPath(), NewMetricsHttpHandler())
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ln, err := net.Listen("tcp", x.ListenAddr)
+	if err != nil {
+		return nil, serr.WithStack(err)
+	}
+	slog.Infof("Metrics listening on %s", x.ListenAddr)
+
+	go func() {
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			slog.Errorf("metrics server: %+v", serr.WithStack(err))
+		}
+	}()
+
+	return server.Shutdown, nil
+}
+
+// NewMetricsHttpHandler 以Prometheus文本格式输出MetricsRegistry
+func NewMetricsHttpHandler() http.Handler {
+	return promhttp.HandlerFor(MetricsRegistry, promhttp.HandlerOpts{Registry: MetricsRegistry})
+}
+
+// MetricsHandler 统计请求的中间件
+func MetricsHandler(ctx IHttpContext) {
+	route := ctx.GetRouteKey()
+	method := ctx.RequestMethod()
+	inFlight := _httpRequestsInFlight.WithLabelValues(route)
+	inFlight.Inc()
+	defer inFlight.Dec()
+	start := time.Now()
+
+	ctx.Next()
+
+	status := strconv.Itoa(ctx.GetStatusCode())
+	_httpRequestsTotal.WithLabelValues(route, method, status).Inc()
+	_httpRequestDuration.WithLabelValues(route, method, status).Observe(time.Since(start).Seconds())
+	if size := getResponseSize(ctx); size >= 0 {
+		_httpResponseSize.WithLabelValues(route, method, status).Observe(float64(size))
+	}
+}
@@ -0,0 +1,597 @@
+package host
+
+import (
+	"bytes"
+	"cmp"
+	"embed"
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// OpenAPISecurity_Bearer 文档中Bearer令牌安全方案的名称
+	OpenAPISecurity_Bearer = "bearerAuth"
+
+	_openAPIVersion          = "3.1.0"
+	_defaultOpenAPIPath      = "/openapi.json"
+	_defaultOpenAPITitle     = "API"
+	_defaultOpenAPIVersion   = "1.0.0"
+	_defaultAuthHandlerName  = "AuthHandler"
+	_openAPISchemaRefPrefix  = "#/components/schemas/"
+	_openAPIResponse_Default = "default"
+)
+
+var (
+	// _swaggerUIFiles swagger-ui-dist 5.18.2的swagger-ui-bundle.js和swagger-ui.css（Apache-2.0）
+	//go:embed swaggerui
+	_swaggerUIFiles embed.FS
+
+	_openAPIParamRegex = regexp.MustCompile(`\{([^{}:?]+)(\?)?(:[^{}]*)?\}`)
+	_schemaNameRegex   = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+	_timeType          = reflect.TypeOf(time.Time{})
+	_problemType       = reflect.TypeOf(Problem{})
+
+	_swaggerUITemplate = template.Must(template.New("swagger-ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.AssetsURL}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.AssetsURL}}/swagger-ui-bundle.js" nonce="{{.Nonce}}"></script>
+<script nonce="{{.Nonce}}">
+window.ui = SwaggerUIBundle({ url: {{.SpecURL}}, dom_id: "#swagger-ui" });
+</script>
+</body>
+</html>
+`))
+)
+
+type (
+	// OpenAPIOptions OpenAPI文档配置，宿主配置此项后根据路由表生成OpenAPI 3.1文档
+	OpenAPIOptions struct {
+		// Path 文档路径，默认/openapi.json
+		Path string
+		// Title 默认API
+		Title string
+		// Version 默认1.0.0
+		Version     string
+		Description string
+		// Servers 服务地址，如https://api.example.com
+		Servers []string
+		// SwaggerUIPath 配置后在此路径提供Swagger UI页面
+		SwaggerUIPath string
+		// SwaggerUIAssetsURL swagger-ui-dist静态资源地址，如https://unpkg.com/swagger-ui-dist@5，
+		// 默认使用嵌入的资源，在SwaggerUIPath下提供，不依赖外部网络
+		SwaggerUIAssetsURL string
+		// AuthHandlers 要求Bearer令牌的中间件名称，路由包含这些中间件时在文档中标记为需要认证，默认AuthHandler（OAuthResourceHost.AuthHandler）
+		AuthHandlers []string
+		// Handlers 在输出文档和Swagger UI前执行，如管理员认证，未调用Next则不输出
+		Handlers []RequestHandler `json:"-"`
+	}
+
+	// ActionDoc Action的文档信息，用于生成OpenAPI文档
+	ActionDoc struct {
+		Summary     string
+		Description string
+		Tags        []string
+		// OperationID 默认为RouteKey
+		OperationID string
+		// Request 请求类型的值，如CreateOrder{}，按Bind的规则生成参数：path标签字段为路径参数，
+		// GET等无请求体的方法其余字段为查询参数，POST、PUT、PATCH的json字段为JSON请求体，json:"-"且有schema标签的字段为查询参数
+		Request interface{}
+		// Response 200响应体类型的值
+		Response interface{}
+		// Scopes 需要的scope，设置后即使没有AuthHandler也标记为需要认证
+		Scopes     []string
+		Deprecated bool
+	}
+
+	// JSONSchema JSON Schema 2020-12
+	JSONSchema map[string]interface{}
+
+	OpenAPIDocument struct {
+		OpenAPI    string                                  `json:"openapi"`
+		Info       *OpenAPIInfo                            `json:"info"`
+		Servers    []*OpenAPIServer                        `json:"servers,omitempty"`
+		Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+		Components *OpenAPIComponents                      `json:"components,omitempty"`
+	}
+
+	OpenAPIInfo struct {
+		Title       string `json:"title"`
+		Version     string `json:"version"`
+		Description string `json:"description,omitempty"`
+	}
+
+	OpenAPIServer struct {
+		URL string `json:"url"`
+	}
+
+	OpenAPIOperation struct {
+		OperationID string                      `json:"operationId,omitempty"`
+		Summary     string                      `json:"summary,omitempty"`
+		Description string                      `json:"description,omitempty"`
+		Tags        []string                    `json:"tags,omitempty"`
+		Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+		RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+		Responses   map[string]*OpenAPIResponse `json:"responses"`
+		Security    []map[string][]string       `json:"security,omitempty"`
+		Deprecated  bool                        `json:"deprecated,omitempty"`
+	}
+
+	OpenAPIParameter struct {
+		Name     string     `json:"name"`
+		In       string     `json:"in"`
+		Required bool       `json:"required,omitempty"`
+		Schema   JSONSchema `json:"schema"`
+	}
+
+	OpenAPIRequestBody struct {
+		Required bool                         `json:"required,omitempty"`
+		Content  map[string]*OpenAPIMediaType `json:"content"`
+	}
+
+	OpenAPIResponse struct {
+		Description string                       `json:"description"`
+		Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+	}
+
+	OpenAPIMediaType struct {
+		Schema JSONSchema `json:"schema"`
+	}
+
+	OpenAPIComponents struct {
+		Schemas         map[string]JSONSchema             `json:"schemas,omitempty"`
+		SecuritySchemes map[string]*OpenAPISecurityScheme `json:"securitySchemes,omitempty"`
+	}
+
+	OpenAPISecurityScheme struct {
+		Type         string `json:"type"`
+		Scheme       string `json:"scheme,omitempty"`
+		BearerFormat string `json:"bearerFormat,omitempty"`
+	}
+
+	// openAPIBuilder 生成Schema，命名结构体放入components并以$ref引用
+	openAPIBuilder struct {
+		schemas map[string]JSONSchema
+		names   map[reflect.Type]string
+	}
+)
+
+func (x *OpenAPIOptions) GetPath() string {
+	if x.Path == "" {
+		return _defaultOpenAPIPath
+	}
+	return x.Path
+}
+
+// GetSwaggerUIAssetsPath 嵌入的Swagger UI资源的路由路径，未配置SwaggerUIPath或配置了SwaggerUIAssetsURL时为空
+func (x *OpenAPIOptions) GetSwaggerUIAssetsPath() string {
+	if x.SwaggerUIPath == "" || x.SwaggerUIAssetsURL != "" {
+		return ""
+	}
+	return strings.TrimSuffix(x.SwaggerUIPath, "/") + "/{filepath:*}"
+}
+
+func (x *OpenAPIOptions) GetAuthHandlers() []string {
+	if len(x.AuthHandlers) == 0 {
+		return []string{_defaultAuthHandlerName}
+	}
+	return x.AuthHandlers
+}
+
+// WithDoc 设置文档信息并返回自身，便于在NewActionGroup中使用
+func (x *Action) WithDoc(doc *ActionDoc) *Action {
+	x.Doc = doc
+	return x
+}
+
+// OpenAPIDocument 根据Routes()生成OpenAPI文档，原生路由、路由列表和文档自身的端点除外
+func (x *BaseWebHost) OpenAPIDocument() *OpenAPIDocument {
+	options := x.OpenAPI
+	if options == nil {
+		options = new(OpenAPIOptions)
+	}
+
+	r := &OpenAPIDocument{
+		OpenAPI: _openAPIVersion,
+		Info: &OpenAPIInfo{
+			Title:       cmp.Or(options.Title, _defaultOpenAPITitle),
+			Version:     cmp.Or(options.Version, _defaultOpenAPIVersion),
+			Description: options.Description,
+		},
+		Paths: make(map[string]map[string]*OpenAPIOperation),
+	}
+	for _, url := range options.Servers {
+		r.Servers = append(r.Servers, &OpenAPIServer{URL: url})
+	}
+
+	skipPaths := []string{options.GetPath(), options.SwaggerUIPath}
+	if x.RouteTable != nil {
+		skipPaths = append(skipPaths, x.RouteTable.GetPath())
+	}
+
+	b := &openAPIBuilder{
+		schemas: make(map[string]JSONSchema),
+		names:   make(map[reflect.Type]string),
+	}
+	authHandlers := options.GetAuthHandlers()
+	operationIDs := make(map[string]bool)
+	secured := false
+	for _, route := range x.Routes() {
+		if route.Native || slices.Contains(skipPaths, route.Path) {
+			continue
+		}
+
+		path, params := openAPIPath(route.Path)
+		op := b.operation(route, params, authHandlers)
+		if op.OperationID != "" {
+			// 同一RouteKey用于多个方法时加上方法后缀
+			if operationIDs[op.OperationID] {
+				op.OperationID += "_" + strings.ToLower(route.Method)
+			}
+			operationIDs[op.OperationID] = true
+		}
+		secured = secured || len(op.Security) > 0
+
+		if r.Paths[path] == nil {
+			r.Paths[path] = make(map[string]*OpenAPIOperation)
+		}
+		r.Paths[path][strings.ToLower(route.Method)] = op
+	}
+
+	r.Components = &OpenAPIComponents{Schemas: b.schemas}
+	if secured {
+		r.Components.SecuritySchemes = map[string]*OpenAPISecurityScheme{
+			OpenAPISecurity_Bearer: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		}
+	}
+	return r
+}
+
+// OpenAPIHandler 以JSON输出OpenAPIDocument()
+func (x *BaseWebHost) OpenAPIHandler(ctx IHttpContext) {
+	data, err := json.Marshal(x.OpenAPIDocument())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.WriteJsonBytes(data)
+}
+
+// SwaggerUIHandler 输出加载OpenAPI文档的Swagger UI页面，脚本带有CSP nonce
+func (x *BaseWebHost) SwaggerUIHandler(ctx IHttpContext) {
+	options := x.OpenAPI
+	if options == nil {
+		options = new(OpenAPIOptions)
+	}
+
+	var buf bytes.Buffer
+	err := _swaggerUITemplate.Execute(&buf, map[string]string{
+		"Title":     cmp.Or(options.Title, _defaultOpenAPITitle),
+		"AssetsURL": strings.TrimSuffix(cmp.Or(options.SwaggerUIAssetsURL, options.SwaggerUIPath), "/"),
+		"SpecURL":   options.GetPath(),
+		"Nonce":     ctx.GetCSPNonce(),
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.SetContentType("text/html; charset=utf-8")
+	ctx.WriteBytes(buf.Bytes())
+}
+
+// SwaggerUIAssets 嵌入的Swagger UI资源，宿主在OpenAPIOptions.GetSwaggerUIAssetsPath()上提供
+func SwaggerUIAssets() fs.FS {
+	r, _ := fs.Sub(_swaggerUIFiles, "swaggerui") // 目录在编译时确定，不会出错
+	return r
+}
+
+// openAPIPath 将fasthttp/router风格的路径转为OpenAPI路径，返回路径参数名
+// 可选参数和通配参数也作为必填的路径参数
+func openAPIPath(path string) (string, []string) {
+	var params []string
+	r := _openAPIParamRegex.ReplaceAllStringFunc(path, func(s string) string {
+		name := _openAPIParamRegex.FindStringSubmatch(s)[1]
+		params = append(params, name)
+		return "{" + name + "}"
+	})
+	return r, params
+}
+
+func (x *openAPIBuilder) operation(route *RouteInfo, pathParams []string, authHandlers []string) *OpenAPIOperation {
+	doc := route.Doc
+	if doc == nil {
+		doc = new(ActionDoc)
+	}
+
+	r := &OpenAPIOperation{
+		OperationID: doc.OperationID,
+		Summary:     doc.Summary,
+		Description: doc.Description,
+		Tags:        doc.Tags,
+		Deprecated:  doc.Deprecated,
+		Responses: map[string]*OpenAPIResponse{
+			strconv.Itoa(http.StatusOK): {Description: http.StatusText(http.StatusOK)},
+			_openAPIResponse_Default: {
+				Description: "Error",
+				Content:     map[string]*OpenAPIMediaType{CType_ProblemJson: {Schema: x.schema(_problemType)}},
+			},
+		},
+	}
+	if r.OperationID == "" && route.Area != "" {
+		r.OperationID = route.RouteKey
+	}
+
+	////////// 参数和请求体
+	var requestType reflect.Type
+	if doc.Request != nil {
+		requestType = derefType(reflect.TypeOf(doc.Request))
+	}
+	hasBody := route.Method == http.MethodPost || route.Method == http.MethodPut || route.Method == http.MethodPatch
+	for _, name := range pathParams {
+		param := &OpenAPIParameter{Name: name, In: "path", Required: true, Schema: JSONSchema{"type": "string"}}
+		if sf, ok := findTaggedField(requestType, Tag_Path, name); ok {
+			param.Schema = x.schema(sf.Type)
+			applyValidateRules(param.Schema, sf)
+		}
+		r.Parameters = append(r.Parameters, param)
+	}
+	if requestType != nil && requestType.Kind() == reflect.Struct {
+		r.Parameters = append(r.Parameters, x.queryParameters(requestType, hasBody)...)
+		if hasBody {
+			r.RequestBody = &OpenAPIRequestBody{
+				Required: true,
+				Content:  map[string]*OpenAPIMediaType{"application/json": {Schema: x.schema(requestType)}},
+			}
+		}
+	}
+
+	////////// 响应
+	if doc.Response != nil {
+		r.Responses[strconv.Itoa(http.StatusOK)].Content = map[string]*OpenAPIMediaType{
+			"application/json": {Schema: x.schema(reflect.TypeOf(doc.Response))},
+		}
+	}
+
+	////////// 认证
+	if len(doc.Scopes) > 0 || slices.ContainsFunc(route.Handlers, isAuthHandler(authHandlers)) || slices.ContainsFunc(route.Middleware, isAuthHandler(authHandlers)) {
+		r.Security = []map[string][]string{{OpenAPISecurity_Bearer: append([]string{}, doc.Scopes...)}}
+	}
+	return r
+}
+
+// queryParameters Bind从查询字符串读取的字段，名称取schema标签或字段名
+func (x *openAPIBuilder) queryParameters(t reflect.Type, hasBody bool) []*OpenAPIParameter {
+	var r []*OpenAPIParameter
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && derefType(sf.Type).Kind() == reflect.Struct {
+			r = append(r, x.queryParameters(derefType(sf.Type), hasBody)...)
+			continue
+		}
+		if !sf.IsExported() || sf.Tag.Get(Tag_Path) != "" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(sf.Tag.Get("schema"), ",")
+		if name == "-" || (hasBody && (name == "" || sf.Tag.Get("json") != "-")) {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		param := &OpenAPIParameter{Name: name, In: "query", Schema: x.schema(sf.Type)}
+		param.Required = applyValidateRules(param.Schema, sf)
+		r = append(r, param)
+	}
+	return r
+}
+
+func (x *openAPIBuilder) schema(t reflect.Type) JSONSchema {
+	t = derefType(t)
+	switch {
+	case t == _timeType:
+		return JSONSchema{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return JSONSchema{"type": "string", "contentEncoding": "base64"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return JSONSchema{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return JSONSchema{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return JSONSchema{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return JSONSchema{"type": "integer", "minimum": 0}
+	case reflect.Float32:
+		return JSONSchema{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return JSONSchema{"type": "number", "format": "double"}
+	case reflect.String:
+		return JSONSchema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return JSONSchema{"type": "array", "items": x.schema(t.Elem())}
+	case reflect.Map:
+		return JSONSchema{"type": "object", "additionalProperties": x.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return x.structSchema(t)
+		}
+		return JSONSchema{"$ref": _openAPISchemaRefPrefix + x.schemaName(t)}
+	}
+	return JSONSchema{}
+}
+
+// schemaName 命名结构体在components中的名称，首次遇到时生成Schema，重名时加数字后缀
+func (x *openAPIBuilder) schemaName(t reflect.Type) string {
+	if name, ok := x.names[t]; ok {
+		return name
+	}
+
+	base := _schemaNameRegex.ReplaceAllString(t.Name(), "_")
+	name := base
+	for i := 2; ; i++ {
+		if _, ok := x.schemas[name]; !ok {
+			break
+		}
+		name = base + strconv.Itoa(i)
+	}
+
+	x.names[t] = name
+	x.schemas[name] = JSONSchema{} // 占位，支持递归引用
+	x.schemas[name] = x.structSchema(t)
+	return name
+}
+
+// structSchema 按encoding/json的规则生成对象Schema，嵌入的结构体字段展开
+func (x *openAPIBuilder) structSchema(t reflect.Type) JSONSchema {
+	properties := make(map[string]JSONSchema)
+	var required []string
+	x.addProperties(t, properties, &required)
+
+	r := JSONSchema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		r["required"] = required
+	}
+	return r
+}
+
+func (x *openAPIBuilder) addProperties(t reflect.Type, properties map[string]JSONSchema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" && derefType(sf.Type).Kind() == reflect.Struct {
+			x.addProperties(derefType(sf.Type), properties, required)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		var s JSONSchema
+		if slices.Contains(strings.Split(opts, ","), "string") {
+			s = JSONSchema{"type": "string"}
+		} else {
+			s = x.schema(sf.Type)
+		}
+		if applyValidateRules(s, sf) {
+			*required = append(*required, name)
+		}
+		properties[name] = s
+	}
+}
+
+// applyValidateRules 将validate标签转为Schema关键字，返回是否必填
+func applyValidateRules(s JSONSchema, sf reflect.StructField) bool {
+	tag := sf.Tag.Get(Tag_Validate)
+	if tag == "" || tag == "-" {
+		return false
+	}
+
+	t := derefType(sf.Type)
+	required := false
+	for _, rule := range splitRules(tag) {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "min", "max":
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			if keyword := limitKeyword(t.Kind(), name); keyword != "" {
+				s[keyword] = limit
+			}
+		case "enum":
+			options := strings.Split(arg, "|")
+			values := make([]interface{}, 0, len(options))
+			for _, option := range options {
+				if n, err := strconv.ParseFloat(option, 64); err == nil && t.Kind() != reflect.String {
+					values = append(values, n)
+				} else {
+					values = append(values, option)
+				}
+			}
+			s["enum"] = values
+		case "regex":
+			s["pattern"] = arg
+		}
+	}
+	return required
+}
+
+func limitKeyword(kind reflect.Kind, rule string) string {
+	var keywords [2]string
+	switch kind {
+	case reflect.String:
+		keywords = [2]string{"minLength", "maxLength"}
+	case reflect.Slice, reflect.Array:
+		keywords = [2]string{"minItems", "maxItems"}
+	case reflect.Map:
+		keywords = [2]string{"minProperties", "maxProperties"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		keywords = [2]string{"minimum", "maximum"}
+	default:
+		return ""
+	}
+	if rule == "min" {
+		return keywords[0]
+	}
+	return keywords[1]
+}
+
+func findTaggedField(t reflect.Type, key, name string) (reflect.StructField, bool) {
+	if t == nil || t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if sf := t.Field(i); sf.IsExported() && sf.Tag.Get(key) == name {
+			return sf, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// isAuthHandler 按handlerName去掉包名和方法值后缀后的名称匹配
+func isAuthHandler(names []string) func(string) bool {
+	return func(handler string) bool {
+		handler = strings.TrimSuffix(handler, "-fm")
+		if i := strings.LastIndex(handler, "."); i >= 0 {
+			handler = handler[i+1:]
+		}
+		return slices.Contains(names, handler)
+	}
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,27 @@
+package host_test
+
+import (
+	"io/fs"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncfuture/host"
+	"github.com/syncfuture/host/hosttest"
+)
+
+func TestSwaggerUIHandler(t *testing.T) {
+	h := &host.BaseWebHost{OpenAPI: &host.OpenAPIOptions{SwaggerUIPath: "/docs/"}}
+	assert.Equal(t, "/docs/{filepath:*}", h.OpenAPI.GetSwaggerUIAssetsPath())
+	ctx := hosttest.NewMockHttpContext(http.MethodGet, "/docs/", nil).Run(h.SwaggerUIHandler)
+	assert.Contains(t, ctx.ResponseBody.String(), `<script src="/docs/swagger-ui-bundle.js"`)
+	_, err := fs.Stat(host.SwaggerUIAssets(), "swagger-ui.css")
+	assert.NoError(t, err)
+
+	// 配置资源地址时不提供嵌入的资源
+	h.OpenAPI.SwaggerUIAssetsURL = "https://cdn.example.com/swagger-ui-dist@5/"
+	assert.Empty(t, h.OpenAPI.GetSwaggerUIAssetsPath())
+	ctx = hosttest.NewMockHttpContext(http.MethodGet, "/docs/", nil).Run(h.SwaggerUIHandler)
+	assert.Contains(t, ctx.ResponseBody.String(), `<script src="https://cdn.example.com/swagger-ui-dist@5/swagger-ui-bundle.js"`)
+	assert.Contains(t, ctx.ResponseBody.String(), `href="https://cdn.example.com/swagger-ui-dist@5/swagger-ui.css"`)
+}
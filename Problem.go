@@ -0,0 +1,166 @@
+package host
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/syncfuture/go/sid"
+	"github.com/syncfuture/go/slog"
+	"github.com/syncfuture/go/u"
+)
+
+const (
+	Ctx_Error         = "error"
+	CType_ProblemJson = "application/problem+json"
+	ProblemType_Blank = "about:blank"
+)
+
+var (
+	_problemDetail500 = "internal server error"
+
+	// 可用serr.Wrap包装后传给ctx.Error，ErrorHandler会映射为对应的状态码
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+)
+
+// Problem RFC 7807 错误响应
+type Problem struct {
+	Status   int           `json:"status"`
+	Type     string        `json:"type,omitempty"`
+	Title    string        `json:"title,omitempty"`
+	Detail   string        `json:"detail,omitempty"`
+	Instance string        `json:"instance,omitempty"`
+	ErrorID  string        `json:"errorId,omitempty"`
+	Errors   []*FieldError `json:"errors,omitempty"`
+	cause    error
+}
+
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// WithCause 记录原始错误，只写入日志，不返回给客户端
+func (x *Problem) WithCause(err error) *Problem {
+	x.cause = err
+	return x
+}
+
+func (x *Problem) Error() string {
+	if x.Detail != "" {
+		return x.Detail
+	}
+	return http.StatusText(x.Status)
+}
+
+func (x *Problem) Unwrap() error {
+	return x.cause
+}
+
+// ToProblem 将错误映射为Problem:
+// *Problem原样返回, *ValidationError => 400, ErrBadRequest/ErrUnauthorized/ErrForbidden/ErrNotFound(可被包装) => 400/401/403/404,
+// context.DeadlineExceeded => 504, context.Canceled => 503, 其他 => 500
+func ToProblem(err error) *Problem {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		r := *problem
+		if r.cause == nil && problem != err {
+			r.cause = err
+		}
+		return &r
+	}
+
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return &Problem{
+			Status: http.StatusBadRequest,
+			Detail: verr.Message,
+			Errors: verr.Errors,
+			cause:  err,
+		}
+	}
+
+	r := &Problem{Status: http.StatusInternalServerError, cause: err}
+	switch {
+	case errors.Is(err, ErrBadRequest):
+		r.Status = http.StatusBadRequest
+	case errors.Is(err, ErrUnauthorized):
+		r.Status = http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		r.Status = http.StatusForbidden
+	case errors.Is(err, ErrNotFound):
+		r.Status = http.StatusNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		r.Status = http.StatusGatewayTimeout
+		r.Detail = "request timed out"
+	case errors.Is(err, context.Canceled):
+		r.Status = http.StatusServiceUnavailable
+		r.Detail = "request canceled"
+	}
+	if r.Status < http.StatusInternalServerError {
+		r.Detail = err.Error()
+	}
+	return r
+}
+
+// ErrorHandler 全局中间件，将ctx.Error记录的错误写为application/problem+json
+func ErrorHandler(ctx IHttpContext) {
+	ctx.Next()
+
+	if err, ok := ctx.GetItem(Ctx_Error).(error); ok && err != nil {
+		WriteProblem(ctx, ToProblem(err))
+	}
+}
+
+// WriteProblem 补全Problem的默认字段，记录日志，并写入响应
+func WriteProblem(ctx IHttpContext, problem *Problem) {
+	if problem.Status == 0 {
+		problem.Status = http.StatusInternalServerError
+	}
+	if problem.Type == "" {
+		problem.Type = ProblemType_Blank
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	if problem.Instance == "" {
+		problem.Instance = ctx.RequestPath()
+	}
+	if problem.ErrorID == "" {
+		// 使用请求ID关联响应和日志
+		if problem.ErrorID = GetRequestID(ctx); problem.ErrorID == "" {
+			problem.ErrorID = sid.GenerateID()
+		}
+	}
+
+	if problem.Status >= http.StatusInternalServerError {
+		if problem.Detail == "" {
+			problem.Detail = _problemDetail500
+		}
+		slog.Errorf("[%s] %+v", problem.ErrorID, problem.logError())
+	} else {
+		slog.Debugf("[%s] %d %s %v", problem.ErrorID, problem.Status, problem.Instance, problem.logError())
+	}
+
+	data, err := json.Marshal(problem)
+	if u.LogError(err) {
+		ctx.SetStatusCode(http.StatusInternalServerError)
+		return
+	}
+	ctx.SetStatusCode(problem.Status)
+	ctx.SetContentType(CType_ProblemJson)
+	ctx.WriteBytes(data)
+}
+
+func (x *Problem) logError() error {
+	if x.cause != nil {
+		return x.cause
+	}
+	return x
+}
@@ -0,0 +1,388 @@
+package host
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/syncfuture/go/serr"
+	"github.com/syncfuture/go/slog"
+	"github.com/syncfuture/go/sredis"
+)
+
+const (
+	RateLimitAlgorithm_TokenBucket   = "token_bucket"
+	RateLimitAlgorithm_SlidingWindow = "sliding_window"
+
+	RateLimitKey_IP    = "ip"
+	RateLimitKey_User  = "user"
+	RateLimitKey_Route = "route"
+
+	RateLimitStore_Memory = "memory"
+	RateLimitStore_Redis  = "redis"
+
+	Header_RateLimitLimit     = "X-RateLimit-Limit"
+	Header_RateLimitRemaining = "X-RateLimit-Remaining"
+	Header_RateLimitReset     = "X-RateLimit-Reset"
+	Header_RetryAfter         = "Retry-After"
+
+	_defaultRateLimitKeyPrefix = "ratelimit:"
+	_rateLimitSweepInterval    = time.Minute
+)
+
+type (
+	// RateLimitRule 限流规则，每WindowSeconds秒允许Limit次请求
+	RateLimitRule struct {
+		// Algorithm token_bucket(默认) 或 sliding_window
+		Algorithm     string
+		Limit         int
+		WindowSeconds int
+		// Burst 令牌桶容量，默认等于Limit，仅token_bucket使用
+		Burst int
+		// KeyBy 限流维度: ip、user、route，可组合，默认ip
+		// user取Ctx_UserID，未登录时按ip
+		KeyBy []string
+	}
+
+	// RateLimitOptions 限流配置，Limit大于0时对所有请求启用默认规则
+	// Action.RateLimit可覆盖单个路由的规则
+	RateLimitOptions struct {
+		RateLimitRule
+		// Store memory(默认) 或 redis，redis需通过UseRedisConfig提供BaseHost.RedisConfig
+		Store     string
+		KeyPrefix string
+	}
+
+	RateLimitResult struct {
+		Allowed   bool
+		Limit     int
+		Remaining int
+		// Reset 配额完全恢复的剩余时间
+		Reset time.Duration
+		// RetryAfter 被拒绝时距下次可请求的时间
+		RetryAfter time.Duration
+	}
+
+	// IRateLimitStore 限流计数存储，Allow消耗key的一次配额
+	IRateLimitStore interface {
+		Allow(ctx context.Context, key string, rule *RateLimitRule) (*RateLimitResult, error)
+	}
+)
+
+func (x *RateLimitRule) GetWindow() time.Duration {
+	if x.WindowSeconds <= 0 {
+		return time.Second
+	}
+	return time.Duration(x.WindowSeconds) * time.Second
+}
+
+func (x *RateLimitRule) GetBurst() int {
+	if x.Burst > 0 {
+		return x.Burst
+	}
+	return x.Limit
+}
+
+func (x *RateLimitRule) isSlidingWindow() bool {
+	return x.Algorithm == RateLimitAlgorithm_SlidingWindow
+}
+
+////////// RateLimiter
+
+// RateLimiter 按规则生成限流key并写入X-RateLimit-*响应头，超限时返回429
+type RateLimiter struct {
+	options   *RateLimitOptions
+	store     IRateLimitStore
+	overrides map[string]bool
+}
+
+func NewRateLimiter(options *RateLimitOptions, store IRateLimitStore) *RateLimiter {
+	if options == nil {
+		options = new(RateLimitOptions)
+	}
+	if store == nil {
+		store = NewMemoryRateLimitStore()
+	}
+	return &RateLimiter{
+		options:   options,
+		store:     store,
+		overrides: make(map[string]bool),
+	}
+}
+
+// Handler 使用默认规则的中间件，已通过Override覆盖规则的路由跳过
+func (x *RateLimiter) Handler(ctx IHttpContext) {
+	if x.overrides[ctx.GetRouteKey()] {
+		ctx.Next()
+		return
+	}
+	x.limit(ctx, &x.options.RateLimitRule, "*")
+}
+
+// Override 返回使用指定规则的中间件，并使默认规则不再作用于routeKey，需在宿主运行前调用
+func (x *RateLimiter) Override(routeKey string, rule *RateLimitRule) RequestHandler {
+	x.overrides[routeKey] = true
+	return func(ctx IHttpContext) {
+		x.limit(ctx, rule, routeKey)
+	}
+}
+
+func (x *RateLimiter) limit(ctx IHttpContext, rule *RateLimitRule, scope string) {
+	if rule == nil || rule.Limit <= 0 {
+		ctx.Next()
+		return
+	}
+
+	result, err := x.store.Allow(ctx.Context(), x.buildKey(ctx, rule, scope), rule)
+	if err != nil {
+		// 存储不可用时放行
+		GetLogger(ctx).Errorf("rate limit: %+v", err)
+		ctx.Next()
+		return
+	}
+
+	ctx.SetHeader(Header_RateLimitLimit, strconv.Itoa(result.Limit))
+	ctx.SetHeader(Header_RateLimitRemaining, strconv.Itoa(result.Remaining))
+	ctx.SetHeader(Header_RateLimitReset, strconv.Itoa(ceilSeconds(result.Reset)))
+	if !result.Allowed {
+		ctx.SetHeader(Header_RetryAfter, strconv.Itoa(max(ceilSeconds(result.RetryAfter), 1)))
+		ctx.Error(NewProblem(http.StatusTooManyRequests, "rate limit exceeded"))
+		return
+	}
+
+	ctx.Next()
+}
+
+func (x *RateLimiter) buildKey(ctx IHttpContext, rule *RateLimitRule, scope string) string {
+	keyBy := rule.KeyBy
+	if len(keyBy) == 0 {
+		keyBy = []string{RateLimitKey_IP}
+	}
+
+	prefix := x.options.KeyPrefix
+	if prefix == "" {
+		prefix = _defaultRateLimitKeyPrefix
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	b.WriteString(scope)
+	for _, k := range keyBy {
+		switch k {
+		case RateLimitKey_User:
+			if userID := ctx.GetItemString(Ctx_UserID); userID != "" {
+				b.WriteString("|user:" + userID)
+			} else if !slices.Contains(keyBy, RateLimitKey_IP) {
+				b.WriteString("|ip:" + ctx.GetRemoteIP())
+			}
+		case RateLimitKey_IP:
+			b.WriteString("|ip:" + ctx.GetRemoteIP())
+		case RateLimitKey_Route:
+			b.WriteString("|route:" + ctx.GetRouteKey())
+		}
+	}
+	return b.String()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+////////// MemoryRateLimitStore
+
+type memoryRateLimitEntry struct {
+	tokens  float64
+	last    time.Time
+	hits    []time.Time
+	expires time.Time
+}
+
+// MemoryRateLimitStore : IRateLimitStore, 单实例使用
+type MemoryRateLimitStore struct {
+	entries   map[string]*memoryRateLimitEntry
+	lock      sync.Mutex
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		entries: make(map[string]*memoryRateLimitEntry),
+		now:     time.Now,
+	}
+}
+
+func (x *MemoryRateLimitStore) Allow(_ context.Context, key string, rule *RateLimitRule) (*RateLimitResult, error) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	now := x.now()
+	x.sweep(now)
+
+	entry, ok := x.entries[key]
+	if !ok {
+		entry = &memoryRateLimitEntry{tokens: float64(rule.GetBurst()), last: now}
+		x.entries[key] = entry
+	}
+
+	if rule.isSlidingWindow() {
+		return entry.slidingWindow(now, rule), nil
+	}
+	return entry.tokenBucket(now, rule), nil
+}
+
+// sweep 定期清理过期的key
+func (x *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Before(x.nextSweep) {
+		return
+	}
+	x.nextSweep = now.Add(_rateLimitSweepInterval)
+	for k, v := range x.entries {
+		if now.After(v.expires) {
+			delete(x.entries, k)
+		}
+	}
+}
+
+func (x *memoryRateLimitEntry) tokenBucket(now time.Time, rule *RateLimitRule) *RateLimitResult {
+	burst := float64(rule.GetBurst())
+	rate := float64(rule.Limit) / rule.GetWindow().Seconds() // 每秒补充的令牌数
+
+	x.tokens = min(burst, x.tokens+now.Sub(x.last).Seconds()*rate)
+	x.last = now
+
+	r := &RateLimitResult{Limit: rule.GetBurst()}
+	if x.tokens >= 1 {
+		x.tokens--
+		r.Allowed = true
+	} else {
+		r.RetryAfter = secondsToDuration((1 - x.tokens) / rate)
+	}
+	r.Remaining = int(x.tokens)
+	r.Reset = secondsToDuration((burst - x.tokens) / rate)
+	x.expires = now.Add(r.Reset)
+	return r
+}
+
+func (x *memoryRateLimitEntry) slidingWindow(now time.Time, rule *RateLimitRule) *RateLimitResult {
+	window := rule.GetWindow()
+	start := now.Add(-window)
+	i := 0
+	for i < len(x.hits) && !x.hits[i].After(start) {
+		i++
+	}
+	x.hits = x.hits[i:]
+
+	r := &RateLimitResult{Limit: rule.Limit}
+	if len(x.hits) < rule.Limit {
+		x.hits = append(x.hits, now)
+		r.Allowed = true
+	} else {
+		r.RetryAfter = x.hits[0].Add(window).Sub(now)
+	}
+	r.Remaining = rule.Limit - len(x.hits)
+	r.Reset = x.hits[len(x.hits)-1].Add(window).Sub(now)
+	x.expires = now.Add(r.Reset)
+	return r
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+////////// RedisRateLimitStore
+
+// 返回 {allowed, remaining, reset_ms, retry_ms}
+var _tokenBucketScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1]) or burst
+local last = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - last) * rate)
+local allowed, retry = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+local reset = math.ceil((burst - tokens) / rate)
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', now)
+redis.call('PEXPIRE', KEYS[1], math.max(reset, 1))
+return {allowed, math.floor(tokens), reset, retry}
+`)
+
+// KEYS[1] 请求时间的有序集合，KEYS[2] 生成成员的序号，返回值同上
+var _slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed, retry = 0, 0
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, now .. '-' .. redis.call('INCR', KEYS[2]))
+	redis.call('PEXPIRE', KEYS[2], window)
+	count = count + 1
+	allowed = 1
+else
+	local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+	retry = tonumber(oldest[2]) + window - now
+end
+redis.call('PEXPIRE', KEYS[1], window)
+local newest = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+return {allowed, limit - count, tonumber(newest[2]) + window - now, retry}
+`)
+
+// RedisRateLimitStore : IRateLimitStore, 多实例共享计数
+type RedisRateLimitStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisRateLimitStore(config *sredis.RedisConfig) *RedisRateLimitStore {
+	if config == nil {
+		slog.Fatal("redis config cannot be nil")
+	}
+	return &RedisRateLimitStore{
+		client: sredis.NewClient(config),
+	}
+}
+
+func (x *RedisRateLimitStore) Allow(ctx context.Context, key string, rule *RateLimitRule) (*RateLimitResult, error) {
+	now := time.Now().UnixMilli()
+	window := rule.GetWindow().Milliseconds()
+
+	var values []int64
+	var err error
+	r := &RateLimitResult{}
+	if rule.isSlidingWindow() {
+		r.Limit = rule.Limit
+		// 序号计数器与有序集合使用相同的hash tag，集群模式下位于同一个slot
+		tagged := "{" + key + "}"
+		values, err = _slidingWindowScript.Run(ctx, x.client, []string{tagged, tagged + ":seq"}, rule.Limit, window, now).Int64Slice()
+	} else {
+		r.Limit = rule.GetBurst()
+		rate := float64(rule.Limit) / float64(window) // 每毫秒补充的令牌数
+		values, err = _tokenBucketScript.Run(ctx, x.client, []string{key}, r.Limit, rate, now).Int64Slice()
+	}
+	if err != nil {
+		return nil, serr.WithStack(err)
+	}
+	if len(values) != 4 {
+		return nil, serr.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	r.Allowed = values[0] == 1
+	r.Remaining = int(values[1])
+	r.Reset = time.Duration(values[2]) * time.Millisecond
+	r.RetryAfter = time.Duration(values[3]) * time.Millisecond
+	return r, nil
+}
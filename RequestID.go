@@ -0,0 +1,114 @@
+package host
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/syncfuture/go/sid"
+	"github.com/syncfuture/go/slog"
+)
+
+const (
+	Header_RequestID = "X-Request-ID"
+	Ctx_RequestID    = "requestid"
+
+	_maxRequestIDLength = 128
+)
+
+type requestIDKey struct{}
+
+// RequestIDHandler 接收或生成请求ID，存入Ctx_RequestID并写入响应头
+// 客户端传入的ID不合法（过长或含特殊字符）时重新生成
+func RequestIDHandler(ctx IHttpContext) {
+	id := ctx.GetHeader(Header_RequestID)
+	if !IsValidRequestID(id) {
+		id = sid.GenerateID()
+	}
+
+	ctx.SetItem(Ctx_RequestID, id)
+	ctx.SetHeader(Header_RequestID, id)
+	ctx.Next()
+}
+
+// IsValidRequestID 只允许字母、数字和-_.:，长度不超过128，防止日志注入
+func IsValidRequestID(id string) bool {
+	if id == "" || len(id) > _maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func GetRequestID(ctx IHttpContext) string {
+	return ctx.GetItemString(Ctx_RequestID)
+}
+
+// ContextWithRequestID 将请求ID附加到context，用于gRPC等非IHttpContext的调用链
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	r, _ := ctx.Value(requestIDKey{}).(string)
+	return r
+}
+
+////////// RequestLogger
+
+// RequestLogger 在每行日志前加上[请求ID]，请求处理期间的日志应使用GetLogger(ctx)输出
+type RequestLogger struct {
+	prefix string
+}
+
+func GetLogger(ctx IHttpContext) *RequestLogger {
+	return NewRequestLogger(GetRequestID(ctx))
+}
+
+func NewRequestLogger(requestID string) *RequestLogger {
+	r := new(RequestLogger)
+	if requestID != "" {
+		r.prefix = "[" + requestID + "] "
+	}
+	return r
+}
+
+func (x *RequestLogger) Debug(args ...interface{}) {
+	slog.Debug(x.prefix + fmt.Sprint(args...))
+}
+func (x *RequestLogger) Debugf(format string, args ...interface{}) {
+	slog.Debugf(x.prefix+format, args...)
+}
+func (x *RequestLogger) Info(args ...interface{}) {
+	slog.Info(x.prefix + fmt.Sprint(args...))
+}
+func (x *RequestLogger) Infof(format string, args ...interface{}) {
+	slog.Infof(x.prefix+format, args...)
+}
+func (x *RequestLogger) Warn(args ...interface{}) {
+	slog.Warn(x.prefix + fmt.Sprint(args...))
+}
+func (x *RequestLogger) Warnf(format string, args ...interface{}) {
+	slog.Warnf(x.prefix+format, args...)
+}
+func (x *RequestLogger) Error(args ...interface{}) {
+	slog.Error(x.prefix + fmt.Sprint(args...))
+}
+func (x *RequestLogger) Errorf(format string, args ...interface{}) {
+	slog.Errorf(x.prefix+format, args...)
+}
+
+// LogError 同u.LogError，err不为nil时输出并返回true
+func (x *RequestLogger) LogError(err error) bool {
+	if err == nil {
+		return false
+	}
+	slog.Errorf(x.prefix+"%+v", err)
+	return true
+}
@@ -0,0 +1,87 @@
+package host
+
+import (
+	"strings"
+
+	"github.com/syncfuture/go/slog"
+)
+
+const (
+	// Middleware_BindError 可在路由配置中引用的内置中间件名称
+	Middleware_BindError       = "BindErrorHandler"
+	Middleware_JsonContentType = "JsonContentTypeHandler"
+	// Middleware_Auth OAuth客户端和资源宿主注册的认证中间件名称
+	Middleware_Auth = "AuthHandler"
+)
+
+// RouteConfig 配置文件中声明的Action，Handler和中间件按名称引用通过RegisterHandler、RegisterMiddleware注册的函数
+//
+//	"Routes": [
+//	    {"Method": "GET", "Path": "/orders/{id}", "RouteKey": "shop_order_get", "Handler": "order.get",
+//	     "Middleware": ["AuthHandler"], "RateLimit": {"Limit": 10, "WindowSeconds": 60}, "TimeoutMs": 5000}
+//	]
+type RouteConfig struct {
+	Method   string
+	Path     string
+	RouteKey string
+	Handler  string
+	// Middleware 按顺序在Handler前执行
+	Middleware []string
+	// RateLimit 覆盖此路由的限流规则，需配置宿主的RateLimit
+	RateLimit *RateLimitRule
+	// TimeoutMs 覆盖宿主Timeout.DefaultMs，负数表示不限制
+	TimeoutMs int
+	SkipCSRF  bool
+}
+
+// RegisterHandler 注册可在路由配置中按名称引用的Handler，需在Run前调用
+func (x *BaseWebHost) RegisterHandler(name string, handler RequestHandler) {
+	if x.namedHandlers == nil {
+		x.namedHandlers = make(map[string]RequestHandler)
+	}
+	x.namedHandlers[name] = handler
+}
+
+// RegisterMiddleware 注册可在路由配置中按名称引用的中间件，需在Run前调用
+func (x *BaseWebHost) RegisterMiddleware(name string, handler RequestHandler) {
+	if x.namedMiddleware == nil {
+		x.namedMiddleware = make(map[string]RequestHandler)
+	}
+	x.namedMiddleware[name] = handler
+}
+
+// AddConfiguredActions 添加RouteConfigs中的Action，宿主在Run时注册路由前调用，引用了未注册的Handler或中间件时终止
+func (x *BaseWebHost) AddConfiguredActions() {
+	for _, config := range x.RouteConfigs {
+		x.addAction(x.newConfiguredAction(config))
+	}
+	x.RouteConfigs = nil // 只添加一次
+}
+
+func (x *BaseWebHost) newConfiguredAction(config *RouteConfig) *Action {
+	route := strings.ToUpper(config.Method) + config.Path
+	if config.Method == "" || !strings.HasPrefix(config.Path, "/") || config.RouteKey == "" {
+		slog.Fatal("invalid route config '" + route + "', method, path starting with '/' and route key are required")
+	}
+
+	handler, ok := x.namedHandlers[config.Handler]
+	if !ok {
+		slog.Fatal("handler '" + config.Handler + "' of route '" + route + "' is not registered")
+	}
+
+	handlers := make([]RequestHandler, 0, len(config.Middleware)+1)
+	for _, name := range config.Middleware {
+		middleware, ok := x.namedMiddleware[name]
+		if !ok {
+			slog.Fatal("middleware '" + name + "' of route '" + route + "' is not registered")
+		}
+		handlers = append(handlers, middleware)
+	}
+	handlers = append(handlers, handler)
+
+	r := NewAction(route, config.RouteKey, handlers...)
+	r.RateLimit = config.RateLimit
+	r.TimeoutMs = config.TimeoutMs
+	r.SkipCSRF = config.SkipCSRF
+	return r
+}
@@ -0,0 +1,58 @@
+package host_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syncfuture/host"
+	"github.com/syncfuture/host/hosttest"
+)
+
+func TestAddConfiguredActions(t *testing.T) {
+	var x host.BaseWebHost
+	err := json.Unmarshal([]byte(`{"ListenAddr": ":8080", "RateLimit": {}, "Routes": [
+		{"Method": "POST", "Path": "/orders", "RouteKey": "shop_order_create", "Handler": "order.create",
+		 "Middleware": ["auth", "BindErrorHandler"], "RateLimit": {"Limit": 2, "WindowSeconds": 60}, "SkipCSRF": true}
+	]}`), &x)
+	require.NoError(t, err)
+	require.Len(t, x.RouteConfigs, 1)
+
+	x.BuildBaseWebHost()
+	var trace []string
+	x.RegisterHandler("order.create", func(ctx host.IHttpContext) {
+		trace = append(trace, "handler")
+		ctx.SetItem(host.Ctx_BindError, &host.ValidationError{Message: "invalid order"})
+	})
+	x.RegisterMiddleware("auth", func(ctx host.IHttpContext) {
+		trace = append(trace, "auth")
+		ctx.Next()
+	})
+	x.AddConfiguredActions()
+	x.AddConfiguredActions() // 只添加一次
+
+	action := x.Actions["POST/orders"]
+	require.NotNil(t, action)
+	assert.Equal(t, "shop_order_create", action.RouteKey)
+	assert.Equal(t, "order", action.Controller)
+	assert.True(t, action.SkipCSRF)
+
+	// 按配置的顺序执行中间件，BindErrorHandler写出绑定错误
+	for i := 0; i < 2; i++ {
+		trace = nil
+		ctx := hosttest.NewMockHttpContext(http.MethodPost, "/orders", nil).Run(action.Handlers...)
+		assert.Equal(t, []string{"auth", "handler"}, trace)
+		assert.Equal(t, http.StatusBadRequest, ctx.GetStatusCode())
+		assert.Contains(t, ctx.ResponseBody.String(), "invalid order")
+		assert.Equal(t, "2", ctx.ResponseHeader.Get(host.Header_RateLimitLimit))
+	}
+
+	// 超过Action的限流规则
+	trace = nil
+	ctx := hosttest.NewMockHttpContext(http.MethodPost, "/orders", nil).Run(action.Handlers...)
+	assert.Equal(t, []string{"auth"}, trace)
+	assert.Equal(t, http.StatusTooManyRequests, host.ToProblem(ctx.GetError()).Status)
+	assert.NotEmpty(t, ctx.ResponseHeader.Get(host.Header_RetryAfter))
+}
@@ -0,0 +1,121 @@
+package host
+
+import "strings"
+
+// RouteGroup : IRouteGroup, 带路径前缀和中间件的路由组
+// 组内路由依次执行: 全局前置中间件 -> 组中间件 -> 路由Handlers -> 全局后置中间件
+type RouteGroup struct {
+	webHost  IWebHost
+	prefix   string
+	handlers []RequestHandler
+}
+
+func NewRouteGroup(webHost IWebHost, prefix string, handlers ...RequestHandler) IRouteGroup {
+	return &RouteGroup{
+		webHost:  webHost,
+		prefix:   JoinRoutePath("", prefix),
+		handlers: handlers,
+	}
+}
+
+func (x *RouteGroup) GetPrefix() string {
+	return x.prefix
+}
+
+// Group 创建子路由组，继承当前组的前缀和中间件
+func (x *RouteGroup) Group(prefix string, handlers ...RequestHandler) IRouteGroup {
+	return &RouteGroup{
+		webHost:  x.webHost,
+		prefix:   JoinRoutePath(x.prefix, prefix),
+		handlers: CombineHandlers(x.handlers, handlers),
+	}
+}
+
+// CORS 覆盖组前缀下的跨域策略，options为nil时禁用
+func (x *RouteGroup) CORS(options *CORSOptions) {
+	x.webHost.OverrideCORS(x.prefix, options)
+}
+
+// GET 直接注册的路由以完整路径作为RouteKey，与IWebHost.GET一致
+func (x *RouteGroup) GET(path string, handlers ...RequestHandler) {
+	x.webHost.GET(JoinRoutePath(x.prefix, path), CombineHandlers(x.handlers, handlers)...)
+}
+func (x *RouteGroup) POST(path string, handlers ...RequestHandler) {
+	x.webHost.POST(JoinRoutePath(x.prefix, path), CombineHandlers(x.handlers, handlers)...)
+}
+func (x *RouteGroup) PUT(path string, handlers ...RequestHandler) {
+	x.webHost.PUT(JoinRoutePath(x.prefix, path), CombineHandlers(x.handlers, handlers)...)
+}
+func (x *RouteGroup) PATCH(path string, handlers ...RequestHandler) {
+	x.webHost.PATCH(JoinRoutePath(x.prefix, path), CombineHandlers(x.handlers, handlers)...)
+}
+func (x *RouteGroup) DELETE(path string, handlers ...RequestHandler) {
+	x.webHost.DELETE(JoinRoutePath(x.prefix, path), CombineHandlers(x.handlers, handlers)...)
+}
+func (x *RouteGroup) OPTIONS(path string, handlers ...RequestHandler) {
+	x.webHost.OPTIONS(JoinRoutePath(x.prefix, path), CombineHandlers(x.handlers, handlers)...)
+}
+
+func (x *RouteGroup) WS(path string, handler WebSocketHandler, handlers ...RequestHandler) {
+	x.webHost.WS(JoinRoutePath(x.prefix, path), handler, CombineHandlers(x.handlers, handlers)...)
+}
+
+// AddActionGroups 为ActionGroup加上组前缀和组中间件后添加到宿主
+func (x *RouteGroup) AddActionGroups(actionGroups ...*ActionGroup) {
+	for _, actionGroup := range actionGroups {
+		x.webHost.AddActionGroups(&ActionGroup{
+			Prefix:        JoinRoutePath(x.prefix, actionGroup.Prefix),
+			PreHandlers:   CombineHandlers(x.handlers, actionGroup.PreHandlers),
+			Actions:       actionGroup.Actions,
+			AfterHandlers: actionGroup.AfterHandlers,
+		})
+	}
+}
+
+func (x *RouteGroup) AddActions(actions ...*Action) {
+	x.AddActionGroups(&ActionGroup{
+		Actions: actions,
+	})
+}
+
+func (x *RouteGroup) AddAction(route, routeKey string, handlers ...RequestHandler) {
+	x.AddActions(NewAction(route, routeKey, handlers...))
+}
+
+// JoinRoutePath 拼接路由路径, JoinRoutePath("/api/", "/orders") => "/api/orders"
+func JoinRoutePath(prefix, path string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if path == "" {
+		if prefix == "" {
+			return "/"
+		}
+		return prefix
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return prefix + path
+}
+
+// SplitRoute 拆分路由, "GET/orders" => "GET", "/orders"
+func SplitRoute(route string) (method, path string) {
+	index := strings.Index(route, "/")
+	if index < 0 {
+		return route, "/"
+	}
+	return route[:index], route[index:]
+}
+
+// CombineHandlers 合并中间件到新的切片，避免共用底层数组
+func CombineHandlers(handlerGroups ...[]RequestHandler) []RequestHandler {
+	count := 0
+	for _, handlers := range handlerGroups {
+		count += len(handlers)
+	}
+
+	r := make([]RequestHandler, 0, count)
+	for _, handlers := range handlerGroups {
+		r = append(r, handlers...)
+	}
+	return r
+}
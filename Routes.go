@@ -0,0 +1,173 @@
+package host
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/syncfuture/go/slog"
+	"github.com/syncfuture/go/ssecurity"
+)
+
+const _defaultRouteTablePath = "/debug/routes"
+
+type (
+	// RouteInfo 已注册的路由
+	RouteInfo struct {
+		Method     string `json:"method"`
+		Path       string `json:"path"`
+		RouteKey   string `json:"route_key,omitempty"`
+		Area       string `json:"area,omitempty"`
+		Controller string `json:"controller,omitempty"`
+		Action     string `json:"action,omitempty"`
+		// Handlers 路由的Handler名称，包括路由组和ActionGroup的中间件
+		Handlers []string `json:"handlers,omitempty"`
+		// Middleware 全局前置和后置中间件的名称，原生路由为空
+		Middleware []string `json:"middleware,omitempty"`
+		// Native 原生路由（静态文件、指标、健康检查、令牌端点等）不经过全局中间件
+		Native bool `json:"native,omitempty"`
+		// Doc Action的文档信息
+		Doc *ActionDoc `json:"-"`
+	}
+
+	// RouteTableOptions 路由列表端点配置，配置后以JSON输出Routes()
+	RouteTableOptions struct {
+		// Path 默认/debug/routes
+		Path string
+		// Handlers 在输出前执行，如管理员认证，未调用Next则不输出
+		Handlers []RequestHandler `json:"-"`
+	}
+
+	// routeRegistry 按"方法 路径"记录路由，重复注册时保留最后一次
+	routeRegistry struct {
+		mu     sync.RWMutex
+		routes map[string]*RouteInfo
+	}
+)
+
+func (x *RouteTableOptions) GetPath() string {
+	if x.Path == "" {
+		return _defaultRouteTablePath
+	}
+	return x.Path
+}
+
+func (x *routeRegistry) add(route *RouteInfo) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.routes == nil {
+		x.routes = make(map[string]*RouteInfo)
+	}
+	x.routes[route.Method+" "+route.Path] = route
+}
+
+func (x *routeRegistry) list() []*RouteInfo {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	r := make([]*RouteInfo, 0, len(x.routes))
+	for _, route := range x.routes {
+		clone := *route
+		r = append(r, &clone)
+	}
+	slices.SortFunc(r, func(a, b *RouteInfo) int {
+		return cmp.Or(strings.Compare(a.Path, b.Path), strings.Compare(a.Method, b.Method))
+	})
+	return r
+}
+
+// RecordRoute 记录直接注册的路由，由各宿主的GET、POST等方法调用
+func (x *BaseWebHost) RecordRoute(method, path, routeKey string, handlers []RequestHandler) {
+	x.routes.add(&RouteInfo{
+		Method:   method,
+		Path:     path,
+		RouteKey: routeKey,
+		Handlers: handlerNames(handlers),
+	})
+}
+
+// RecordAction 记录Action，添加Action时调用，运行前即可在Routes()中看到
+func (x *BaseWebHost) RecordAction(action *Action) {
+	method, path := SplitRoute(action.Route)
+	x.routes.add(&RouteInfo{
+		Method:     method,
+		Path:       path,
+		RouteKey:   action.RouteKey,
+		Area:       action.Area,
+		Controller: action.Controller,
+		Action:     action.Action,
+		Handlers:   handlerNames(action.Handlers),
+		Doc:        action.Doc,
+	})
+}
+
+// RecordNativeRoute 记录不经过全局中间件的原生路由，name描述其用途
+func (x *BaseWebHost) RecordNativeRoute(method, path, name string) {
+	x.routes.add(&RouteInfo{
+		Method:   method,
+		Path:     path,
+		Handlers: []string{name},
+		Native:   true,
+	})
+}
+
+// Routes 已注册的路由，按路径和方法排序
+func (x *BaseWebHost) Routes() []*RouteInfo {
+	r := x.routes.list()
+	middleware := handlerNames(CombineHandlers(x.GlobalPreHandlers, x.GlobalSufHandlers))
+	for _, route := range r {
+		if !route.Native {
+			route.Middleware = middleware
+		}
+	}
+	return r
+}
+
+// RouteTableHandler 以JSON输出Routes()
+func (x *BaseWebHost) RouteTableHandler(ctx IHttpContext) {
+	data, err := json.Marshal(x.Routes())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.WriteJsonBytes(data)
+}
+
+// UseRouteProvider 提供BaseHost.RouteProvider，运行前检查Action的RouteKey是否都已在其中定义，需在Run前调用
+func (x *BaseWebHost) UseRouteProvider(provider ssecurity.IRouteProvider) {
+	x.routeProvider = provider
+}
+
+// CheckRouteKeys 返回RouteProvider中不存在的Action RouteKey并记录警告，未提供RouteProvider时返回nil
+// 缺少的RouteKey无法进行权限检查，通常是忘记在路由配置中添加
+func (x *BaseWebHost) CheckRouteKeys() []string {
+	if x.routeProvider == nil {
+		return nil
+	}
+
+	var r []string
+	for _, action := range x.Actions {
+		if action.RouteKey == "" || slices.Contains(r, action.RouteKey) {
+			continue
+		}
+		route, err := x.routeProvider.GetRoute(action.RouteKey)
+		if err != nil || route == nil {
+			r = append(r, action.RouteKey)
+		}
+	}
+
+	slices.Sort(r)
+	for _, key := range r {
+		slog.Warnf("route key '%s' is not defined in route provider", key)
+	}
+	return r
+}
+
+func handlerNames(handlers []RequestHandler) []string {
+	r := make([]string, 0, len(handlers))
+	for _, handler := range handlers {
+		r = append(r, handlerName(handler))
+	}
+	return r
+}
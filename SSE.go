@@ -0,0 +1,180 @@
+package host
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syncfuture/go/serr"
+)
+
+const (
+	Header_LastEventID = "Last-Event-ID"
+	CType_EventStream  = "text/event-stream"
+
+	_defaultHeartbeatInterval = 15 * time.Second
+)
+
+var (
+	ErrStreamClosed = serr.New("event stream is closed")
+)
+
+type (
+	// IEventStream SSE事件流，只能在SSEHandler中使用
+	IEventStream interface {
+		// Send 发送事件，event和id可为空，多行data会拆分为多个data字段
+		Send(event, id, data string) error
+		// LastEventID 客户端重连时通过Last-Event-ID头带上的最后事件ID
+		LastEventID() string
+		// Done 客户端断开或宿主开始关闭时关闭此通道
+		Done() <-chan struct{}
+	}
+
+	// SSEHandler 在处理链执行完毕后运行，返回即结束事件流
+	// 此时IHttpContext已被回收，不能在SSEHandler中使用ctx，需要的值请在调用ctx.SSE前取出
+	SSEHandler func(stream IEventStream)
+
+	SSEOptions struct {
+		// HeartbeatInterval 心跳间隔，同时用于检测客户端断开，小于0不发送，默认15秒
+		HeartbeatInterval time.Duration
+		// Retry 告知客户端断线重连间隔，0不发送
+		Retry time.Duration
+	}
+)
+
+func NewSSEOptions(options ...func(*SSEOptions)) *SSEOptions {
+	r := &SSEOptions{
+		HeartbeatInterval: _defaultHeartbeatInterval,
+	}
+	for _, o := range options {
+		o(r)
+	}
+	return r
+}
+
+// SetSSEHeaders 设置SSE响应头
+func SetSSEHeaders(ctx IHttpContext) {
+	ctx.SetContentType(CType_EventStream)
+	ctx.SetHeader("Cache-Control", "no-cache")
+	ctx.SetHeader("X-Accel-Buffering", "no") // 禁止nginx缓冲
+}
+
+// EventStream : IEventStream, 各宿主SSE实现的公共部分
+type EventStream struct {
+	w           io.Writer
+	flush       func() error
+	lastEventID string
+	lock        sync.Mutex
+	done        chan struct{}
+	doneOnce    sync.Once
+}
+
+func NewEventStream(w io.Writer, flush func() error, lastEventID string) *EventStream {
+	return &EventStream{
+		w:           w,
+		flush:       flush,
+		lastEventID: lastEventID,
+		done:        make(chan struct{}),
+	}
+}
+
+func (x *EventStream) LastEventID() string {
+	return x.lastEventID
+}
+
+func (x *EventStream) Done() <-chan struct{} {
+	return x.done
+}
+
+func (x *EventStream) Close() {
+	x.doneOnce.Do(func() {
+		close(x.done)
+	})
+}
+
+func (x *EventStream) Send(event, id, data string) error {
+	var b strings.Builder
+	if id != "" {
+		b.WriteString("id: " + strings.ReplaceAll(id, "\n", "") + "\n")
+	}
+	if event != "" {
+		b.WriteString("event: " + strings.ReplaceAll(event, "\n", "") + "\n")
+	}
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	return x.write(b.String())
+}
+
+func (x *EventStream) write(s string) error {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	select {
+	case <-x.done:
+		return ErrStreamClosed
+	default:
+	}
+
+	_, err := io.WriteString(x.w, s)
+	if err == nil {
+		err = x.flush()
+	}
+	if err != nil {
+		// 写入失败视为客户端已断开
+		x.Close()
+		return serr.WithStack(err)
+	}
+	return nil
+}
+
+// Serve 发送retry、启动心跳，执行handler直到其返回，cancels中任一通道关闭时结束事件流
+func (x *EventStream) Serve(handler SSEHandler, options *SSEOptions, cancels ...<-chan struct{}) {
+	defer func() {
+		// 等待进行中的写入完成，返回后不能再使用w
+		x.lock.Lock()
+		x.Close()
+		x.lock.Unlock()
+	}()
+
+	if options.Retry > 0 {
+		if x.write("retry: "+strconv.FormatInt(options.Retry.Milliseconds(), 10)+"\n\n") != nil {
+			return
+		}
+	}
+
+	for _, c := range cancels {
+		if c == nil {
+			continue
+		}
+		go func(c <-chan struct{}) {
+			select {
+			case <-c:
+				x.Close()
+			case <-x.done:
+			}
+		}(c)
+	}
+
+	if options.HeartbeatInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(options.HeartbeatInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					x.write(": heartbeat\n\n")
+				case <-x.done:
+					return
+				}
+			}
+		}()
+	}
+
+	handler(x)
+}
@@ -0,0 +1,115 @@
+package host
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+const (
+	Header_StrictTransportSecurity         = "Strict-Transport-Security"
+	Header_ContentTypeOptions              = "X-Content-Type-Options"
+	Header_FrameOptions                    = "X-Frame-Options"
+	Header_ReferrerPolicy                  = "Referrer-Policy"
+	Header_PermissionsPolicy               = "Permissions-Policy"
+	Header_ContentSecurityPolicy           = "Content-Security-Policy"
+	Header_ContentSecurityPolicyReportOnly = "Content-Security-Policy-Report-Only"
+
+	Ctx_CSPNonce = "cspnonce"
+
+	// CSPNoncePlaceholder ContentSecurityPolicy中的占位符，每个请求替换为新的nonce
+	CSPNoncePlaceholder = "{nonce}"
+	// SecurityHeaderDisabled 设为此值时不发送有默认值的响应头
+	SecurityHeaderDisabled = "-"
+
+	_defaultContentTypeOptions = "nosniff"
+	_defaultFrameOptions       = "DENY"
+	_defaultReferrerPolicy     = "strict-origin-when-cross-origin"
+	_cspNonceSize              = 16
+)
+
+// SecurityHeadersOptions 安全响应头配置，为空的项使用默认值，设为"-"不发送
+type SecurityHeadersOptions struct {
+	// HSTSMaxAgeSeconds 大于0时发送Strict-Transport-Security，浏览器只在HTTPS响应中采用
+	HSTSMaxAgeSeconds     int
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// ContentTypeOptions 默认nosniff
+	ContentTypeOptions string
+	// FrameOptions 默认DENY
+	FrameOptions string
+	// ReferrerPolicy 默认strict-origin-when-cross-origin
+	ReferrerPolicy string
+	// PermissionsPolicy 如camera=(), microphone=()，默认不发送
+	PermissionsPolicy string
+	// ContentSecurityPolicy 默认不发送，包含{nonce}时每个请求生成nonce，
+	// 如script-src 'self' 'nonce-{nonce}'，页面通过IHttpContext.GetCSPNonce()取得并写入<script nonce="...">
+	ContentSecurityPolicy string
+	// CSPReportOnly 以Content-Security-Policy-Report-Only发送，只报告不拦截
+	CSPReportOnly bool
+}
+
+// NewSecurityHeadersHandler 创建安全响应头中间件，响应头在执行后续Handler前写入，错误和流式响应同样带有
+func NewSecurityHeadersHandler(options *SecurityHeadersOptions) RequestHandler {
+	var headers [][2]string
+	if options.HSTSMaxAgeSeconds > 0 {
+		hsts := "max-age=" + strconv.Itoa(options.HSTSMaxAgeSeconds)
+		if options.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if options.HSTSPreload {
+			hsts += "; preload"
+		}
+		headers = append(headers, [2]string{Header_StrictTransportSecurity, hsts})
+	}
+	headers = appendSecurityHeader(headers, Header_ContentTypeOptions, options.ContentTypeOptions, _defaultContentTypeOptions)
+	headers = appendSecurityHeader(headers, Header_FrameOptions, options.FrameOptions, _defaultFrameOptions)
+	headers = appendSecurityHeader(headers, Header_ReferrerPolicy, options.ReferrerPolicy, _defaultReferrerPolicy)
+	headers = appendSecurityHeader(headers, Header_PermissionsPolicy, options.PermissionsPolicy, "")
+
+	cspHeader := Header_ContentSecurityPolicy
+	if options.CSPReportOnly {
+		cspHeader = Header_ContentSecurityPolicyReportOnly
+	}
+	csp := options.ContentSecurityPolicy
+	if csp == SecurityHeaderDisabled {
+		csp = ""
+	}
+	useNonce := strings.Contains(csp, CSPNoncePlaceholder)
+	if csp != "" && !useNonce {
+		headers = append(headers, [2]string{cspHeader, csp})
+	}
+
+	return func(ctx IHttpContext) {
+		for _, h := range headers {
+			ctx.SetHeader(h[0], h[1])
+		}
+		if useNonce {
+			nonce := NewCSPNonce()
+			ctx.SetItem(Ctx_CSPNonce, nonce)
+			ctx.SetHeader(cspHeader, strings.ReplaceAll(csp, CSPNoncePlaceholder, nonce))
+		}
+		ctx.Next()
+	}
+}
+
+func appendSecurityHeader(headers [][2]string, key, value, defaultValue string) [][2]string {
+	switch value {
+	case SecurityHeaderDisabled:
+		return headers
+	case "":
+		value = defaultValue
+	}
+	if value == "" {
+		return headers
+	}
+	return append(headers, [2]string{key, value})
+}
+
+// NewCSPNonce 生成128位随机nonce的base64编码
+func NewCSPNonce() string {
+	b := make([]byte, _cspNonceSize)
+	_, _ = rand.Read(b) // crypto/rand.Read不会返回错误
+	return base64.StdEncoding.EncodeToString(b)
+}
@@ -0,0 +1,236 @@
+package host
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/syncfuture/go/slog"
+)
+
+const (
+	_defaultIndexName      = "index.html"
+	_cacheControlNoCache   = "no-cache"
+	_cacheControlImmutable = "public, max-age=31536000, immutable"
+)
+
+var (
+	// 以.或-分隔、至少8位的哈希，如app.3f9a1c2b.js、index-BK3f9s2a.js
+	_fingerprintRegex = regexp.MustCompile(`[.-]([A-Za-z0-9_]{8,})\.[A-Za-z0-9]+$`)
+)
+
+type (
+	// StaticOptions ServeStatic和ServeEmbedFiles的配置
+	StaticOptions struct {
+		// SPA 找不到文件且请求接受text/html时返回IndexName，以便前端路由处理
+		SPA bool
+		// MaxAgeSeconds 普通文件Cache-Control的max-age，默认0，即no-cache（每次使用ETag验证），HTML文件总是no-cache
+		MaxAgeSeconds int
+		// ImmutablePattern 匹配文件名的正则表达式，匹配的文件视为带指纹，缓存一年并标记immutable，
+		// 默认匹配以.或-分隔、至少8位且包含数字的哈希，如app.3f9a1c2b.js、index-BK3f9s2a.js
+		ImmutablePattern string
+	}
+
+	// StaticFileServer 提供fs.FS中的文件，支持SPA回退、ETag、Cache-Control、If-None-Match/If-Modified-Since和Range
+	// 修改时间为零的文件系统（embed.FS）在创建时计算内容哈希作为强ETag，其它按修改时间和大小生成
+	StaticFileServer struct {
+		fsys        fs.FS
+		indexName   string
+		options     *StaticOptions
+		compression *CompressionOptions
+		immutable   *regexp.Regexp
+		etags       map[string]string
+	}
+
+	// StaticFile StaticFileServer.Resolve找到的文件
+	StaticFile struct {
+		File fs.File
+		Info fs.FileInfo
+		// Name 打开的文件名，预压缩时为原文件名
+		Name string
+		// Header 需设置的响应头：Content-Type（可按扩展名确定时）、Content-Encoding、ETag、Cache-Control和Vary
+		Header http.Header
+	}
+)
+
+// NewStaticFileServer indexName为空时使用index.html，compression启用Precompressed时优先返回name.br、name.gz
+func NewStaticFileServer(fsys fs.FS, indexName string, options *StaticOptions, compression *CompressionOptions) *StaticFileServer {
+	if options == nil {
+		options = new(StaticOptions)
+	}
+	if indexName == "" {
+		indexName = _defaultIndexName
+	}
+
+	r := &StaticFileServer{
+		fsys:        fsys,
+		indexName:   indexName,
+		options:     options,
+		compression: compression,
+	}
+	if options.ImmutablePattern != "" {
+		var err error
+		if r.immutable, err = regexp.Compile(options.ImmutablePattern); err != nil {
+			slog.Fatal("invalid static immutable pattern: " + err.Error())
+		}
+	}
+	if info, err := fs.Stat(fsys, "."); err == nil && info.ModTime().IsZero() {
+		r.etags = computeETags(fsys)
+	}
+	return r
+}
+
+// computeETags 计算所有文件内容的哈希，用于不会改变的文件系统
+func computeETags(fsys fs.FS) map[string]string {
+	r := make(map[string]string)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		r[name] = `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+		return nil
+	})
+	if err != nil {
+		slog.Fatal("compute static file etags: " + err.Error())
+	}
+	return r
+}
+
+// ServeHTTP 按r.URL.Path查找文件，需先去掉路由前缀
+func (x *StaticFileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	file := x.Resolve(r.URL.Path, r.Header.Get(Header_AcceptEncoding), strings.Contains(r.Header.Get("Accept"), "text/html"))
+	if file == nil {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, "NOT FOUND")
+		return
+	}
+	defer file.File.Close()
+
+	header := w.Header()
+	for key, values := range file.Header {
+		header[key] = append(header[key], values...)
+	}
+
+	content, ok := file.File.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(file.File)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			slog.Error(err)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+	http.ServeContent(w, r, file.Name, file.Info.ModTime(), content)
+}
+
+// Resolve 按name（已去掉路由前缀）查找文件，找不到且启用SPA、acceptHTML时返回IndexName，都找不到时返回nil
+// 条件请求和Range由调用方根据返回的ETag和修改时间处理，调用方需关闭File
+func (x *StaticFileServer) Resolve(name, acceptEncoding string, acceptHTML bool) *StaticFile {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	file, info, encoding, name := x.open(name, acceptEncoding)
+	fallback := false
+	if file == nil && x.options.SPA && acceptHTML {
+		file, info, encoding, name = x.open(x.indexName, acceptEncoding)
+		fallback = true
+	}
+	if file == nil {
+		return nil
+	}
+
+	header := make(http.Header)
+	if x.compression != nil && x.compression.Precompressed {
+		header.Add(Header_Vary, Header_AcceptEncoding)
+	}
+	if cType := mime.TypeByExtension(path.Ext(name)); cType != "" {
+		header.Set("Content-Type", cType)
+	}
+	if encoding != "" {
+		header.Set(Header_ContentEncoding, encoding)
+	}
+	header.Set("ETag", x.etag(name, encoding, info))
+	header.Set("Cache-Control", x.cacheControl(name, fallback))
+
+	return &StaticFile{
+		File:   file,
+		Info:   info,
+		Name:   name,
+		Header: header,
+	}
+}
+
+// open 打开name，目录时打开其中的IndexName，找不到时file为nil
+func (x *StaticFileServer) open(name, acceptEncoding string) (file fs.File, info fs.FileInfo, encoding, opened string) {
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
+		return nil, nil, "", name
+	}
+	if stat, err := fs.Stat(x.fsys, name); err == nil && stat.IsDir() {
+		name = path.Join(name, x.indexName)
+	}
+
+	file, encoding, err := x.compression.OpenStaticFile(x.fsys, name, acceptEncoding)
+	if err != nil {
+		return nil, nil, "", name
+	}
+	info, err = file.Stat()
+	if err != nil || info.IsDir() {
+		file.Close()
+		return nil, nil, "", name
+	}
+	return file, info, encoding, name
+}
+
+// etag 预压缩文件在原文件的ETag后加上编码
+func (x *StaticFileServer) etag(name, encoding string, info fs.FileInfo) string {
+	r, ok := x.etags[name]
+	if !ok {
+		r = `"` + strconv.FormatInt(info.ModTime().Unix(), 16) + "-" + strconv.FormatInt(info.Size(), 16) + `"`
+	}
+	if encoding != "" {
+		r = strings.TrimSuffix(r, `"`) + "-" + encoding + `"`
+	}
+	return r
+}
+
+func (x *StaticFileServer) cacheControl(name string, fallback bool) string {
+	switch {
+	case fallback || path.Ext(name) == ".html":
+		return _cacheControlNoCache
+	case x.isFingerprinted(path.Base(name)):
+		return _cacheControlImmutable
+	case x.options.MaxAgeSeconds > 0:
+		return "public, max-age=" + strconv.Itoa(x.options.MaxAgeSeconds)
+	default:
+		return _cacheControlNoCache
+	}
+}
+
+func (x *StaticFileServer) isFingerprinted(name string) bool {
+	if x.immutable != nil {
+		return x.immutable.MatchString(name)
+	}
+	m := _fingerprintRegex.FindStringSubmatch(name)
+	return m != nil && strings.ContainsAny(m[1], "0123456789")
+}
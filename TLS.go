@@ -0,0 +1,285 @@
+package host
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/syncfuture/go/serr"
+	"github.com/syncfuture/go/slog"
+)
+
+const (
+	TLSClientAuth_None             = "none"
+	TLSClientAuth_Request          = "request"
+	TLSClientAuth_Require          = "require"
+	TLSClientAuth_VerifyIfGiven    = "verify_if_given"
+	TLSClientAuth_RequireAndVerify = "require_and_verify"
+
+	_defaultTLSReloadInterval = 10 * time.Second
+)
+
+// TLSOptions TLS配置，配置ClientCAFile后启用双向TLS
+type TLSOptions struct {
+	CertFile string
+	KeyFile  string
+	// MinVersion 1.2(默认) 或 1.3
+	MinVersion string
+	// CipherSuites 密码套件名称，如TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256，为空时使用Go的默认值，TLS 1.3不可配置
+	CipherSuites []string
+	// ClientCAFile 验证客户端证书的CA证书(PEM)
+	ClientCAFile string
+	// ClientAuth none、request、require、verify_if_given、require_and_verify，配置ClientCAFile时默认require_and_verify
+	ClientAuth string
+	// ReloadIntervalSeconds 检查证书文件变化的间隔，默认10，负数不自动重新加载
+	ReloadIntervalSeconds int
+}
+
+// TLSClientOptions 连接TLS服务端的配置，配置CertFile和KeyFile时向服务端提供客户端证书
+type TLSClientOptions struct {
+	// CAFile 验证服务端证书的CA证书(PEM)，为空时使用系统根证书
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	// ServerName 验证服务端证书的主机名，为空时使用连接地址中的主机名
+	ServerName string
+	// MinVersion 1.2(默认) 或 1.3
+	MinVersion string
+}
+
+// TLSReloader 持有当前的tls.Config，证书、私钥或客户端CA文件变化时重新加载，新连接使用新的证书
+type TLSReloader struct {
+	options    *TLSOptions
+	nextProtos []string
+	config     atomic.Pointer[tls.Config]
+	mu         sync.Mutex
+	modTimes   []time.Time
+	stop       chan struct{}
+}
+
+// NewTLSReloader 加载证书，nextProtos为ALPN协议，如h2、http/1.1
+func NewTLSReloader(options *TLSOptions, nextProtos ...string) (*TLSReloader, error) {
+	if options.CertFile == "" || options.KeyFile == "" {
+		return nil, serr.New("TLS.CertFile and TLS.KeyFile cannot be empty")
+	}
+	r := &TLSReloader{
+		options:    options,
+		nextProtos: nextProtos,
+		stop:       make(chan struct{}),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload 重新加载所有文件，失败时保留当前配置
+func (x *TLSReloader) Reload() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	modTimes := x.getModTimes()
+	config, err := x.options.load()
+	if err != nil {
+		return err
+	}
+	config.NextProtos = x.nextProtos
+	x.config.Store(config)
+	x.modTimes = modTimes
+	return nil
+}
+
+// TLSConfig 用于Server的配置，每个连接使用最新加载的证书
+func (x *TLSReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		NextProtos: x.nextProtos,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &x.config.Load().Certificates[0], nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return x.config.Load(), nil
+		},
+	}
+}
+
+// Start 按ReloadIntervalSeconds检查文件变化，返回的关闭钩子停止检查
+func (x *TLSReloader) Start() ShutdownHook {
+	interval := time.Duration(x.options.ReloadIntervalSeconds) * time.Second
+	if x.options.ReloadIntervalSeconds == 0 {
+		interval = _defaultTLSReloadInterval
+	}
+	if interval > 0 {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-x.stop:
+					return
+				case <-ticker.C:
+					x.reloadIfChanged()
+				}
+			}
+		}()
+	}
+
+	return func(ctx context.Context) error {
+		close(x.stop)
+		return nil
+	}
+}
+
+func (x *TLSReloader) reloadIfChanged() {
+	modTimes := x.getModTimes()
+	x.mu.Lock()
+	changed := !slices.EqualFunc(modTimes, x.modTimes, time.Time.Equal)
+	x.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	if err := x.Reload(); err != nil {
+		// 证书和私钥可能尚未全部写入，下次检查时重试
+		slog.Warnf("reload tls certificate: %v", err)
+		return
+	}
+	slog.Infof("tls certificate reloaded from %s", x.options.CertFile)
+}
+
+func (x *TLSReloader) getModTimes() []time.Time {
+	files := []string{x.options.CertFile, x.options.KeyFile, x.options.ClientCAFile}
+	r := make([]time.Time, len(files))
+	for i, file := range files {
+		if file == "" {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil {
+			r[i] = info.ModTime()
+		}
+	}
+	return r
+}
+
+// load 读取文件创建tls.Config
+func (x *TLSOptions) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(x.CertFile, x.KeyFile)
+	if err != nil {
+		return nil, serr.WithStack(err)
+	}
+
+	r := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+	if r.MinVersion, err = parseTLSVersion(x.MinVersion); err != nil {
+		return nil, err
+	}
+
+	if len(x.CipherSuites) > 0 {
+		ids := make(map[string]uint16)
+		for _, s := range tls.CipherSuites() {
+			ids[s.Name] = s.ID
+		}
+		for _, name := range x.CipherSuites {
+			id, ok := ids[strings.TrimSpace(name)]
+			if !ok {
+				return nil, serr.Errorf("unsupported or insecure tls cipher suite '%s'", name)
+			}
+			r.CipherSuites = append(r.CipherSuites, id)
+		}
+	}
+
+	if x.ClientCAFile != "" {
+		if r.ClientCAs, err = loadCertPool(x.ClientCAFile); err != nil {
+			return nil, err
+		}
+		r.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	switch x.ClientAuth {
+	case "":
+	case TLSClientAuth_None:
+		r.ClientAuth = tls.NoClientCert
+	case TLSClientAuth_Request:
+		r.ClientAuth = tls.RequestClientCert
+	case TLSClientAuth_Require:
+		r.ClientAuth = tls.RequireAnyClientCert
+	case TLSClientAuth_VerifyIfGiven:
+		r.ClientAuth = tls.VerifyClientCertIfGiven
+	case TLSClientAuth_RequireAndVerify:
+		r.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, serr.Errorf("unsupported tls client auth '%s'", x.ClientAuth)
+	}
+	if r.ClientAuth >= tls.VerifyClientCertIfGiven && r.ClientCAs == nil {
+		return nil, serr.New("TLS.ClientCAFile is required to verify client certificates")
+	}
+
+	return r, nil
+}
+
+// BuildTLSConfig 创建客户端的tls.Config
+func (x *TLSClientOptions) BuildTLSConfig() (*tls.Config, error) {
+	r := &tls.Config{
+		ServerName: x.ServerName,
+	}
+	var err error
+	if r.MinVersion, err = parseTLSVersion(x.MinVersion); err != nil {
+		return nil, err
+	}
+
+	if x.CAFile != "" {
+		if r.RootCAs, err = loadCertPool(x.CAFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if x.CertFile != "" || x.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(x.CertFile, x.KeyFile)
+		if err != nil {
+			return nil, serr.WithStack(err)
+		}
+		r.Certificates = []tls.Certificate{cert}
+	}
+
+	return r, nil
+}
+
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, serr.Errorf("unsupported tls min version '%s'", version)
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, serr.WithStack(err)
+	}
+	r := x509.NewCertPool()
+	if !r.AppendCertsFromPEM(pem) {
+		return nil, serr.Errorf("no certificate found in '%s'", file)
+	}
+	return r, nil
+}
+
+// BuildTLSConfig 由各宿主在监听前调用，启动证书文件检查，未配置TLS时返回nil
+func (x *BaseWebHost) BuildTLSConfig(nextProtos ...string) (*tls.Config, error) {
+	if x.TLS == nil {
+		return nil, nil
+	}
+	reloader, err := NewTLSReloader(x.TLS, nextProtos...)
+	if err != nil {
+		return nil, err
+	}
+	x.AddShutdownHooks(reloader.Start())
+	return reloader.TLSConfig(), nil
+}
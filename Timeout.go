@@ -0,0 +1,82 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// TimeoutOptions 请求超时配置，只作用于通过Action添加的路由
+type TimeoutOptions struct {
+	// DefaultMs Action的默认超时毫秒数，0表示不限制，可被Action.TimeoutMs覆盖
+	DefaultMs int
+}
+
+// NewTimeoutHandler 为ctx.Context()设置截止时间，超时是协作式的，Handler需使用ctx.Context()发起调用或检查其是否结束
+// 处理链返回时context已结束则丢弃已写入的Body并记录错误，由ErrorHandler写为504（超时）或503（服务关闭、客户端断开）
+func NewTimeoutHandler(timeout time.Duration) RequestHandler {
+	return func(ctx IHttpContext) {
+		parent := ctx.Context()
+		if err := parent.Err(); err != nil {
+			ctx.Error(err)
+			return
+		}
+
+		goctx, cancel := context.WithTimeout(parent, timeout)
+		defer func() {
+			cancel()
+			ctx.SetContext(parent)
+		}()
+		ctx.SetContext(goctx)
+
+		ctx.Next()
+
+		err := goctx.Err()
+		if err == nil || ctx.IsBodyStream() {
+			return
+		}
+		if cause, ok := ctx.GetItem(Ctx_Error).(error); ok && cause != nil {
+			ctx.Error(fmt.Errorf("%w: %w", err, cause))
+			return
+		}
+		ctx.SetResponseBody(nil)
+		ctx.Error(err)
+	}
+}
+
+// applyTimeout 将Action的超时中间件插入到最前面，以便认证等中间件也受超时限制
+func (x *BaseWebHost) applyTimeout(action *Action) {
+	timeout := action.TimeoutMs
+	if timeout == 0 && x.Timeout != nil {
+		timeout = x.Timeout.DefaultMs
+	}
+	if timeout <= 0 {
+		return
+	}
+
+	handler := NewTimeoutHandler(time.Duration(timeout) * time.Millisecond)
+	action.Handlers = slices.Insert(slices.Clone(action.Handlers), 0, handler)
+}
+
+// LinkContext 返回在ctx或parent结束时取消的context，并继承parent的截止时间，用于使出站调用随请求超时或取消
+// parent为nil或不会结束时返回ctx，调用结束后需调用返回的stop
+func LinkContext(ctx, parent context.Context) (r context.Context, stop func()) {
+	if parent == nil || parent.Done() == nil {
+		return ctx, func() {}
+	}
+
+	r, cancel := context.WithCancelCause(ctx)
+	cancelDeadline := context.CancelFunc(func() {})
+	if deadline, ok := parent.Deadline(); ok {
+		r, cancelDeadline = context.WithDeadline(r, deadline)
+	}
+	stopAfter := context.AfterFunc(parent, func() {
+		cancel(context.Cause(parent))
+	})
+	return r, func() {
+		stopAfter()
+		cancelDeadline()
+		cancel(context.Canceled)
+	}
+}
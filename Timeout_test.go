@@ -0,0 +1,54 @@
+package host_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncfuture/host"
+	"github.com/syncfuture/host/hosttest"
+)
+
+func TestApplyTimeout(t *testing.T) {
+	x := host.BaseWebHost{Timeout: &host.TimeoutOptions{DefaultMs: 1000}}
+	x.Actions = make(map[string]*host.Action)
+	var deadline time.Time
+	var ok bool
+	handler := func(ctx host.IHttpContext) {
+		deadline, ok = ctx.Context().Deadline()
+	}
+
+	x.AddAction("GET/default", "default", handler)
+	custom := host.NewAction("GET/custom", "custom", handler)
+	custom.TimeoutMs = 50
+	unlimited := host.NewAction("GET/unlimited", "unlimited", handler)
+	unlimited.TimeoutMs = -1
+	slow := host.NewAction("GET/slow", "slow", func(ctx host.IHttpContext) {
+		ctx.WriteString("partial")
+		<-ctx.Context().Done()
+	})
+	slow.TimeoutMs = 10
+	x.AddActions(custom, unlimited, slow)
+
+	// 返回处理时context的剩余时间，没有截止时间时返回0
+	remaining := func(action *host.Action) time.Duration {
+		ok = false
+		start := time.Now()
+		hosttest.NewMockHttpContext(http.MethodGet, "/", nil).Run(action.Handlers...)
+		if !ok {
+			return 0
+		}
+		return deadline.Sub(start)
+	}
+	assert.InDelta(t, time.Second, remaining(x.Actions["GET/default"]), float64(100*time.Millisecond))
+	assert.InDelta(t, 50*time.Millisecond, remaining(custom), float64(40*time.Millisecond))
+	assert.Zero(t, remaining(unlimited))
+
+	// 超时后丢弃已写入的Body并记录错误，处理链返回后恢复原来的context
+	ctx := hosttest.NewMockHttpContext(http.MethodGet, "/slow", nil).Run(slow.Handlers...)
+	assert.ErrorIs(t, ctx.GetError(), context.DeadlineExceeded)
+	assert.Empty(t, ctx.ResponseBody.String())
+	assert.NoError(t, ctx.Context().Err())
+}
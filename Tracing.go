@@ -0,0 +1,252 @@
+package host
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/syncfuture/go/serr"
+	"github.com/syncfuture/go/slog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TracingExporter_OTLP   = "otlp"
+	TracingExporter_Stdout = "stdout"
+	TracingExporter_Memory = "memory"
+
+	_tracerName = "github.com/syncfuture/host"
+)
+
+// TracingOptions 链路追踪配置，使用W3C traceparent/tracestate在HTTP和gRPC间传播
+type TracingOptions struct {
+	ServiceName string
+	// Exporter otlp(默认，OTLP over HTTP)、stdout 或 memory(测试用)
+	Exporter string
+	// Endpoint OTLP接收端地址，如localhost:4318，为空时使用OTEL_EXPORTER_OTLP_ENDPOINT
+	Endpoint string
+	Insecure bool
+	// SampleRatio 根span采样率[0,1]，默认1，有父span时跟随父span
+	SampleRatio *float64
+	// TraceHandlers 为处理链中的每个Handler创建子span
+	TraceHandlers bool
+	// SpanExporter 自定义导出器，指定时忽略Exporter。Exporter为memory时构建后可从此取得*tracetest.InMemoryExporter
+	SpanExporter sdktrace.SpanExporter `json:"-"`
+}
+
+// BuildTracerProvider 创建TracerProvider并设置为全局，同时设置W3C传播器，返回的Shutdown需在宿主关闭时调用
+func (x *TracingOptions) BuildTracerProvider() (*sdktrace.TracerProvider, error) {
+	var processor sdktrace.SpanProcessor
+	if x.SpanExporter == nil {
+		switch x.Exporter {
+		case "", TracingExporter_OTLP:
+			var options []otlptracehttp.Option
+			if x.Endpoint != "" {
+				options = append(options, otlptracehttp.WithEndpoint(x.Endpoint))
+			}
+			if x.Insecure {
+				options = append(options, otlptracehttp.WithInsecure())
+			}
+			exporter, err := otlptracehttp.New(context.Background(), options...)
+			if err != nil {
+				return nil, serr.WithStack(err)
+			}
+			x.SpanExporter = exporter
+		case TracingExporter_Stdout:
+			exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+			if err != nil {
+				return nil, serr.WithStack(err)
+			}
+			x.SpanExporter = exporter
+		case TracingExporter_Memory:
+			x.SpanExporter = tracetest.NewInMemoryExporter()
+		default:
+			return nil, serr.Errorf("unsupported tracing exporter '%s'", x.Exporter)
+		}
+	}
+
+	if _, ok := x.SpanExporter.(*tracetest.InMemoryExporter); ok {
+		processor = sdktrace.NewSimpleSpanProcessor(x.SpanExporter) // 同步导出，测试中结束span后即可读取
+	} else {
+		processor = sdktrace.NewBatchSpanProcessor(x.SpanExporter)
+	}
+
+	ratio := 1.0
+	if x.SampleRatio != nil {
+		ratio = *x.SampleRatio
+	}
+
+	r := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(x.ServiceName))),
+	)
+	otel.SetTracerProvider(r)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return r, nil
+}
+
+// WrapHandlers 创建请求span，TraceHandlers时为每个Handler创建子span
+func (x *TracingOptions) WrapHandlers(handlers []RequestHandler) []RequestHandler {
+	r := make([]RequestHandler, 0, len(handlers)+1)
+	r = append(r, TracingHandler)
+	if !x.TraceHandlers {
+		return append(r, handlers...)
+	}
+	for _, h := range handlers {
+		r = append(r, traceHandler(h))
+	}
+	return r
+}
+
+// TracingHandler 从请求头中提取父span并创建服务端span，之后ctx.Context()中包含此span
+func TracingHandler(ctx IHttpContext) {
+	method := ctx.RequestMethod()
+	route := ctx.GetRouteKey()
+	parent := otel.GetTextMapPropagator().Extract(ctx.Context(), httpContextCarrier{ctx})
+
+	goctx, span := otel.Tracer(_tracerName).Start(parent, method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(method),
+			semconv.HTTPRoute(route),
+			semconv.URLPath(ctx.RequestPath()),
+			semconv.ClientAddress(ctx.GetRemoteIP()),
+		),
+	)
+	defer span.End()
+	ctx.SetContext(goctx)
+
+	ctx.Next()
+
+	status := ctx.GetStatusCode()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if requestID := GetRequestID(ctx); requestID != "" {
+		span.SetAttributes(attribute.String("request.id", requestID))
+	}
+	if err, ok := ctx.GetItem(Ctx_Error).(error); ok && err != nil {
+		span.RecordError(err)
+	}
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
+
+// traceHandler 以handler的函数名创建子span，span包含其调用Next执行的后续Handler
+func traceHandler(handler RequestHandler) RequestHandler {
+	name := handlerName(handler)
+	return func(ctx IHttpContext) {
+		parent := ctx.Context()
+		goctx, span := otel.Tracer(_tracerName).Start(parent, name)
+		defer span.End()
+
+		ctx.SetContext(goctx)
+		handler(ctx)
+		ctx.SetContext(parent)
+	}
+}
+
+func handlerName(handler RequestHandler) string {
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// GetTraceContext 当前span所在的context，用于发起出站调用，同ctx.Context()
+func GetTraceContext(ctx IHttpContext) context.Context {
+	return ctx.Context()
+}
+
+// NewTracingTransport 为出站HTTP请求创建客户端span并注入traceparent
+// parent不为nil时请求随parent超时或取消，且请求的context中没有span时，以parent中的span为父span
+func NewTracingTransport(base http.RoundTripper, parent context.Context) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &tracingTransport{
+		base:   otelhttp.NewTransport(base),
+		parent: parent,
+	}
+}
+
+type tracingTransport struct {
+	base   http.RoundTripper
+	parent context.Context
+}
+
+func (x *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if x.parent == nil {
+		return x.base.RoundTrip(req)
+	}
+
+	goctx, stop := LinkContext(req.Context(), x.parent)
+	if !trace.SpanContextFromContext(goctx).IsValid() {
+		goctx = trace.ContextWithSpan(goctx, trace.SpanFromContext(x.parent))
+	}
+	resp, err := x.base.RoundTrip(req.WithContext(goctx))
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	resp.Body = &stopOnCloseBody{ReadCloser: resp.Body, stop: stop}
+	return resp, nil
+}
+
+// stopOnCloseBody 读取完响应Body前保持LinkContext的context
+type stopOnCloseBody struct {
+	io.ReadCloser
+	stop func()
+}
+
+func (x *stopOnCloseBody) Close() error {
+	err := x.ReadCloser.Close()
+	x.stop()
+	return err
+}
+
+type httpContextCarrier struct {
+	ctx IHttpContext
+}
+
+func (x httpContextCarrier) Get(key string) string {
+	return x.ctx.GetHeader(key)
+}
+func (x httpContextCarrier) Set(key, value string) {
+	x.ctx.SetHeader(key, value)
+}
+func (x httpContextCarrier) Keys() []string {
+	return nil
+}
+
+// buildTracing 在BuildBaseWebHost中调用
+func (x *BaseWebHost) buildTracing() {
+	if x.Tracing == nil {
+		return
+	}
+	if x.Tracing.ServiceName == "" {
+		x.Tracing.ServiceName = filepath.Base(os.Args[0])
+	}
+	provider, err := x.Tracing.BuildTracerProvider()
+	if err != nil {
+		slog.Fatalf("%+v", err)
+	}
+	x.AddShutdownHooks(provider.Shutdown)
+}
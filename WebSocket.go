@@ -0,0 +1,210 @@
+package host
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/syncfuture/go/sconv"
+	"github.com/syncfuture/go/serr"
+)
+
+const (
+	WSMessage_Text   = websocket.TextMessage
+	WSMessage_Binary = websocket.BinaryMessage
+
+	WSClose_Normal          = websocket.CloseNormalClosure
+	WSClose_GoingAway       = websocket.CloseGoingAway
+	WSClose_ProtocolError   = websocket.CloseProtocolError
+	WSClose_Unsupported     = websocket.CloseUnsupportedData
+	WSClose_NoStatus        = websocket.CloseNoStatusReceived
+	WSClose_Abnormal        = websocket.CloseAbnormalClosure
+	WSClose_InvalidPayload  = websocket.CloseInvalidFramePayloadData
+	WSClose_PolicyViolation = websocket.ClosePolicyViolation
+	WSClose_TooBig          = websocket.CloseMessageTooBig
+	WSClose_InternalError   = websocket.CloseInternalServerErr
+
+	_wsWriteWait = 10 * time.Second
+)
+
+type (
+	// IWebSocketConn 与宿主实现无关的WebSocket连接
+	// 读方法只能在一个goroutine中调用，写方法可并发调用
+	IWebSocketConn interface {
+		ReadMessage() (messageType int, data []byte, err error)
+		ReadJSON(objPtr interface{}) error
+		WriteMessage(messageType int, data []byte) error
+		WriteText(text string) error
+		WriteBinary(data []byte) error
+		WriteJSON(v interface{}) error
+		Ping(data []byte) error
+		SetPingHandler(h func(data string) error)
+		SetPongHandler(h func(data string) error)
+		SetReadDeadline(t time.Time) error
+		SetReadLimit(limit int64)
+		// Close 发送关闭帧后关闭连接
+		Close(code int, reason string) error
+		// GetItem 升级前处理链设置的Items（如Ctx_UserID、Ctx_Claims）的快照
+		GetItem(key string) interface{}
+		GetItemString(key string) string
+		GetRouteKey() string
+		RemoteAddr() string
+		Subprotocol() string
+	}
+
+	// WebSocketHandler 升级成功后执行，返回时关闭连接
+	WebSocketHandler func(conn IWebSocketConn)
+
+	WebSocketOptions struct {
+		ReadBufferSize          int
+		WriteBufferSize         int
+		HandshakeTimeoutSeconds int
+		// AllowedOrigins 允许的Origin，"*"允许所有，为空时只允许同源
+		AllowedOrigins    []string
+		Subprotocols      []string
+		EnableCompression bool
+	}
+)
+
+// CheckOrigin 按AllowedOrigins检查Origin，返回ok=false表示使用默认的同源检查
+func (x *WebSocketOptions) CheckOrigin(origin string) (allowed, ok bool) {
+	if x == nil || len(x.AllowedOrigins) == 0 {
+		return false, false
+	}
+	if origin == "" {
+		return true, true // 非浏览器客户端
+	}
+	return slices.Contains(x.AllowedOrigins, "*") || slices.Contains(x.AllowedOrigins, origin), true
+}
+
+func (x *WebSocketOptions) GetHandshakeTimeout() time.Duration {
+	if x == nil {
+		return 0
+	}
+	return time.Duration(x.HandshakeTimeoutSeconds) * time.Second
+}
+
+// IsWebSocketCloseError err是否为指定关闭码的关闭错误
+func IsWebSocketCloseError(err error, codes ...int) bool {
+	return websocket.IsCloseError(err, codes...)
+}
+
+// GetWebSocketCloseCode 从读取错误中取出关闭码，非关闭错误返回WSClose_Abnormal
+func GetWebSocketCloseCode(err error) int {
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		return closeErr.Code
+	}
+	return WSClose_Abnormal
+}
+
+// WebSocketConn : IWebSocketConn
+type WebSocketConn struct {
+	conn      *websocket.Conn
+	items     map[string]interface{}
+	writeLock sync.Mutex
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// NewWebSocketConn shutdown关闭时以WSClose_GoingAway关闭连接
+func NewWebSocketConn(conn *websocket.Conn, items map[string]interface{}, shutdown <-chan struct{}) *WebSocketConn {
+	r := &WebSocketConn{
+		conn:   conn,
+		items:  items,
+		closed: make(chan struct{}),
+	}
+
+	if shutdown != nil {
+		go func() {
+			select {
+			case <-shutdown:
+				r.Close(WSClose_GoingAway, "server is shutting down")
+			case <-r.closed:
+			}
+		}()
+	}
+
+	return r
+}
+
+// Serve 执行handler，返回后关闭连接
+func (x *WebSocketConn) Serve(handler WebSocketHandler) {
+	defer x.Close(WSClose_Normal, "")
+	handler(x)
+}
+
+func (x *WebSocketConn) ReadMessage() (int, []byte, error) {
+	return x.conn.ReadMessage()
+}
+func (x *WebSocketConn) ReadJSON(objPtr interface{}) error {
+	_, data, err := x.conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return serr.WithStack(json.Unmarshal(data, objPtr))
+}
+
+func (x *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	x.writeLock.Lock()
+	defer x.writeLock.Unlock()
+	return x.conn.WriteMessage(messageType, data)
+}
+func (x *WebSocketConn) WriteText(text string) error {
+	return x.WriteMessage(WSMessage_Text, []byte(text))
+}
+func (x *WebSocketConn) WriteBinary(data []byte) error {
+	return x.WriteMessage(WSMessage_Binary, data)
+}
+func (x *WebSocketConn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return serr.WithStack(err)
+	}
+	return x.WriteMessage(WSMessage_Text, data)
+}
+
+func (x *WebSocketConn) Ping(data []byte) error {
+	return x.conn.WriteControl(websocket.PingMessage, data, time.Now().Add(_wsWriteWait))
+}
+func (x *WebSocketConn) SetPingHandler(h func(data string) error) {
+	x.conn.SetPingHandler(h)
+}
+func (x *WebSocketConn) SetPongHandler(h func(data string) error) {
+	x.conn.SetPongHandler(h)
+}
+func (x *WebSocketConn) SetReadDeadline(t time.Time) error {
+	return x.conn.SetReadDeadline(t)
+}
+func (x *WebSocketConn) SetReadLimit(limit int64) {
+	x.conn.SetReadLimit(limit)
+}
+
+func (x *WebSocketConn) Close(code int, reason string) (err error) {
+	x.closeOnce.Do(func() {
+		close(x.closed)
+		msg := websocket.FormatCloseMessage(code, reason)
+		x.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(_wsWriteWait)) // 对方可能已断开，忽略错误
+		err = x.conn.Close()
+	})
+	return
+}
+
+func (x *WebSocketConn) GetItem(key string) interface{} {
+	return x.items[key]
+}
+func (x *WebSocketConn) GetItemString(key string) string {
+	return sconv.ToString(x.items[key])
+}
+func (x *WebSocketConn) GetRouteKey() string {
+	return x.GetItemString(Ctx_RouteKey)
+}
+func (x *WebSocketConn) RemoteAddr() string {
+	return x.conn.RemoteAddr().String()
+}
+func (x *WebSocketConn) Subprotocol() string {
+	return x.conn.Subprotocol()
+}
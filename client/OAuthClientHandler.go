@@ -1,14 +1,13 @@
 package client
 
 import (
-	"context"
 	"fmt"
 	"net/http"
 	"net/url"
 
 	oauth2core "github.com/Lukiya/oauth2go/core"
 	"github.com/pascaldekloe/jwt"
-	"github.com/syncfuture/go/slog"
+	"github.com/syncfuture/go/serr"
 	"github.com/syncfuture/go/srand"
 	"github.com/syncfuture/go/u"
 	"github.com/syncfuture/host"
@@ -73,8 +72,7 @@ func (x *OAuthClientHandler) SignInCallbackHandler(ctx host.IHttpContext) {
 	state := ctx.GetFormString(oauth2core.Form_State)
 	redirectUrl := ctx.GetSessionString(state)
 	if redirectUrl == "" {
-		ctx.WriteString("invalid state")
-		ctx.SetStatusCode(http.StatusBadRequest)
+		ctx.Error(host.NewProblem(http.StatusBadRequest, "invalid state"))
 		return
 	}
 	ctx.RemoveSession(state) // 释放内存
@@ -83,15 +81,13 @@ func (x *OAuthClientHandler) SignInCallbackHandler(ctx host.IHttpContext) {
 	if x.OAuth.PkceRequired {
 		sessionCodeVerifier = ctx.GetSessionString(oauth2core.Form_CodeVerifier)
 		if sessionCodeVerifier == "" {
-			ctx.WriteString("pkce code verifier does not exist in store")
-			ctx.SetStatusCode(http.StatusBadRequest)
+			ctx.Error(host.NewProblem(http.StatusBadRequest, "pkce code verifier does not exist in store"))
 			return
 		}
 		ctx.RemoveSession(oauth2core.Form_CodeVerifier)
 		sessionSodeChallengeMethod = ctx.GetSessionString(oauth2core.Form_CodeChallengeMethod)
 		if sessionCodeVerifier == "" {
-			ctx.WriteString("pkce transformation method does not exist in store")
-			ctx.SetStatusCode(http.StatusBadRequest)
+			ctx.Error(host.NewProblem(http.StatusBadRequest, "pkce transformation method does not exist in store"))
 			return
 		}
 		ctx.RemoveSession(oauth2core.Form_CodeChallengeMethod)
@@ -100,22 +96,20 @@ func (x *OAuthClientHandler) SignInCallbackHandler(ctx host.IHttpContext) {
 		codeChallengeMethod := ctx.GetFormString(oauth2core.Form_CodeChallengeMethod)
 
 		if sessionSodeChallengeMethod != codeChallengeMethod {
-			ctx.WriteString("pkce transformation method does not match")
-			slog.Debugf("session method: '%s', incoming method:'%s'", sessionSodeChallengeMethod, codeChallengeMethod)
-			ctx.SetStatusCode(http.StatusBadRequest)
+			ctx.Error(host.NewProblem(http.StatusBadRequest, "pkce transformation method does not match"))
+			host.GetLogger(ctx).Debugf("session method: '%s', incoming method:'%s'", sessionSodeChallengeMethod, codeChallengeMethod)
 			return
 		} else if (sessionSodeChallengeMethod == oauth2core.Pkce_Plain && codeChallenge != oauth2core.ToSHA256Base64URL(sessionCodeVerifier)) ||
 			(sessionSodeChallengeMethod == oauth2core.Pkce_Plain && codeChallenge != sessionCodeVerifier) {
-			ctx.WriteString("pkce code verifiver and chanllenge does not match")
-			slog.Debugf("session verifiver: '%s', incoming chanllenge:'%s'", sessionCodeVerifier, codeChallenge)
-			ctx.SetStatusCode(http.StatusBadRequest)
+			ctx.Error(host.NewProblem(http.StatusBadRequest, "pkce code verifiver and chanllenge does not match"))
+			host.GetLogger(ctx).Debugf("session verifiver: '%s', incoming chanllenge:'%s'", sessionCodeVerifier, codeChallenge)
 			return
 		}
 	}
 
 	// 交换令牌
 	code := ctx.GetFormString(oauth2core.Form_Code)
-	httpCtx := context.Background()
+	httpCtx := tracingContext(ctx.Context())
 	var oauth2Token *oauth2.Token
 	var err error
 
@@ -144,9 +138,8 @@ func (x *OAuthClientHandler) SignInCallbackHandler(ctx host.IHttpContext) {
 		}
 	}
 
-	if u.LogError(err) {
-		ctx.WriteString(err.Error())
-		ctx.SetStatusCode(http.StatusInternalServerError)
+	if err != nil {
+		ctx.Error(serr.WithStack(err))
 		return
 	}
 
@@ -165,8 +158,7 @@ func (x *OAuthClientHandler) SignInCallbackHandler(ctx host.IHttpContext) {
 		// 重定向到登录前页面
 		ctx.Redirect(redirectUrl, http.StatusFound)
 	} else {
-		ctx.WriteString(err.Error())
-		u.LogError(err)
+		ctx.Error(serr.WithStack(err))
 	}
 }
 func (x *OAuthClientHandler) SignOutHandler(ctx host.IHttpContext) {
@@ -192,15 +184,13 @@ func (x *OAuthClientHandler) SignOutCallbackHandler(ctx host.IHttpContext) {
 	state := ctx.GetFormString(oauth2core.Form_State)
 	returnURL := ctx.GetSessionString(state)
 	if returnURL == "" {
-		ctx.WriteString("invalid state")
-		ctx.SetStatusCode(http.StatusBadRequest)
+		ctx.Error(host.NewProblem(http.StatusBadRequest, "invalid state"))
 		return
 	}
 
 	endSessionID := ctx.GetFormString(oauth2core.Form_EndSessionID)
 	if endSessionID == "" {
-		ctx.WriteString("missing es_id")
-		ctx.SetStatusCode(http.StatusBadRequest)
+		ctx.Error(host.NewProblem(http.StatusBadRequest, "missing es_id"))
 		return
 	}
 
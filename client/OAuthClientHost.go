@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/muesli/cache2go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/syncfuture/go/serr"
 	"github.com/syncfuture/go/slog"
 	"github.com/syncfuture/go/ssecurity"
@@ -16,6 +18,18 @@ import (
 	"golang.org/x/oauth2"
 )
 
+const (
+	_refreshResult_Success = "success"
+	_refreshResult_Failure = "failure"
+)
+
+var (
+	_tokenRefreshTotal = promauto.With(host.MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "oauth_client_token_refresh_total",
+		Help: "Total number of user token refreshes by result.",
+	}, []string{"result"})
+)
+
 type IOAuthClientHost interface {
 	host.IBaseHost
 	host.IWebHost
@@ -46,6 +60,7 @@ type OAuthClientHost struct {
 	ContextTokenStore   host.IContextTokenStore
 	UserLocks           *cache2go.CacheTable
 	CookieEncryptor     ssecurity.ICookieEncryptor
+	clientTokenMu       sync.Mutex
 }
 
 func (x *OAuthClientHost) BuildOAuthClientHost() {
@@ -108,8 +123,9 @@ func (x *OAuthClientHost) BuildOAuthClientHost() {
 	// }
 }
 
+// GetHttpClient 请求会创建客户端span并注入traceparent，父span取自请求的context
 func (x *OAuthClientHost) GetHttpClient() *http.Client {
-	return x.OAuthOptions.ClientCredential.Client(context.Background())
+	return x.OAuthOptions.ClientCredential.Client(tracingContext(nil))
 }
 
 func (x *OAuthClientHost) GetUserHttpClient(ctx host.IHttpContext) (*http.Client, error) {
@@ -154,22 +170,51 @@ func (x *OAuthClientHost) GetUserHttpClient(ctx host.IHttpContext) (*http.Client
 	if err != nil {
 		return nil, err
 	}
-	return oauth2.NewClient(context.Background(), *tokenSource), nil
+	return oauth2.NewClient(tracingContext(ctx.Context()), *tokenSource), nil
+}
+
+// tracingContext 使oauth2创建的客户端使用带链路追踪的Transport，parent为请求的context，请求随其超时或取消
+func tracingContext(parent context.Context) context.Context {
+	client := &http.Client{Transport: host.NewTracingTransport(http.DefaultTransport, parent)}
+	if parent == nil {
+		parent = context.Background()
+	}
+	return context.WithValue(parent, oauth2.HTTPClient, client)
 }
 
+// GetClientToken 令牌不存在或即将过期时使用ctx.Context()请求新令牌，并发的请求等待同一次请求的结果
+// 没有过期时间的令牌一直有效
 func (x *OAuthClientHost) GetClientToken(ctx host.IHttpContext) (*oauth2.Token, error) {
-	return x.OAuthOptions.ClientCredential.Token()
+	x.clientTokenMu.Lock()
+	defer x.clientTokenMu.Unlock()
+
+	credential := x.OAuthOptions.ClientCredential
+	if credential.AccessToken.Valid() {
+		return credential.AccessToken, nil
+	}
+
+	token, err := credential.Config.Token(tracingContext(ctx.Context()))
+	if err != nil {
+		return nil, serr.WithStack(err)
+	}
+	credential.AccessToken = token
+	return token, nil
 }
 
+// GetUserToken 刷新令牌的请求随ctx.Context()超时或取消，返回的TokenSource不应在请求结束后使用
 func (x *OAuthClientHost) GetUserToken(ctx host.IHttpContext) (*oauth2.TokenSource, error) {
-	goctx := context.Background()
+	goctx := tracingContext(ctx.Context())
 	userID := host.GetUserID(ctx, x.UserIDSessionKey)
 	if userID == "" {
 		return nil, serr.New("user isn't authenticated")
 	}
 
 	// 获取用户锁
-	userLock := x.GetUserLock(userID)
+	userLock, err := x.getUserLock(userID)
+	if err != nil {
+		host.GetLogger(ctx).LogError(err)
+		return nil, err
+	}
 
 	// read lock
 	userLock.RLock()
@@ -184,11 +229,13 @@ func (x *OAuthClientHost) GetUserToken(ctx host.IHttpContext) (*oauth2.TokenSour
 	newToken, err := tokenSource.Token()
 	if err != nil {
 		// refresh token failed, sign user out
+		_tokenRefreshTotal.WithLabelValues(_refreshResult_Failure).Inc()
 		host.SignOut(ctx, x.TokenCookieName)
 		return nil, serr.WithStack(err)
 	}
 
 	if newToken.AccessToken != t.AccessToken {
+		_tokenRefreshTotal.WithLabelValues(_refreshResult_Success).Inc()
 		// token been refreshed, lock
 		userLock.Lock()
 		// save token to session
@@ -206,8 +253,7 @@ func (x *OAuthClientHost) GetUserToken(ctx host.IHttpContext) (*oauth2.TokenSour
 func (x *OAuthClientHost) AuthHandler(ctx host.IHttpContext) {
 	routeKey := ctx.GetItemString(host.Ctx_RouteKey)
 	if routeKey == "" {
-		ctx.SetStatusCode(500)
-		ctx.WriteString("route key does not exist")
+		ctx.Error(serr.New("route key does not exist"))
 		return
 	}
 
@@ -255,13 +301,22 @@ func (x *OAuthClientHost) AuthHandler(ctx host.IHttpContext) {
 }
 
 func (x *OAuthClientHost) GetUserLock(userID string) *sync.RWMutex {
+	userLock, err := x.getUserLock(userID)
+	u.LogError(err)
+	return userLock
+}
+
+// getUserLock 返回错误，由有请求上下文的调用方记录请求ID
+func (x *OAuthClientHost) getUserLock(userID string) (*sync.RWMutex, error) {
 	if !x.UserLocks.Exists(userID) {
 		x.UserLocks.Add(userID, time.Second*30, new(sync.RWMutex))
 	}
 
 	userLockCache, err := x.UserLocks.Value(userID)
-	u.LogError(err)
-	return userLockCache.Data().(*sync.RWMutex)
+	if err != nil {
+		return nil, serr.WithStack(err)
+	}
+	return userLockCache.Data().(*sync.RWMutex), nil
 }
 
 func (x *OAuthClientHost) GetUserJsonSessionKey() string {
@@ -0,0 +1,57 @@
+package client_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Lukiya/oauth2go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syncfuture/host"
+	"github.com/syncfuture/host/client"
+	"github.com/syncfuture/host/hosttest"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+func TestClientToken(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := fetches.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		// 没有expires_in，令牌的Expiry为零值
+		fmt.Fprintf(w, `{"access_token":"t%d","token_type":"bearer"}`, n)
+	}))
+	defer server.Close()
+
+	h := &client.OAuthClientHost{OAuthOptions: &host.OAuthOptions{ClientCredential: &oauth2go.ClientCredential{
+		Config: &clientcredentials.Config{ClientID: "id", ClientSecret: "secret", TokenURL: server.URL},
+	}}}
+
+	// 并发的请求共享同一次请求的令牌
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := h.GetClientToken(hosttest.NewMockHttpContext(http.MethodGet, "/", nil))
+			if assert.NoError(t, err) {
+				assert.Equal(t, "t1", token.AccessToken)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 1, fetches.Load())
+
+	// 过期后重新请求
+	h.OAuthOptions.ClientCredential.AccessToken.Expiry = time.Now().Add(-time.Minute)
+	token, err := h.GetClientToken(hosttest.NewMockHttpContext(http.MethodGet, "/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "t2", token.AccessToken)
+	assert.EqualValues(t, 2, fetches.Load())
+}
@@ -1,7 +1,250 @@
 package host
 
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	Header_Origin                        = "Origin"
+	Header_AccessControlRequestMethod    = "Access-Control-Request-Method"
+	Header_AccessControlRequestHeaders   = "Access-Control-Request-Headers"
+	Header_AccessControlAllowOrigin      = "Access-Control-Allow-Origin"
+	Header_AccessControlAllowCredentials = "Access-Control-Allow-Credentials"
+	Header_AccessControlAllowMethods     = "Access-Control-Allow-Methods"
+	Header_AccessControlAllowHeaders     = "Access-Control-Allow-Headers"
+	Header_AccessControlExposeHeaders    = "Access-Control-Expose-Headers"
+	Header_AccessControlMaxAge           = "Access-Control-Max-Age"
+
+	// RouteKey_CORSPreflight 没有对应OPTIONS路由的预检请求使用的RouteKey
+	RouteKey_CORSPreflight = "CORSPreflight"
+)
+
+var _defaultCORSMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// CORSOptions 跨域配置
 type CORSOptions struct {
-	AllowedOrigin  string
-	AllowedMethods string
-	AllowedHeaders string
+	// AllowedOrigins 允许的来源，如https://example.com；*表示任意来源；https://*.example.com匹配任意层子域名
+	AllowedOrigins []string
+	// AllowedMethods 预检请求允许的方法，默认GET、HEAD、POST、PUT、PATCH、DELETE
+	AllowedMethods []string
+	// AllowedHeaders 预检请求允许的请求头，*表示允许请求的所有头
+	AllowedHeaders []string
+	// ExposedHeaders 允许浏览器读取的响应头
+	ExposedHeaders []string
+	// AllowCredentials 允许携带Cookie，此时总是回显来源而不是*
+	AllowCredentials bool
+	// MaxAgeSeconds 预检结果的缓存时间，0不发送
+	MaxAgeSeconds int
+}
+
+// corsPolicy 预处理后的CORSOptions
+type corsPolicy struct {
+	anyOrigin     bool
+	origins       []string
+	wildcards     [][2]string // 前缀和后缀，如https://和.example.com
+	methods       []string
+	anyHeader     bool
+	headers       []string
+	allowMethods  string
+	allowHeaders  string
+	exposeHeaders string
+	credentials   bool
+	maxAge        string
+}
+
+func newCORSPolicy(options *CORSOptions) *corsPolicy {
+	r := &corsPolicy{
+		exposeHeaders: strings.Join(options.ExposedHeaders, ", "),
+		credentials:   options.AllowCredentials,
+	}
+	for _, origin := range options.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		switch {
+		case origin == "*":
+			r.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			i := strings.Index(origin, "*")
+			r.wildcards = append(r.wildcards, [2]string{origin[:i], origin[i+1:]})
+		default:
+			r.origins = append(r.origins, origin)
+		}
+	}
+	methods := options.AllowedMethods
+	if len(methods) == 0 {
+		methods = _defaultCORSMethods
+	}
+	for _, m := range methods {
+		r.methods = append(r.methods, strings.ToUpper(m))
+	}
+	r.allowMethods = strings.Join(r.methods, ", ")
+	for _, h := range options.AllowedHeaders {
+		if h == "*" {
+			r.anyHeader = true
+		} else {
+			r.headers = append(r.headers, http.CanonicalHeaderKey(h))
+		}
+	}
+	r.allowHeaders = strings.Join(r.headers, ", ")
+	if options.MaxAgeSeconds > 0 {
+		r.maxAge = strconv.Itoa(options.MaxAgeSeconds)
+	}
+	return r
+}
+
+func (x *corsPolicy) isOriginAllowed(origin string) bool {
+	if x.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if slices.Contains(x.origins, origin) {
+		return true
+	}
+	for _, w := range x.wildcards {
+		// 至少包含一级子域名
+		if len(origin) > len(w[0])+len(w[1]) && strings.HasPrefix(origin, w[0]) && strings.HasSuffix(origin, w[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (x *corsPolicy) isHeadersAllowed(requested string) bool {
+	if x.anyHeader || requested == "" {
+		return true
+	}
+	for _, h := range strings.Split(requested, ",") {
+		if h = strings.TrimSpace(h); h != "" && !slices.Contains(x.headers, http.CanonicalHeaderKey(h)) {
+			return false
+		}
+	}
+	return true
+}
+
+////////// CORSPolicies
+
+// CORSPolicies 全局和按路径前缀覆盖的跨域策略，最长前缀优先
+type CORSPolicies struct {
+	global    *corsPolicy
+	mu        sync.RWMutex
+	overrides []corsOverride
+}
+
+type corsOverride struct {
+	prefix string
+	policy *corsPolicy // nil表示该前缀下禁用CORS
+}
+
+// NewCORSPolicies options为nil时只有Override的前缀启用CORS
+func NewCORSPolicies(options *CORSOptions) *CORSPolicies {
+	r := new(CORSPolicies)
+	if options != nil {
+		r.global = newCORSPolicy(options)
+	}
+	return r
+}
+
+// Override 覆盖路径前缀下的跨域策略，options为nil时禁用
+func (x *CORSPolicies) Override(pathPrefix string, options *CORSOptions) {
+	o := corsOverride{prefix: JoinRoutePath("", pathPrefix)}
+	if options != nil {
+		o.policy = newCORSPolicy(options)
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.overrides = slices.DeleteFunc(x.overrides, func(v corsOverride) bool { return v.prefix == o.prefix })
+	x.overrides = append(x.overrides, o)
+	sort.SliceStable(x.overrides, func(i, j int) bool { return len(x.overrides[i].prefix) > len(x.overrides[j].prefix) })
+}
+
+func (x *CORSPolicies) match(path string) *corsPolicy {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	for _, o := range x.overrides {
+		if o.prefix == "/" || path == o.prefix || strings.HasPrefix(path, o.prefix+"/") {
+			return o.policy
+		}
+	}
+	return x.global
+}
+
+// Handler 全局前置中间件，为允许的来源添加CORS响应头
+// 预检请求若有用户注册的OPTIONS路由，添加响应头后继续执行该路由，否则由PreflightHandler响应
+func (x *CORSPolicies) Handler(ctx IHttpContext) {
+	policy := x.match(ctx.RequestPath())
+	if policy == nil {
+		ctx.Next()
+		return
+	}
+
+	AddVary(ctx, Header_Origin)
+	origin := ctx.GetHeader(Header_Origin)
+	if origin == "" || !policy.isOriginAllowed(origin) {
+		ctx.Next()
+		return
+	}
+
+	if IsPreflight(ctx) {
+		AddVary(ctx, Header_AccessControlRequestMethod)
+		AddVary(ctx, Header_AccessControlRequestHeaders)
+		requestedHeaders := ctx.GetHeader(Header_AccessControlRequestHeaders)
+		if !slices.Contains(policy.methods, strings.ToUpper(ctx.GetHeader(Header_AccessControlRequestMethod))) || !policy.isHeadersAllowed(requestedHeaders) {
+			ctx.Next() // 不添加响应头，浏览器会拒绝实际请求
+			return
+		}
+
+		setAllowOrigin(ctx, policy, origin)
+		ctx.SetHeader(Header_AccessControlAllowMethods, policy.allowMethods)
+		switch {
+		case policy.anyHeader && requestedHeaders != "":
+			ctx.SetHeader(Header_AccessControlAllowHeaders, requestedHeaders)
+		case policy.allowHeaders != "":
+			ctx.SetHeader(Header_AccessControlAllowHeaders, policy.allowHeaders)
+		}
+		if policy.maxAge != "" {
+			ctx.SetHeader(Header_AccessControlMaxAge, policy.maxAge)
+		}
+	} else {
+		setAllowOrigin(ctx, policy, origin)
+		if policy.exposeHeaders != "" {
+			ctx.SetHeader(Header_AccessControlExposeHeaders, policy.exposeHeaders)
+		}
+	}
+
+	ctx.Next()
+}
+
+// PreflightHandler 响应没有对应OPTIONS路由的预检请求，CORS响应头已由Handler添加
+func (x *CORSPolicies) PreflightHandler(ctx IHttpContext) {
+	ctx.SetStatusCode(http.StatusNoContent)
+}
+
+func setAllowOrigin(ctx IHttpContext, policy *corsPolicy, origin string) {
+	if policy.credentials {
+		ctx.SetHeader(Header_AccessControlAllowOrigin, origin)
+		ctx.SetHeader(Header_AccessControlAllowCredentials, "true")
+	} else if policy.anyOrigin {
+		ctx.SetHeader(Header_AccessControlAllowOrigin, "*")
+	} else {
+		ctx.SetHeader(Header_AccessControlAllowOrigin, origin)
+	}
+}
+
+// IsPreflight 是否为CORS预检请求：带Origin和Access-Control-Request-Method的OPTIONS请求
+func IsPreflight(ctx IHttpContext) bool {
+	return ctx.RequestMethod() == http.MethodOptions &&
+		ctx.GetHeader(Header_Origin) != "" &&
+		ctx.GetHeader(Header_AccessControlRequestMethod) != ""
+}
+
+// IsPreflightRequest 同IsPreflight，用于标准库请求
+func IsPreflightRequest(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get(Header_Origin) != "" &&
+		r.Header.Get(Header_AccessControlRequestMethod) != ""
 }
@@ -1,10 +1,20 @@
 package host
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/pascaldekloe/jwt"
 	"github.com/stretchr/testify/assert"
+	"github.com/syncfuture/go/ssecurity"
 	"github.com/syncfuture/go/u"
 )
 
@@ -14,3 +24,520 @@ func TestJwt(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, a)
 }
+
+func TestLifecycleShutdown(t *testing.T) {
+	var x Lifecycle
+	var order []int
+	x.AddShutdownHooks(func(ctx context.Context) error {
+		order = append(order, 1)
+		return nil
+	}, func(ctx context.Context) error {
+		order = append(order, 2)
+		return errors.New("hook failed")
+	})
+
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- x.RunUntilSignal(context.Background(), func() error {
+			close(started)
+			<-stopped
+			return nil
+		}, nil)
+	}()
+	<-started
+
+	err := x.GracefulShutdown(time.Second, func(ctx context.Context) error {
+		close(stopped)
+		return nil
+	})
+	assert.EqualError(t, err, "hook failed")
+	assert.Equal(t, []int{2, 1}, order)
+	assert.True(t, x.IsShuttingDown())
+	assert.EqualError(t, <-done, "hook failed")
+
+	// 只执行一次
+	assert.EqualError(t, x.GracefulShutdown(time.Second, nil), "hook failed")
+}
+
+func TestLifecycleContextCancel(t *testing.T) {
+	var x Lifecycle
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	shutdown := func(timeout time.Duration) error {
+		return x.GracefulShutdown(timeout, func(ctx context.Context) error {
+			close(stopped)
+			return nil
+		})
+	}
+
+	cancel()
+	err := x.RunUntilSignal(ctx, func() error {
+		<-stopped
+		return nil
+	}, shutdown)
+	assert.NoError(t, err)
+	assert.True(t, x.IsShuttingDown())
+}
+
+func TestJoinRoutePath(t *testing.T) {
+	assert.Equal(t, "/", JoinRoutePath("", ""))
+	assert.Equal(t, "/api", JoinRoutePath("/api/", ""))
+	assert.Equal(t, "/api/orders", JoinRoutePath("/api/", "/orders"))
+	assert.Equal(t, "/api/orders", JoinRoutePath("/api", "orders"))
+	assert.Equal(t, "/api/", JoinRoutePath("/api", "/"))
+}
+
+func TestNewAction(t *testing.T) {
+	handler := func(ctx IHttpContext) {}
+
+	a := NewAction("GET/orders", "shop_order_list", handler)
+	assert.Equal(t, []string{"shop", "order", "list"}, []string{a.Area, a.Controller, a.Action})
+
+	a = NewAction("GET/orders", "shop_order", handler)
+	assert.Equal(t, []string{"shop", "order", ""}, []string{a.Area, a.Controller, a.Action})
+
+	a = NewAction("GET/orders", "shop", handler)
+	assert.Equal(t, []string{"shop", "", ""}, []string{a.Area, a.Controller, a.Action})
+}
+
+func TestValidate(t *testing.T) {
+	type item struct {
+		SKU string `json:"sku" validate:"required"`
+	}
+	type order struct {
+		Count *int     `json:"count" validate:"min=1"`
+		Tags  []string `json:"tags" validate:"max=2"`
+		Code  string   `json:"code" validate:"required,regex=^[a-z]{2,3}$"`
+		Items []item   `json:"items"`
+	}
+
+	assert.NoError(t, Validate(&order{Code: "ab", Items: []item{{SKU: "x"}}}))
+
+	zero := 0
+	err := Validate(&order{Count: &zero, Tags: []string{"a", "b", "c"}, Code: "ABCD", Items: []item{{}}})
+	var verr *ValidationError
+	assert.True(t, errors.As(err, &verr))
+	if assert.Len(t, verr.Errors, 4) {
+		assert.Equal(t, "count", verr.Errors[0].Field)
+		assert.Equal(t, "tags", verr.Errors[1].Field)
+		assert.Equal(t, "code", verr.Errors[2].Field)
+		assert.Equal(t, "regex", verr.Errors[2].Rule)
+		assert.Equal(t, "items[0].sku", verr.Errors[3].Field)
+	}
+
+	// 指针字段的零值满足required，数值的零值同样按enum校验
+	type flags struct {
+		Enabled *bool  `json:"enabled" validate:"required"`
+		Level   int    `json:"level" validate:"enum=1|2"`
+		Mode    string `json:"mode" validate:"enum=a|b"`
+	}
+	disabled := false
+	err = Validate(&flags{Enabled: &disabled})
+	assert.True(t, errors.As(err, &verr))
+	if assert.Len(t, verr.Errors, 1) {
+		assert.Equal(t, "level", verr.Errors[0].Field)
+		assert.Equal(t, "enum", verr.Errors[0].Rule)
+	}
+	err = Validate(&flags{Level: 2})
+	assert.True(t, errors.As(err, &verr))
+	if assert.Len(t, verr.Errors, 1) {
+		assert.Equal(t, "enabled", verr.Errors[0].Field)
+		assert.Equal(t, "required", verr.Errors[0].Rule)
+	}
+}
+
+func TestCheckValidateTags(t *testing.T) {
+	type node struct {
+		Name     string  `json:"name" validate:"required"`
+		Children []*node `json:"children"`
+	}
+	assert.NoError(t, CheckValidateTags(node{}))
+	assert.NoError(t, CheckValidateTags(&testOrderUpdate{}))
+	assert.NoError(t, CheckValidateTags(nil))
+
+	type badRegex struct {
+		Code string `validate:"regex=^[a-z"`
+	}
+	type badLimit struct {
+		Name string `validate:"min=x"`
+	}
+	type unknownRule struct {
+		Name string `validate:"email"`
+	}
+	type requiredInt struct {
+		Count int `validate:"required"`
+	}
+	type nested struct {
+		Items []badLimit
+	}
+	for _, obj := range []interface{}{badRegex{}, badLimit{}, unknownRule{}, requiredInt{}, &nested{}} {
+		// 请求时返回同样的错误而不是panic
+		if err := CheckValidateTags(obj); assert.Error(t, err, "%T", obj) {
+			assert.EqualError(t, Validate(obj), err.Error(), "%T", obj)
+		}
+	}
+}
+
+func TestToProblem(t *testing.T) {
+	p := ToProblem(fmt.Errorf("user 1: %w", ErrForbidden))
+	assert.Equal(t, http.StatusForbidden, p.Status)
+	assert.Equal(t, "user 1: forbidden", p.Detail)
+
+	p = ToProblem(&ValidationError{Message: "validation failed", Errors: []*FieldError{{Field: "name"}}})
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Len(t, p.Errors, 1)
+
+	shared := NewProblem(http.StatusConflict, "conflict")
+	p = ToProblem(fmt.Errorf("wrapped: %w", shared))
+	assert.Equal(t, http.StatusConflict, p.Status)
+	assert.NotSame(t, shared, p)
+
+	p = ToProblem(errors.New("secret"))
+	assert.Equal(t, http.StatusInternalServerError, p.Status)
+	assert.Empty(t, p.Detail)
+
+	p = ToProblem(fmt.Errorf("call order service: %w", context.DeadlineExceeded))
+	assert.Equal(t, http.StatusGatewayTimeout, p.Status)
+	assert.Equal(t, "request timed out", p.Detail)
+
+	p = ToProblem(fmt.Errorf("%w: %w", context.DeadlineExceeded, context.Canceled))
+	assert.Equal(t, http.StatusGatewayTimeout, p.Status)
+
+	p = ToProblem(context.Canceled)
+	assert.Equal(t, http.StatusServiceUnavailable, p.Status)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	supported := []string{Encoding_Brotli, Encoding_Zstd, Encoding_Gzip}
+	assert.Equal(t, "", NegotiateEncoding("", supported))
+	assert.Equal(t, Encoding_Gzip, NegotiateEncoding("gzip, deflate", supported))
+	assert.Equal(t, Encoding_Brotli, NegotiateEncoding("gzip, br", supported))
+	assert.Equal(t, Encoding_Gzip, NegotiateEncoding("br;q=0.2, GZIP;q=0.9", supported))
+	assert.Equal(t, Encoding_Zstd, NegotiateEncoding("*, br;q=0", supported))
+	assert.Equal(t, "", NegotiateEncoding("identity", supported))
+
+	o := &CompressionOptions{}
+	assert.True(t, o.IsCompressible("application/problem+json"))
+	assert.True(t, o.IsCompressible("text/html; charset=utf-8"))
+	assert.False(t, o.IsCompressible("image/png"))
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return now }
+
+	// 令牌桶: 容量3，每秒补充1个
+	bucket := &RateLimitRule{Limit: 10, WindowSeconds: 10, Burst: 3}
+	for i := 2; i >= 0; i-- {
+		r, err := store.Allow(context.Background(), "bucket", bucket)
+		assert.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 3, r.Limit)
+		assert.Equal(t, i, r.Remaining)
+	}
+	r, _ := store.Allow(context.Background(), "bucket", bucket)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, time.Second, r.RetryAfter)
+	assert.Equal(t, 3*time.Second, r.Reset)
+
+	now = now.Add(time.Second)
+	r, _ = store.Allow(context.Background(), "bucket", bucket)
+	assert.True(t, r.Allowed)
+
+	// 滑动窗口: 10秒内2次
+	window := &RateLimitRule{Algorithm: RateLimitAlgorithm_SlidingWindow, Limit: 2, WindowSeconds: 10}
+	r, _ = store.Allow(context.Background(), "window", window)
+	assert.True(t, r.Allowed)
+	now = now.Add(4 * time.Second)
+	r, _ = store.Allow(context.Background(), "window", window)
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 0, r.Remaining)
+	r, _ = store.Allow(context.Background(), "window", window)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, 6*time.Second, r.RetryAfter)
+
+	now = now.Add(6 * time.Second)
+	r, _ = store.Allow(context.Background(), "window", window)
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 0, r.Remaining)
+	assert.Equal(t, 10*time.Second, r.Reset)
+}
+
+func TestIsValidRequestID(t *testing.T) {
+	assert.True(t, IsValidRequestID("0b6f3c1e-9d2a-4f7b-8c3e-1a2b3c4d5e6f"))
+	assert.True(t, IsValidRequestID("svc.a:123_x"))
+	assert.False(t, IsValidRequestID(""))
+	assert.False(t, IsValidRequestID("a b"))
+	assert.False(t, IsValidRequestID("a\nINFO forged"))
+	assert.False(t, IsValidRequestID(strings.Repeat("a", 129)))
+}
+
+func TestAccessLogFormats(t *testing.T) {
+	entry := &AccessLogEntry{
+		Time:      time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC),
+		Method:    http.MethodGet,
+		Path:      "/users/1",
+		RouteKey:  "api_users_get",
+		Status:    http.StatusOK,
+		Bytes:     42,
+		LatencyMs: 1.5,
+		RemoteIP:  "10.0.0.1",
+		RequestID: "req-1",
+		URI:       "/users/1?x=1",
+		Protocol:  "HTTP/1.1",
+		UserAgent: "curl/8.0",
+	}
+
+	assert.JSONEq(t, `{"time":"2024-03-05T14:07:09Z","method":"GET","path":"/users/1","route":"api_users_get","status":200,"bytes":42,"latency_ms":1.5,"ip":"10.0.0.1","request_id":"req-1"}`, FormatAccessLogJSON(entry))
+	assert.Equal(t, `time=2024-03-05T14:07:09Z method=GET path=/users/1 route=api_users_get status=200 bytes=42 latency_ms=1.5 ip=10.0.0.1 request_id=req-1`, FormatAccessLogLogfmt(entry))
+	assert.Equal(t, `10.0.0.1 - - [05/Mar/2024:14:07:09 +0000] "GET /users/1?x=1 HTTP/1.1" 200 42 "-" "curl/8.0"`, FormatAccessLogCombined(entry))
+
+	entry.UserID = "a b"
+	entry.Slow = true
+	assert.Contains(t, FormatAccessLogLogfmt(entry), `user="a b" request_id=req-1 slow=true`)
+
+	o := &AccessLogOptions{ExcludePaths: []string{"/healthz", "/metrics/*"}}
+	assert.True(t, o.isExcluded("/healthz"))
+	assert.True(t, o.isExcluded("/metrics/go"))
+	assert.False(t, o.isExcluded("/healthz/x"))
+}
+
+func TestHealthChecker(t *testing.T) {
+	var shuttingDown bool
+	var calls int
+	x := NewHealthChecker(&HealthOptions{TimeoutMs: 50, CacheMs: 200}, func() bool { return shuttingDown })
+	x.AddCheck("ok", func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+	x.AddCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	x.AddLivenessCheck("live", func(ctx context.Context) error { return nil })
+
+	r := x.Live(context.Background())
+	assert.Equal(t, HealthStatus_Up, r.Status)
+	assert.Len(t, r.Checks, 1)
+
+	// 超时的检查使就绪失败
+	r = x.Ready(context.Background())
+	assert.Equal(t, HealthStatus_Down, r.Status)
+	assert.Equal(t, HealthStatus_Up, r.Checks["ok"].Status)
+	assert.Equal(t, HealthStatus_Down, r.Checks["slow"].Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), r.Checks["slow"].Error)
+
+	// 缓存期内不重复执行
+	x.Ready(context.Background())
+	assert.Equal(t, 1, calls)
+
+	// 关闭期间就绪总是失败
+	shuttingDown = true
+	r = x.Ready(context.Background())
+	assert.Equal(t, HealthStatus_Down, r.Status)
+	assert.Contains(t, r.Checks, "shutdown")
+	assert.Equal(t, HealthStatus_Up, x.Live(context.Background()).Status)
+}
+
+type testRouteProvider struct {
+	ssecurity.IRouteProvider
+	keys []string
+}
+
+func (x *testRouteProvider) GetRoute(key string) (*ssecurity.Route, error) {
+	if slices.Contains(x.keys, key) {
+		return new(ssecurity.Route), nil
+	}
+	return nil, errors.New("route not found")
+}
+
+func TestCheckRouteKeys(t *testing.T) {
+	x := &BaseWebHost{Actions: make(map[string]*Action)}
+	handler := func(ctx IHttpContext) {}
+	x.AddAction("GET/orders", "shop_order_list", handler)
+	x.AddAction("POST/orders", "shop_order_create", handler)
+	x.AddAction("PUT/orders", "shop_order_create", handler)
+	x.AddAction("GET/users", "shop_user_list", handler)
+
+	assert.Nil(t, x.CheckRouteKeys())
+
+	x.UseRouteProvider(&testRouteProvider{keys: []string{"shop_order_list"}})
+	assert.Equal(t, []string{"shop_order_create", "shop_user_list"}, x.CheckRouteKeys())
+
+	routes := x.Routes()
+	assert.Len(t, routes, 4)
+	assert.Equal(t, "GET", routes[0].Method)
+	assert.Equal(t, "/orders", routes[0].Path)
+	assert.Equal(t, "order", routes[0].Controller)
+	assert.Equal(t, "/users", routes[3].Path)
+}
+
+type (
+	testAuth struct{}
+
+	testOrder struct {
+		ID        int64     `json:"id"`
+		Status    string    `json:"status"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+
+	testOrderItem struct {
+		SKU string `json:"sku" validate:"required,regex=^[A-Z]+$"`
+		Qty int    `json:"qty" validate:"min=1,max=99"`
+	}
+
+	testOrderQuery struct {
+		ID     int      `path:"id" json:"-"`
+		Lang   string   `schema:"lang" validate:"enum=en|zh"`
+		Expand []string `schema:"expand"`
+	}
+
+	testOrderUpdate struct {
+		ID     int              `path:"id" json:"-"`
+		Notify bool             `schema:"notify" json:"-"`
+		Status string           `json:"status" validate:"required,enum=new|paid"`
+		Items  []*testOrderItem `json:"items" validate:"min=1"`
+	}
+)
+
+func (x *testAuth) AuthHandler(ctx IHttpContext) {
+	ctx.Next()
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	x := &BaseWebHost{
+		Actions: make(map[string]*Action),
+		OpenAPI: &OpenAPIOptions{Title: "Shop", Servers: []string{"https://shop.example.com"}},
+	}
+	handler := func(ctx IHttpContext) {}
+	x.AddActionGroups(NewPrefixedActionGroup("/orders", []RequestHandler{new(testAuth).AuthHandler}, []*Action{
+		NewAction("GET/{id}", "shop_order_get", handler).WithDoc(&ActionDoc{
+			Summary:  "Get order",
+			Tags:     []string{"orders"},
+			Request:  testOrderQuery{},
+			Response: &testOrder{},
+		}),
+		NewAction("POST/{id}", "shop_order_update", handler).WithDoc(&ActionDoc{
+			Request: testOrderUpdate{},
+			Scopes:  []string{"order.write"},
+		}),
+	}))
+	x.AddAction("GET/public/{filepath:*}", "shop_public", handler)
+	x.RecordRoute(http.MethodGet, "/openapi.json", "/openapi.json", []RequestHandler{x.OpenAPIHandler})
+	x.RecordNativeRoute(http.MethodGet, "/healthz", "health.live")
+
+	doc := x.OpenAPIDocument()
+	assert.Equal(t, "3.1.0", doc.OpenAPI)
+	assert.Equal(t, "Shop", doc.Info.Title)
+	assert.Equal(t, "1.0.0", doc.Info.Version)
+	assert.Equal(t, "https://shop.example.com", doc.Servers[0].URL)
+	assert.Len(t, doc.Paths, 2) // 文档端点和原生路由除外
+
+	toJson := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		assert.NoError(t, err)
+		return string(data)
+	}
+	problem := `"default":{"description":"Error","content":{"application/problem+json":{"schema":{"$ref":"#/components/schemas/Problem"}}}}`
+
+	assert.JSONEq(t, `{
+		"operationId":"shop_order_get","summary":"Get order","tags":["orders"],
+		"parameters":[
+			{"name":"id","in":"path","required":true,"schema":{"type":"integer","format":"int64"}},
+			{"name":"lang","in":"query","schema":{"type":"string","enum":["en","zh"]}},
+			{"name":"expand","in":"query","schema":{"type":"array","items":{"type":"string"}}}
+		],
+		"responses":{
+			"200":{"description":"OK","content":{"application/json":{"schema":{"$ref":"#/components/schemas/testOrder"}}}},
+			`+problem+`
+		},
+		"security":[{"bearerAuth":[]}]
+	}`, toJson(doc.Paths["/orders/{id}"]["get"]))
+
+	assert.JSONEq(t, `{
+		"operationId":"shop_order_update",
+		"parameters":[
+			{"name":"id","in":"path","required":true,"schema":{"type":"integer","format":"int64"}},
+			{"name":"notify","in":"query","schema":{"type":"boolean"}}
+		],
+		"requestBody":{"required":true,"content":{"application/json":{"schema":{"$ref":"#/components/schemas/testOrderUpdate"}}}},
+		"responses":{"200":{"description":"OK"},`+problem+`},
+		"security":[{"bearerAuth":["order.write"]}]
+	}`, toJson(doc.Paths["/orders/{id}"]["post"]))
+
+	assert.JSONEq(t, `{
+		"operationId":"shop_public",
+		"parameters":[{"name":"filepath","in":"path","required":true,"schema":{"type":"string"}}],
+		"responses":{"200":{"description":"OK"},`+problem+`}
+	}`, toJson(doc.Paths["/public/{filepath}"]["get"]))
+
+	assert.JSONEq(t, `{
+		"testOrder":{"type":"object","properties":{"id":{"type":"integer","format":"int64"},"status":{"type":"string"},"createdAt":{"type":"string","format":"date-time"}}},
+		"testOrderUpdate":{"type":"object","properties":{
+			"status":{"type":"string","enum":["new","paid"]},
+			"items":{"type":"array","items":{"$ref":"#/components/schemas/testOrderItem"},"minItems":1}
+		},"required":["status"]},
+		"testOrderItem":{"type":"object","properties":{
+			"sku":{"type":"string","pattern":"^[A-Z]+$"},
+			"qty":{"type":"integer","format":"int64","minimum":1,"maximum":99}
+		},"required":["sku"]}
+	}`, toJson(map[string]JSONSchema{
+		"testOrder":       doc.Components.Schemas["testOrder"],
+		"testOrderUpdate": doc.Components.Schemas["testOrderUpdate"],
+		"testOrderItem":   doc.Components.Schemas["testOrderItem"],
+	}))
+	assert.Contains(t, doc.Components.Schemas, "Problem")
+	assert.Contains(t, doc.Components.Schemas, "FieldError")
+	assert.Equal(t, "bearer", doc.Components.SecuritySchemes[OpenAPISecurity_Bearer].Scheme)
+}
+
+func TestLinkContext(t *testing.T) {
+	r, stop := LinkContext(context.Background(), nil)
+	assert.Equal(t, context.Background(), r)
+	stop()
+
+	// 继承parent的截止时间，parent取消时随之取消
+	parent, cancel := context.WithTimeout(context.Background(), time.Hour)
+	r, stop = LinkContext(context.Background(), parent)
+	deadline, ok := r.Deadline()
+	assert.True(t, ok)
+	expected, _ := parent.Deadline()
+	assert.Equal(t, expected, deadline)
+	cancel()
+	select {
+	case <-r.Done():
+		assert.ErrorIs(t, context.Cause(r), context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("linked context was not canceled with parent")
+	}
+	stop()
+
+	// stop后取消，不再受parent影响
+	parent, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	r, stop = LinkContext(context.Background(), parent)
+	stop()
+	assert.ErrorIs(t, r.Err(), context.Canceled)
+	assert.NoError(t, parent.Err())
+}
+
+func TestStaticFingerprint(t *testing.T) {
+	x := NewStaticFileServer(os.DirFS("."), "", nil, nil)
+	for name, expected := range map[string]bool{
+		"app.3f9a1c2b.js":           true,
+		"index-BK3f9s2a.js":         true,
+		"chunk-vendors.a1b2c3d4.js": true,
+		"app.component.js":          false,
+		"vendor.min.js":             false,
+		"app.js":                    false,
+	} {
+		assert.Equal(t, expected, x.isFingerprinted(name), name)
+	}
+
+	x = NewStaticFileServer(os.DirFS("."), "", &StaticOptions{ImmutablePattern: `^lib-`}, nil)
+	assert.True(t, x.isFingerprinted("lib-app.js"))
+	assert.False(t, x.isFingerprinted("app.3f9a1c2b.js"))
+}
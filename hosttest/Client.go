@@ -0,0 +1,203 @@
+// Package hosttest 在内存中运行IWebHost并发送请求，用于go test，不监听端口
+package hosttest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/syncfuture/host"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+const (
+	// DefaultHost Start启动的宿主的主机名，请求路径以/开头时发往第一个启动的宿主
+	DefaultHost = "host.test"
+
+	_shutdownTimeout = 5 * time.Second
+	_requestTimeout  = 30 * time.Second
+)
+
+// Client 按主机名将请求转发到内存中的宿主，所有请求共享Cookie，
+// 因此可以完整测试跨宿主的流程，如OAuth客户端登录时在客户端宿主和授权服务之间的跳转
+type Client struct {
+	t           testing.TB
+	jar         http.CookieJar
+	client      *http.Client
+	mu          sync.RWMutex
+	listeners   map[string]*fasthttputil.InmemoryListener
+	defaultHost string
+}
+
+// Start 在DefaultHost上运行h，测试结束时关闭
+func Start(t testing.TB, h host.IWebHost) *Client {
+	r := NewClient(t)
+	r.StartHost(DefaultHost, h)
+	return r
+}
+
+// NewClient 创建没有宿主的客户端，通过StartHost添加
+func NewClient(t testing.TB) *Client {
+	jar, _ := cookiejar.New(nil) // 没有Options时不会返回错误
+	r := &Client{
+		t:         t,
+		jar:       jar,
+		listeners: make(map[string]*fasthttputil.InmemoryListener),
+	}
+	r.client = &http.Client{
+		Jar:     jar,
+		Timeout: _requestTimeout,
+		Transport: &http.Transport{
+			DialContext:       r.dial,
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return r
+}
+
+// StartHost 在hostname上运行h，请求http://hostname/...时由h处理，测试结束时关闭
+func (x *Client) StartHost(hostname string, h host.IWebHost) {
+	x.t.Helper()
+
+	ln := fasthttputil.NewInmemoryListener()
+	h.UseListener(ln)
+
+	x.mu.Lock()
+	if _, ok := x.listeners[hostname]; ok {
+		x.mu.Unlock()
+		x.t.Fatalf("host '%s' has already been started", hostname)
+	}
+	x.listeners[hostname] = ln
+	if x.defaultHost == "" {
+		x.defaultHost = hostname
+	}
+	x.mu.Unlock()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- h.RunContext(context.Background())
+	}()
+
+	x.t.Cleanup(func() {
+		if err := h.Shutdown(_shutdownTimeout); err != nil {
+			x.t.Errorf("shutdown host '%s': %v", hostname, err)
+		}
+		select {
+		case err := <-runErr:
+			if err != nil {
+				x.t.Errorf("run host '%s': %v", hostname, err)
+			}
+		case <-time.After(_shutdownTimeout):
+			x.t.Errorf("host '%s' did not stop after shutdown", hostname)
+		}
+	})
+}
+
+// HttpClient 底层的http.Client，不跟随重定向，可交给需要http.Client的组件，如oauth2.HTTPClient
+func (x *Client) HttpClient() *http.Client {
+	return x.client
+}
+
+// Cookies 将发往target的Cookie
+func (x *Client) Cookies(target string) []*http.Cookie {
+	return x.jar.Cookies(x.parseURL(target))
+}
+
+// Cookie 将发往target的名为name的Cookie，不存在时为nil
+func (x *Client) Cookie(target, name string) *http.Cookie {
+	for _, c := range x.Cookies(target) {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// SetCookie 设置发往target的Cookie
+func (x *Client) SetCookie(target string, cookie *http.Cookie) {
+	x.jar.SetCookies(x.parseURL(target), []*http.Cookie{cookie})
+}
+
+func (x *Client) GET(target string) *Request {
+	return x.NewRequest(http.MethodGet, target)
+}
+
+func (x *Client) POST(target string) *Request {
+	return x.NewRequest(http.MethodPost, target)
+}
+
+func (x *Client) PUT(target string) *Request {
+	return x.NewRequest(http.MethodPut, target)
+}
+
+func (x *Client) PATCH(target string) *Request {
+	return x.NewRequest(http.MethodPatch, target)
+}
+
+func (x *Client) DELETE(target string) *Request {
+	return x.NewRequest(http.MethodDelete, target)
+}
+
+// NewRequest target为完整URL，或以/开头的路径（发往第一个启动的宿主）
+func (x *Client) NewRequest(method, target string) *Request {
+	return &Request{
+		client: x,
+		method: method,
+		url:    x.parseURL(target),
+		header: make(http.Header),
+	}
+}
+
+func (x *Client) parseURL(target string) *url.URL {
+	x.t.Helper()
+	if strings.HasPrefix(target, "/") {
+		x.mu.RLock()
+		target = "http://" + x.defaultHost + target
+		x.mu.RUnlock()
+	}
+	r, err := url.Parse(target)
+	if err != nil {
+		x.t.Fatalf("invalid url '%s': %v", target, err)
+	}
+	return r
+}
+
+// dial 连接到主机名对应的内存监听器
+func (x *Client) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	hostname, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		hostname = addr
+	}
+	x.mu.RLock()
+	ln, ok := x.listeners[hostname]
+	x.mu.RUnlock()
+	if !ok {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: &net.DNSError{Err: "no host started", Name: hostname, IsNotFound: true}}
+	}
+
+	// InmemoryListener.Dial在宿主Accept前阻塞，宿主启动失败时由ctx结束等待
+	type dialResult struct {
+		conn net.Conn
+		err  error
+	}
+	done := make(chan dialResult, 1)
+	go func() {
+		conn, err := ln.Dial()
+		done <- dialResult{conn, err}
+	}()
+	select {
+	case r := <-done:
+		return r.conn, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,440 @@
+package hosttest
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gorilla/schema"
+	"github.com/syncfuture/go/sconv"
+	"github.com/syncfuture/go/serr"
+	"github.com/syncfuture/go/shttp"
+	"github.com/syncfuture/host"
+)
+
+const _maxMultipartMemory = 32 << 20
+
+var _decoder = schema.NewDecoder()
+
+func init() {
+	_decoder.IgnoreUnknownKeys(true)
+}
+
+// MockHttpContext 在内存中实现IHttpContext，用于单独测试RequestHandler和中间件，
+// 请求来自Request，响应记录在导出字段中，Session、路由参数和Items均为内存中的map
+// 加密Cookie不加密，SetEncryptedCookieKV和GetEncryptedCookieString直接使用明文
+type MockHttpContext struct {
+	Request *http.Request
+	Items   map[string]interface{}
+	// Params 路由参数
+	Params  map[string]string
+	Session map[string]string
+	// SessionEnded 是否调用过EndSession
+	SessionEnded bool
+
+	StatusCode     int
+	ResponseHeader http.Header
+	ResponseBody   bytes.Buffer
+	// ResponseCookies 按顺序记录设置的Cookie，RemoveCookie记录MaxAge为-1的Cookie
+	ResponseCookies []*http.Cookie
+	// RedirectURL 最后一次Redirect的地址
+	RedirectURL string
+	// BodyStream CopyBodyAndStatusCode设置的响应流
+	BodyStream io.ReadCloser
+	// SSEHandler SSE设置的处理函数，不会执行
+	SSEHandler host.SSEHandler
+	// NextCount 调用Next的次数
+	NextCount int
+
+	reqBody      []byte
+	bodyRead     bool
+	handlers     []host.RequestHandler
+	handlerIndex int
+}
+
+// NewMockHttpContext 使用httptest.NewRequest创建请求，target为路径或完整URL，body可为nil
+func NewMockHttpContext(method, target string, body io.Reader) *MockHttpContext {
+	return &MockHttpContext{
+		Request:        httptest.NewRequest(method, target, body),
+		Items:          make(map[string]interface{}),
+		Params:         make(map[string]string),
+		Session:        make(map[string]string),
+		ResponseHeader: make(http.Header),
+	}
+}
+
+// Run 依次执行handlers，handler调用Next时执行下一个，返回自身以便检查结果
+func (x *MockHttpContext) Run(handlers ...host.RequestHandler) *MockHttpContext {
+	x.handlers = handlers
+	x.handlerIndex = 0
+	if len(handlers) > 0 {
+		handlers[0](x)
+	}
+	return x
+}
+
+// NextCalled 是否调用过Next
+func (x *MockHttpContext) NextCalled() bool {
+	return x.NextCount > 0
+}
+
+// GetError 通过Error记录的错误
+func (x *MockHttpContext) GetError() error {
+	err, _ := x.Items[host.Ctx_Error].(error)
+	return err
+}
+
+// GetResponseCookie 最后一次设置的名为name的Cookie，不存在时为nil
+func (x *MockHttpContext) GetResponseCookie(name string) *http.Cookie {
+	for i := len(x.ResponseCookies) - 1; i >= 0; i-- {
+		if x.ResponseCookies[i].Name == name {
+			return x.ResponseCookies[i]
+		}
+	}
+	return nil
+}
+
+func (x *MockHttpContext) Write(p []byte) (n int, err error) {
+	return x.ResponseBody.Write(p)
+}
+
+////////// Items
+
+func (x *MockHttpContext) SetItem(key string, value interface{}) {
+	x.Items[key] = value
+}
+func (x *MockHttpContext) GetItem(key string) interface{} {
+	return x.Items[key]
+}
+func (x *MockHttpContext) GetItemString(key string) string {
+	return sconv.ToString(x.Items[key])
+}
+func (x *MockHttpContext) GetItemInt(key string) int {
+	return sconv.ToInt(x.Items[key])
+}
+func (x *MockHttpContext) GetItemInt32(key string) int32 {
+	return sconv.ToInt32(x.Items[key])
+}
+func (x *MockHttpContext) GetItemInt64(key string) int64 {
+	return sconv.ToInt64(x.Items[key])
+}
+
+func (x *MockHttpContext) GetRouteKey() string {
+	return x.GetItemString(host.Ctx_RouteKey)
+}
+
+// Context 返回Request.Context()
+func (x *MockHttpContext) Context() context.Context {
+	return x.Request.Context()
+}
+
+// SetContext 替换Request的context
+func (x *MockHttpContext) SetContext(c context.Context) {
+	x.Request = x.Request.WithContext(c)
+}
+
+func (x *MockHttpContext) GetCSPNonce() string {
+	return x.GetItemString(host.Ctx_CSPNonce)
+}
+
+func (x *MockHttpContext) GetPeerCertificate() *x509.Certificate {
+	if x.Request.TLS == nil || len(x.Request.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return x.Request.TLS.PeerCertificates[0]
+}
+
+////////// Cookie
+
+func (x *MockHttpContext) SetCookieKV(key, value string, options ...func(*http.Cookie)) {
+	c := &http.Cookie{Name: key, Value: value}
+	for _, o := range options {
+		o(c)
+	}
+	x.ResponseCookies = append(x.ResponseCookies, c)
+}
+func (x *MockHttpContext) GetCookieString(key string) string {
+	c, err := x.Request.Cookie(key)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+func (x *MockHttpContext) SetEncryptedCookieKV(key, value string, options ...func(*http.Cookie)) {
+	x.SetCookieKV(key, value, options...)
+}
+func (x *MockHttpContext) GetEncryptedCookieString(key string) string {
+	return x.GetCookieString(key)
+}
+func (x *MockHttpContext) RemoveCookie(key string, options ...func(*http.Cookie)) {
+	x.SetCookieKV(key, "", append([]func(*http.Cookie){func(c *http.Cookie) {
+		c.MaxAge = -1
+	}}, options...)...)
+}
+
+////////// Session
+
+func (x *MockHttpContext) SetSession(key, value string) {
+	x.Session[key] = value
+}
+func (x *MockHttpContext) GetSessionString(key string) string {
+	return x.Session[key]
+}
+func (x *MockHttpContext) RemoveSession(key string) {
+	delete(x.Session, key)
+}
+func (x *MockHttpContext) EndSession() {
+	x.Session = make(map[string]string)
+	x.SessionEnded = true
+}
+
+////////// 请求
+
+// readBody 读取并缓存请求体，以便多次读取
+func (x *MockHttpContext) readBody() []byte {
+	if !x.bodyRead {
+		x.bodyRead = true
+		if x.Request.Body != nil {
+			x.reqBody, _ = io.ReadAll(x.Request.Body) // 内存中的请求体不会读取失败
+		}
+		x.Request.Body = io.NopCloser(bytes.NewReader(x.reqBody))
+	}
+	return x.reqBody
+}
+
+func (x *MockHttpContext) parseForm() error {
+	if x.Request.Form != nil {
+		return nil
+	}
+
+	x.readBody()
+	var err error
+	if strings.HasPrefix(x.Request.Header.Get("Content-Type"), "multipart/form-data") {
+		err = x.Request.ParseMultipartForm(_maxMultipartMemory)
+	} else {
+		err = x.Request.ParseForm()
+	}
+	x.Request.Body = io.NopCloser(bytes.NewReader(x.reqBody))
+	return serr.WithStack(err)
+}
+
+func (x *MockHttpContext) GetFormString(key string) string {
+	x.parseForm()
+	return x.Request.FormValue(key)
+}
+func (x *MockHttpContext) GetFormStringDefault(key, d string) string {
+	if r := x.GetFormString(key); r != "" {
+		return r
+	}
+	return d
+}
+func (x *MockHttpContext) GetFormFile(key string) (*multipart.FileHeader, error) {
+	if err := x.parseForm(); err != nil {
+		return nil, err
+	}
+	file, r, err := x.Request.FormFile(key)
+	if err != nil {
+		return nil, serr.WithStack(err)
+	}
+	file.Close()
+	return r, nil
+}
+func (x *MockHttpContext) GetMultipartForm() (*multipart.Form, error) {
+	if err := x.parseForm(); err != nil {
+		return nil, err
+	}
+	if x.Request.MultipartForm == nil {
+		return nil, serr.WithStack(http.ErrNotMultipart)
+	}
+	return x.Request.MultipartForm, nil
+}
+
+func (x *MockHttpContext) GetBodyString() string {
+	return string(x.readBody())
+}
+func (x *MockHttpContext) GetBodyBytes() []byte {
+	return x.readBody()
+}
+
+func (x *MockHttpContext) GetParamString(key string) string {
+	if v, ok := x.Params[key]; ok {
+		return v
+	}
+	return x.GetItemString(key)
+}
+func (x *MockHttpContext) GetParamInt(key string) int {
+	return sconv.ToInt(x.GetParamString(key))
+}
+func (x *MockHttpContext) GetParamInt32(key string) int32 {
+	return sconv.ToInt32(x.GetParamString(key))
+}
+func (x *MockHttpContext) GetParamInt64(key string) int64 {
+	return sconv.ToInt64(x.GetParamString(key))
+}
+
+func (x *MockHttpContext) ReadJSON(objPtr interface{}) error {
+	err := json.Unmarshal(x.readBody(), objPtr)
+	return serr.WithStack(err)
+}
+func (x *MockHttpContext) ReadQuery(objPtr interface{}) error {
+	err := _decoder.Decode(objPtr, x.Request.URL.Query())
+	return serr.WithStack(err)
+}
+func (x *MockHttpContext) ReadForm(objPtr interface{}) error {
+	if err := x.parseForm(); err != nil {
+		return err
+	}
+	err := _decoder.Decode(objPtr, x.Request.PostForm)
+	return serr.WithStack(err)
+}
+func (x *MockHttpContext) ReadFormMap() (map[string][]string, error) {
+	if err := x.parseForm(); err != nil {
+		return nil, err
+	}
+	dic := make(map[string][]string, len(x.Request.PostForm))
+	for k, v := range x.Request.PostForm {
+		if len(v) > 0 {
+			dic[k] = []string{v[len(v)-1]}
+		}
+	}
+	return dic, nil
+}
+func (x *MockHttpContext) Bind(objPtr interface{}) error {
+	return host.Bind(x, objPtr)
+}
+
+func (x *MockHttpContext) GetHeader(key string) string {
+	if strings.EqualFold(key, "Host") {
+		return x.Request.Host
+	}
+	return x.Request.Header.Get(key)
+}
+
+func (x *MockHttpContext) RequestURL() string {
+	scheme := "http"
+	if x.Request.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + x.Request.Host + x.Request.URL.RequestURI()
+}
+func (x *MockHttpContext) RequestPath() string {
+	return x.Request.URL.Path
+}
+func (x *MockHttpContext) RequestMethod() string {
+	return x.Request.Method
+}
+func (x *MockHttpContext) RequestURI() string {
+	return x.Request.RequestURI
+}
+func (x *MockHttpContext) RequestProtocol() string {
+	return x.Request.Proto
+}
+func (x *MockHttpContext) GetRemoteIP() string {
+	ip, _, err := net.SplitHostPort(x.Request.RemoteAddr)
+	if err != nil {
+		return x.Request.RemoteAddr
+	}
+	return ip
+}
+func (x *MockHttpContext) UserAgent() string {
+	return x.Request.UserAgent()
+}
+
+////////// 响应
+
+func (x *MockHttpContext) SetHeader(key, value string) {
+	x.ResponseHeader.Set(key, value)
+}
+func (x *MockHttpContext) SetStatusCode(statusCode int) {
+	x.StatusCode = statusCode
+}
+func (x *MockHttpContext) GetStatusCode() int {
+	if x.StatusCode == 0 {
+		return http.StatusOK
+	}
+	return x.StatusCode
+}
+func (x *MockHttpContext) GetResponseHeader(key string) string {
+	return x.ResponseHeader.Get(key)
+}
+func (x *MockHttpContext) GetResponseBody() []byte {
+	if x.IsBodyStream() {
+		return nil
+	}
+	return x.ResponseBody.Bytes()
+}
+func (x *MockHttpContext) SetResponseBody(body []byte) {
+	x.ResponseBody.Reset()
+	x.ResponseBody.Write(body)
+}
+func (x *MockHttpContext) IsBodyStream() bool {
+	return x.BodyStream != nil || x.SSEHandler != nil
+}
+func (x *MockHttpContext) SetContentType(cType string) {
+	x.ResponseHeader.Set("Content-Type", cType)
+}
+func (x *MockHttpContext) WriteString(body string) (int, error) {
+	return x.ResponseBody.WriteString(body)
+}
+func (x *MockHttpContext) WriteBytes(body []byte) (int, error) {
+	return x.ResponseBody.Write(body)
+}
+func (x *MockHttpContext) WriteJsonBytes(body []byte) (int, error) {
+	x.SetContentType(shttp.CTYPE_JSON)
+	return x.ResponseBody.Write(body)
+}
+
+func (x *MockHttpContext) SSE(handler host.SSEHandler, options ...func(*host.SSEOptions)) {
+	host.SetSSEHeaders(x)
+	x.SSEHandler = handler
+}
+func (x *MockHttpContext) Error(err error) {
+	x.SetItem(host.Ctx_Error, err)
+}
+func (x *MockHttpContext) Redirect(url string, statusCode int) {
+	x.ResponseHeader.Set("Location", url)
+	x.RedirectURL = url
+	x.StatusCode = statusCode
+}
+func (x *MockHttpContext) CopyBodyAndStatusCode(resp *http.Response) {
+	x.StatusCode = resp.StatusCode
+	x.BodyStream = resp.Body
+}
+
+func (x *MockHttpContext) Next() {
+	x.NextCount++
+	if x.handlerIndex < len(x.handlers)-1 {
+		x.handlerIndex++
+		x.handlers[x.handlerIndex](x)
+	}
+}
+
+// Reset 清除Items、Session和记录的响应，保留Request
+func (x *MockHttpContext) Reset() {
+	x.Items = make(map[string]interface{})
+	x.Params = make(map[string]string)
+	x.Session = make(map[string]string)
+	x.SessionEnded = false
+	x.StatusCode = 0
+	x.ResponseHeader = make(http.Header)
+	x.ResponseBody.Reset()
+	x.ResponseCookies = nil
+	x.RedirectURL = ""
+	x.BodyStream = nil
+	x.SSEHandler = nil
+	x.NextCount = 0
+	x.handlers = nil
+	x.handlerIndex = 0
+}
+
+// GetInnerContext 返回Request
+func (x *MockHttpContext) GetInnerContext() interface{} {
+	return x.Request
+}
@@ -0,0 +1,56 @@
+package hosttest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncfuture/go/shttp"
+	"github.com/syncfuture/host"
+	"github.com/syncfuture/host/resource"
+)
+
+var _ host.IHttpContext = (*MockHttpContext)(nil)
+
+func TestMockJsonContentTypeHandler(t *testing.T) {
+	ctx := NewMockHttpContext(http.MethodGet, "/", nil).Run(host.JsonConentTypeHandler, func(ctx host.IHttpContext) {
+		ctx.WriteString(`{}`)
+	})
+	assert.True(t, ctx.NextCalled())
+	assert.Equal(t, shttp.CTYPE_JSON, ctx.GetResponseHeader("Content-Type"))
+	assert.Equal(t, `{}`, ctx.ResponseBody.String())
+}
+
+func TestMockResourceAuthHandler(t *testing.T) {
+	h := new(resource.OAuthResourceHost)
+
+	ctx := NewMockHttpContext(http.MethodGet, "/api", nil).Run(host.ErrorHandler, h.AuthHandler)
+	assert.Equal(t, 1, ctx.NextCount) // 只有ErrorHandler调用了Next
+	assert.Equal(t, http.StatusUnauthorized, ctx.GetStatusCode())
+	assert.Contains(t, ctx.ResponseBody.String(), "Authorization header is missing")
+
+	ctx = NewMockHttpContext(http.MethodGet, "/api", nil)
+	ctx.Request.Header.Set(shttp.HEADER_AUTH, "Basic abc")
+	h.AuthHandler(ctx)
+	assert.False(t, ctx.NextCalled())
+	assert.Equal(t, http.StatusBadRequest, host.ToProblem(ctx.GetError()).Status)
+}
+
+func TestMockHttpContextResponse(t *testing.T) {
+	ctx := NewMockHttpContext(http.MethodGet, "/users/7?name=tom", nil)
+	ctx.Params["id"] = "7"
+	ctx.Request.AddCookie(&http.Cookie{Name: "c", Value: "v"})
+	ctx.Run(func(ctx host.IHttpContext) {
+		ctx.SetCookieKV("id", ctx.GetParamString("id"))
+		ctx.RemoveCookie(ctx.GetCookieString("c"))
+		ctx.SetSession("name", ctx.GetFormString("name"))
+		ctx.Redirect("/home", http.StatusFound)
+	})
+
+	assert.Equal(t, "/home", ctx.RedirectURL)
+	assert.Equal(t, http.StatusFound, ctx.GetStatusCode())
+	assert.Equal(t, "7", ctx.GetResponseCookie("id").Value)
+	assert.Equal(t, -1, ctx.GetResponseCookie("v").MaxAge)
+	assert.Equal(t, "tom", ctx.Session["name"])
+	assert.False(t, ctx.NextCalled())
+}
@@ -0,0 +1,115 @@
+package hosttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Request 请求构建器，Send前的方法均返回自身以便链式调用
+type Request struct {
+	client          *Client
+	method          string
+	url             *url.URL
+	header          http.Header
+	cookies         []*http.Cookie
+	body            []byte
+	followRedirects bool
+}
+
+func (x *Request) Header(key, value string) *Request {
+	x.header.Set(key, value)
+	return x
+}
+
+func (x *Request) Query(key, value string) *Request {
+	q := x.url.Query()
+	q.Add(key, value)
+	x.url.RawQuery = q.Encode()
+	return x
+}
+
+// Cookie 只随本次请求发送的Cookie，不写入Cookie容器
+func (x *Request) Cookie(name, value string) *Request {
+	x.cookies = append(x.cookies, &http.Cookie{Name: name, Value: value})
+	return x
+}
+
+// BearerToken 设置Authorization: Bearer token
+func (x *Request) BearerToken(token string) *Request {
+	return x.Header("Authorization", "Bearer "+token)
+}
+
+// JSON 以JSON编码v作为请求体
+func (x *Request) JSON(v interface{}) *Request {
+	x.client.t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		x.client.t.Fatalf("marshal json body: %v", err)
+	}
+	return x.Body("application/json", data)
+}
+
+// Form 以application/x-www-form-urlencoded编码values作为请求体
+func (x *Request) Form(values url.Values) *Request {
+	return x.Body("application/x-www-form-urlencoded", []byte(values.Encode()))
+}
+
+func (x *Request) Body(contentType string, body []byte) *Request {
+	x.body = body
+	return x.Header("Content-Type", contentType)
+}
+
+// FollowRedirects 跟随重定向（最多10次），可在内存中的宿主之间跳转，默认返回重定向响应本身
+func (x *Request) FollowRedirects() *Request {
+	x.followRedirects = true
+	return x
+}
+
+// Send 发送请求并读取完整的响应，失败时终止测试
+func (x *Request) Send() *Response {
+	t := x.client.t
+	t.Helper()
+
+	var body io.Reader
+	if x.body != nil {
+		body = bytes.NewReader(x.body)
+	}
+	req, err := http.NewRequest(x.method, x.url.String(), body)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	for k, v := range x.header {
+		req.Header[k] = v
+	}
+	for _, c := range x.cookies {
+		req.AddCookie(c)
+	}
+
+	client := x.client.client
+	if x.followRedirects {
+		c := *client
+		c.CheckRedirect = nil
+		client = &c
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", x.method, x.url, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read response of %s %s: %v", x.method, x.url, err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	return &Response{
+		Response: resp,
+		t:        t,
+		body:     data,
+	}
+}
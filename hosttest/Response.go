@@ -0,0 +1,92 @@
+package hosttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Response 已读取完整响应体的响应，Assert方法失败时标记测试失败并继续，均返回自身以便链式调用
+type Response struct {
+	*http.Response
+	t    testing.TB
+	body []byte
+}
+
+func (x *Response) BodyBytes() []byte {
+	return x.body
+}
+
+func (x *Response) BodyString() string {
+	return string(x.body)
+}
+
+// DecodeJSON 将响应体解码到v，失败时终止测试
+func (x *Response) DecodeJSON(v interface{}) *Response {
+	x.t.Helper()
+	if err := json.Unmarshal(x.body, v); err != nil {
+		x.t.Fatalf("decode json body %q: %v", x.body, err)
+	}
+	return x
+}
+
+// GetCookie 响应设置的名为name的Cookie，不存在时为nil
+func (x *Response) GetCookie(name string) *http.Cookie {
+	for _, c := range x.Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (x *Response) AssertStatus(statusCode int) *Response {
+	x.t.Helper()
+	assert.Equal(x.t, statusCode, x.StatusCode, "status of %s %s, body: %s", x.Request.Method, x.Request.URL, x.body)
+	return x
+}
+
+func (x *Response) AssertHeader(key, value string) *Response {
+	x.t.Helper()
+	assert.Equal(x.t, value, x.Header.Get(key), "header %s", key)
+	return x
+}
+
+func (x *Response) AssertBody(body string) *Response {
+	x.t.Helper()
+	assert.Equal(x.t, body, string(x.body))
+	return x
+}
+
+func (x *Response) AssertBodyContains(s string) *Response {
+	x.t.Helper()
+	assert.Contains(x.t, string(x.body), s)
+	return x
+}
+
+// AssertJSON 响应体与expected是等价的JSON，忽略格式和字段顺序
+func (x *Response) AssertJSON(expected string) *Response {
+	x.t.Helper()
+	assert.JSONEq(x.t, expected, string(x.body))
+	return x
+}
+
+// AssertRedirect 响应为重定向且Location为location
+func (x *Response) AssertRedirect(location string) *Response {
+	x.t.Helper()
+	assert.True(x.t, x.StatusCode >= http.StatusMultipleChoices && x.StatusCode < http.StatusBadRequest, "status %d is not a redirect", x.StatusCode)
+	assert.Equal(x.t, location, x.Header.Get("Location"))
+	return x
+}
+
+// AssertCookie 响应设置了名为name、值为value的Cookie
+func (x *Response) AssertCookie(name, value string) *Response {
+	x.t.Helper()
+	c := x.GetCookie(name)
+	if assert.NotNil(x.t, c, "cookie %s", name) {
+		assert.Equal(x.t, value, c.Value, "cookie %s", name)
+	}
+	return x
+}
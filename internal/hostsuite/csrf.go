@@ -0,0 +1,77 @@
+package hostsuite
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syncfuture/host"
+)
+
+// SecureCookieHostFactory 使用指定监听地址和CSRF配置创建宿主，后端的CookieEncryptor取自secureCookie
+type SecureCookieHostFactory func(listenAddr string, options *host.CSRFOptions, secureCookie *host.SecureCookieHost) host.IWebHost
+
+// RunCSRFDoubleSubmit 运行double_submit模式CSRF的测试，令牌以SecureCookieHost加密的Cookie下发
+func RunCSRFDoubleSubmit(t *testing.T, factory SecureCookieHostFactory) {
+	secureCookie := &host.SecureCookieHost{
+		HashKey:  "suite-hash-key-suite-hash-key-00",
+		BlockKey: "suite-block-key-suite-block-key0",
+	}
+	secureCookie.BuildSecureCookieHost()
+
+	s := start(t, func(listenAddr string, _ func(*host.BaseWebHost)) host.IWebHost {
+		return factory(listenAddr, &host.CSRFOptions{Mode: host.CSRFMode_DoubleSubmit}, secureCookie)
+	}, nil, func(h host.IWebHost) {
+		h.GET("/csrf/plain", func(ctx host.IHttpContext) {
+			ctx.WriteString("plain")
+		})
+		h.GET("/csrf/form", func(ctx host.IHttpContext) {
+			ctx.WriteString(host.GetCSRFToken(ctx))
+		})
+		h.POST("/csrf/submit", func(ctx host.IHttpContext) {
+			ctx.WriteString("ok")
+		})
+	})
+	defer s.shutdown(t)
+	client, baseURL := s.client, s.baseURL
+
+	// 不取令牌的请求不下发Cookie
+	resp, _ := do(t, client, http.MethodGet, baseURL+"/csrf/plain", nil, nil)
+	assert.Empty(t, resp.Header.Values("Set-Cookie"))
+
+	resp, token := do(t, client, http.MethodGet, baseURL+"/csrf/form", nil, nil)
+	require.NotEmpty(t, token)
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "_csrf" {
+			cookie = c
+		}
+	}
+	require.NotNil(t, cookie)
+	assert.NotContains(t, cookie.Value, token) // Cookie中是加密的令牌
+	assert.True(t, cookie.HttpOnly)
+	_, again := do(t, client, http.MethodGet, baseURL+"/csrf/form", nil, nil)
+	assert.Equal(t, token, again)
+
+	resp, body := do(t, client, http.MethodPost, baseURL+"/csrf/submit", nil, map[string]string{host.Header_CSRFToken: token})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ok", body)
+	resp, _ = do(t, client, http.MethodPost, baseURL+"/csrf/submit", nil, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp, _ = do(t, client, http.MethodPost, baseURL+"/csrf/submit", nil, map[string]string{host.Header_CSRFToken: token + "x"})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// 没有Cookie时令牌无效
+	jar, _ := cookiejar.New(nil)
+	other := &http.Client{Jar: jar}
+	resp, _ = do(t, other, http.MethodPost, baseURL+"/csrf/submit", nil, map[string]string{host.Header_CSRFToken: token})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Cookie被篡改时令牌无效
+	tampered, _ := cookiejar.New(nil)
+	tampered.SetCookies(resp.Request.URL, []*http.Cookie{{Name: "_csrf", Value: token}})
+	resp, _ = do(t, &http.Client{Jar: tampered}, http.MethodPost, baseURL+"/csrf/submit", nil, map[string]string{host.Header_CSRFToken: token})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
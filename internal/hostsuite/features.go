@@ -0,0 +1,802 @@
+package hostsuite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"html"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syncfuture/host"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 每个可选功能在只配置了该功能的宿主上测试，避免功能之间相互掩盖问题
+
+// testCompression 只启用Compression（Precompressed）
+func testCompression(t *testing.T, factory HostFactory) {
+	s := start(t, factory, func(x *host.BaseWebHost) {
+		x.Compression = &host.CompressionOptions{Precompressed: true}
+	}, func(h host.IWebHost) {
+		registerCommon(h)
+		h.GET("/compress", func(ctx host.IHttpContext) {
+			ctx.WriteJsonBytes([]byte(strings.Repeat(`{"name":"compress"},`, 100)))
+		})
+		registerFiles(h)
+	})
+	defer s.shutdown(t)
+	client, baseURL := s.client, s.baseURL
+
+	t.Run("Dynamic", func(t *testing.T) {
+		expected := strings.Repeat(`{"name":"compress"},`, 100)
+		for _, c := range []struct{ accept, encoding string }{
+			{"gzip", host.Encoding_Gzip},
+			{"br;q=0.5, gzip;q=0.8", host.Encoding_Gzip},
+			{"deflate, zstd;q=0", host.Encoding_Deflate},
+			{"*", host.Encoding_Brotli},
+			{"zstd", host.Encoding_Zstd},
+			{"identity", ""},
+		} {
+			resp, body := do(t, client, http.MethodGet, baseURL+"/compress", nil, map[string]string{host.Header_AcceptEncoding: c.accept})
+			assert.Equal(t, c.encoding, resp.Header.Get(host.Header_ContentEncoding), c.accept)
+			assert.Contains(t, resp.Header.Get(host.Header_Vary), host.Header_AcceptEncoding)
+			assert.Equal(t, expected, decompress(t, c.encoding, body), c.accept)
+		}
+
+		resp, body := do(t, client, http.MethodGet, baseURL+"/header", nil, map[string]string{host.Header_AcceptEncoding: "gzip", "X-In": "small"})
+		assert.Empty(t, resp.Header.Get(host.Header_ContentEncoding))
+		assert.Equal(t, "small", body)
+	})
+
+	t.Run("Precompressed", func(t *testing.T) {
+		raw, err := _testdata.ReadFile("testdata/static/app.js")
+		require.NoError(t, err)
+		for _, path := range []string{"/embed/app.js", "/files/app.js"} {
+			resp, body := do(t, client, http.MethodGet, baseURL+path, nil, map[string]string{host.Header_AcceptEncoding: "br, gzip"})
+			assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+			assert.Equal(t, host.Encoding_Gzip, resp.Header.Get(host.Header_ContentEncoding), path)
+			assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/javascript"), path)
+			assert.Contains(t, resp.Header.Get(host.Header_Vary), host.Header_AcceptEncoding, path)
+			assert.Equal(t, string(raw), decompress(t, host.Encoding_Gzip, body), path)
+
+			resp, body = do(t, client, http.MethodGet, baseURL+path, nil, map[string]string{host.Header_AcceptEncoding: "br"})
+			assert.Empty(t, resp.Header.Get(host.Header_ContentEncoding), path)
+			assert.Equal(t, string(raw), body, path)
+		}
+
+		// 预压缩文件使用不同的ETag
+		for _, path := range []string{"/embed/app.js", "/static/app.js"} {
+			resp, _ := do(t, client, http.MethodGet, baseURL+path, nil, map[string]string{host.Header_AcceptEncoding: "identity"})
+			etag := resp.Header.Get("ETag")
+			require.NotEmpty(t, etag, path)
+			resp, _ = do(t, client, http.MethodGet, baseURL+path, nil, map[string]string{host.Header_AcceptEncoding: "gzip"})
+			assert.Equal(t, host.Encoding_Gzip, resp.Header.Get(host.Header_ContentEncoding), path)
+			assert.NotEqual(t, etag, resp.Header.Get("ETag"), path)
+		}
+	})
+}
+
+// testStatic 只配置Static
+func testStatic(t *testing.T, factory HostFactory) {
+	s := start(t, factory, func(x *host.BaseWebHost) {
+		x.Static = &host.StaticOptions{
+			SPA:           true,
+			MaxAgeSeconds: 60,
+		}
+	}, registerFiles)
+	defer s.shutdown(t)
+	client, baseURL := s.client, s.baseURL
+
+	raw, err := _testdata.ReadFile("testdata/static/app.js")
+	require.NoError(t, err)
+	index, err := _testdata.ReadFile("testdata/static/index.html")
+	require.NoError(t, err)
+	identity := map[string]string{host.Header_AcceptEncoding: "identity"}
+	withIdentity := func(key, value string) map[string]string {
+		return map[string]string{host.Header_AcceptEncoding: "identity", key: value}
+	}
+
+	for _, prefix := range []string{"/embed", "/static"} {
+		url := baseURL + prefix + "/app.js"
+		resp, body := do(t, client, http.MethodGet, url, nil, identity)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, prefix)
+		assert.Equal(t, string(raw), body, prefix)
+		assert.Equal(t, "public, max-age=60", resp.Header.Get("Cache-Control"), prefix)
+		etag := resp.Header.Get("ETag")
+		require.True(t, strings.HasPrefix(etag, `"`), prefix) // 强ETag
+
+		resp, body = do(t, client, http.MethodGet, url, nil, withIdentity("If-None-Match", etag))
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode, prefix)
+		assert.Empty(t, body, prefix)
+
+		// 未启用Compression时不返回预压缩文件
+		resp, _ = do(t, client, http.MethodGet, url, nil, map[string]string{host.Header_AcceptEncoding: "gzip"})
+		assert.Empty(t, resp.Header.Get(host.Header_ContentEncoding), prefix)
+		assert.Equal(t, etag, resp.Header.Get("ETag"), prefix)
+
+		resp, body = do(t, client, http.MethodGet, url, nil, withIdentity("Range", "bytes=0-3"))
+		assert.Equal(t, http.StatusPartialContent, resp.StatusCode, prefix)
+		assert.Equal(t, string(raw[:4]), body, prefix)
+		assert.Equal(t, "bytes 0-3/"+strconv.Itoa(len(raw)), resp.Header.Get("Content-Range"), prefix)
+
+		resp, body = do(t, client, http.MethodGet, url, nil, withIdentity("Range", "bytes=-3"))
+		assert.Equal(t, http.StatusPartialContent, resp.StatusCode, prefix)
+		assert.Equal(t, string(raw[len(raw)-3:]), body, prefix)
+		resp, body = do(t, client, http.MethodGet, url, nil, withIdentity("Range", "bytes=2-"))
+		assert.Equal(t, http.StatusPartialContent, resp.StatusCode, prefix)
+		assert.Equal(t, string(raw[2:]), body, prefix)
+		resp, _ = do(t, client, http.MethodGet, url, nil, withIdentity("Range", "bytes="+strconv.Itoa(len(raw))+"-"))
+		assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, resp.StatusCode, prefix)
+		assert.Equal(t, "bytes */"+strconv.Itoa(len(raw)), resp.Header.Get("Content-Range"), prefix)
+		// If-Range与当前ETag不一致时返回整个文件
+		resp, body = do(t, client, http.MethodGet, url, nil, map[string]string{host.Header_AcceptEncoding: "identity", "Range": "bytes=0-3", "If-Range": `"stale"`})
+		assert.Equal(t, http.StatusOK, resp.StatusCode, prefix)
+		assert.Equal(t, string(raw), body, prefix)
+		resp, _ = do(t, client, http.MethodGet, url, nil, withIdentity("If-Match", `"stale"`))
+		assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode, prefix)
+
+		resp, body = do(t, client, http.MethodHead, url, nil, identity)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, prefix)
+		assert.Empty(t, body, prefix)
+		assert.Equal(t, etag, resp.Header.Get("ETag"), prefix)
+		assert.Equal(t, int64(len(raw)), resp.ContentLength, prefix)
+
+		// 带指纹的文件长期缓存
+		resp, _ = do(t, client, http.MethodGet, baseURL+prefix+"/assets/app.3f9a1c2b.js", nil, identity)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, prefix)
+		assert.Equal(t, "public, max-age=31536000, immutable", resp.Header.Get("Cache-Control"), prefix)
+
+		// 目录返回IndexName
+		resp, body = do(t, client, http.MethodGet, baseURL+prefix+"/", nil, identity)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, prefix)
+		assert.Equal(t, string(index), body, prefix)
+		assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"), prefix)
+
+		// 前端路由回退到IndexName，其它请求仍返回404
+		resp, body = do(t, client, http.MethodGet, baseURL+prefix+"/users/7", nil, withIdentity("Accept", "text/html,application/xhtml+xml"))
+		assert.Equal(t, http.StatusOK, resp.StatusCode, prefix)
+		assert.Equal(t, string(index), body, prefix)
+		assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html"), prefix)
+		assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"), prefix)
+		resp, _ = do(t, client, http.MethodGet, baseURL+prefix+"/missing.js", nil, identity)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, prefix)
+	}
+
+	// 磁盘文件支持If-Modified-Since，嵌入的文件没有修改时间
+	resp, _ := do(t, client, http.MethodGet, baseURL+"/static/app.js", nil, identity)
+	lastModified := resp.Header.Get("Last-Modified")
+	require.NotEmpty(t, lastModified)
+	resp, _ = do(t, client, http.MethodGet, baseURL+"/static/app.js", nil, withIdentity("If-Modified-Since", lastModified))
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	resp, _ = do(t, client, http.MethodGet, baseURL+"/embed/app.js", nil, identity)
+	assert.Empty(t, resp.Header.Get("Last-Modified"))
+}
+
+// testRateLimit 只配置RateLimit，不设默认规则
+func testRateLimit(t *testing.T, factory HostFactory) {
+	s := start(t, factory, func(x *host.BaseWebHost) {
+		x.RateLimit = &host.RateLimitOptions{}
+	}, func(h host.IWebHost) {
+		registerCommon(h)
+		limited := host.NewAction("GET/limited", "limited", func(ctx host.IHttpContext) {
+			ctx.WriteString(ctx.GetItemString(host.Ctx_UserID))
+		})
+		limited.RateLimit = &host.RateLimitRule{Limit: 2, WindowSeconds: 60, KeyBy: []string{host.RateLimitKey_User}}
+		h.AddActionGroups(host.NewActionGroup([]host.RequestHandler{func(ctx host.IHttpContext) {
+			ctx.SetItem(host.Ctx_UserID, ctx.GetHeader("X-User"))
+			ctx.Next()
+		}}, []*host.Action{limited}))
+	})
+	defer s.shutdown(t)
+	client, baseURL := s.client, s.baseURL
+
+	for i := 1; i <= 2; i++ {
+		resp, body := do(t, client, http.MethodGet, baseURL+"/limited", nil, map[string]string{"X-User": "u1"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "u1", body)
+		assert.Equal(t, "2", resp.Header.Get(host.Header_RateLimitLimit))
+		assert.Equal(t, strconv.Itoa(2-i), resp.Header.Get(host.Header_RateLimitRemaining))
+	}
+
+	resp, body := do(t, client, http.MethodGet, baseURL+"/limited", nil, map[string]string{"X-User": "u1"})
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, host.CType_ProblemJson, resp.Header.Get("Content-Type"))
+	assert.Equal(t, "0", resp.Header.Get(host.Header_RateLimitRemaining))
+	retryAfter, err := strconv.Atoi(resp.Header.Get(host.Header_RetryAfter))
+	assert.NoError(t, err)
+	assert.True(t, retryAfter > 0 && retryAfter <= 30, retryAfter)
+	assert.Contains(t, body, `"status":429`)
+
+	// 按用户分别计数
+	resp, _ = do(t, client, http.MethodGet, baseURL+"/limited", nil, map[string]string{"X-User": "u2"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 未覆盖规则的路由不限流
+	resp, _ = do(t, client, http.MethodGet, baseURL+"/header", nil, map[string]string{"X-In": "x"})
+	assert.Empty(t, resp.Header.Get(host.Header_RateLimitLimit))
+}
+
+// testGlobalRateLimit 配置按用户限流的默认规则，用户由认证类的全局中间件设置
+func testGlobalRateLimit(t *testing.T, factory HostFactory) {
+	s := start(t, factory, func(x *host.BaseWebHost) {
+		x.RateLimit = &host.RateLimitOptions{
+			RateLimitRule: host.RateLimitRule{Limit: 1, WindowSeconds: 60, KeyBy: []string{host.RateLimitKey_User}},
+		}
+	}, func(h host.IWebHost) {
+		h.AddGlobalPreHandlers(true, func(ctx host.IHttpContext) {
+			ctx.SetItem(host.Ctx_UserID, ctx.GetHeader("X-User"))
+			ctx.Next()
+		})
+		h.GET("/global", func(ctx host.IHttpContext) {
+			ctx.WriteString(ctx.GetItemString(host.Ctx_UserID))
+		})
+	})
+	defer s.shutdown(t)
+	client, baseURL := s.client, s.baseURL
+
+	resp, body := do(t, client, http.MethodGet, baseURL+"/global", nil, map[string]string{"X-User": "u1"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "u1", body)
+	assert.Equal(t, "1", resp.Header.Get(host.Header_RateLimitLimit))
+	resp, _ = do(t, client, http.MethodGet, baseURL+"/global", nil, map[string]string{"X-User": "u1"})
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	// 同一IP的其他用户单独计数
+	resp, _ = do(t, client, http.MethodGet, baseURL+"/global", nil, map[string]string{"X-User": "u2"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+// testMetrics 只配置Metrics，在默认路径上暴露
+func testMetrics(t *testing.T, factory HostFactory) {
+	s := start(t, factory, func(x *host.BaseWebHost) {
+		x.Metrics = &host.MetricsOptions{}
+	}, registerCommon)
+	defer s.shutdown(t)
+	client, baseURL := s.client, s.baseURL
+
+	do(t, client, http.MethodGet, baseURL+"/header", nil, map[string]string{"X-In": "m"})
+	do(t, client, http.MethodGet, baseURL+"/error/notfound", nil, nil)
+
+	resp, body := do(t, client, http.MethodGet, baseURL+"/metrics", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain"))
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/header",status="200"}`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/error/notfound",status="404"}`)
+	assert.Contains(t, body, `http_request_duration_seconds_bucket{method="GET",route="/header",status="200",le="+Inf"}`)
+	assert.Contains(t, body, `http_response_size_bytes_count{method="GET",route="/header",status="200"}`)
+	assert.Contains(t, body, `http_requests_in_flight{route="/header"} 0`)
+	// 指标路径本身不统计
+	assert.NotContains(t, body, `route="/metrics"`)
+	assertNativeRoute(t, s.host, "/metrics", "metrics")
+}
+
+// testMetricsServer 指标服务单独监听时在宿主监听成功后启动，任一监听失败时不遗留另一个，宿主关闭时一并关闭
+func testMetricsServer(t *testing.T, factory HostFactory) {
+	busy, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer busy.Close()
+	withMetricsAddr := func(addr string) func(*host.BaseWebHost) {
+		return func(x *host.BaseWebHost) {
+			x.Metrics = &host.MetricsOptions{ListenAddr: addr}
+		}
+	}
+
+	metricsAddr := freeAddr(t)
+	h := factory(busy.Addr().String(), withMetricsAddr(metricsAddr))
+	assert.Error(t, h.RunContext(context.Background()))
+	assertFree(t, metricsAddr)
+
+	addr := freeAddr(t)
+	h = factory(addr, withMetricsAddr(busy.Addr().String()))
+	assert.Error(t, h.RunContext(context.Background()))
+	assertFree(t, addr)
+
+	metricsAddr = freeAddr(t)
+	s := start(t, factory, withMetricsAddr(metricsAddr), registerCommon)
+	resp, body := do(t, s.client, http.MethodGet, "http://"+metricsAddr+"/metrics", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "go_goroutines")
+	s.shutdown(t)
+	assertFree(t, metricsAddr)
+}
+
+// testTracing 只启用Tracing
+func testTracing(t *testing.T, factory HostFactory) {
+	s := start(t, factory, func(x *host.BaseWebHost) {
+		x.Tracing = &host.TracingOptions{Exporter: host.TracingExporter_Memory, TraceHandlers: true}
+	}, func(h host.IWebHost) {
+		h.GET("/tracing", func(ctx host.IHttpContext) {
+			var traceparent string
+			outbound := &http.Client{Transport: host.NewTracingTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				traceparent = req.Header.Get("traceparent")
+				return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody, Request: req}, nil
+			}), host.GetTraceContext(ctx))}
+			resp, err := outbound.Get("http://downstream.test/")
+			if err != nil {
+				ctx.Error(err)
+				return
+			}
+			resp.Body.Close()
+			ctx.WriteString(trace.SpanContextFromContext(host.GetTraceContext(ctx)).TraceID().String() + "|" + traceparent)
+		})
+	})
+	defer s.shutdown(t)
+	client, baseURL := s.client, s.baseURL
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	resp, body := do(t, client, http.MethodGet, baseURL+"/tracing", nil, map[string]string{"traceparent": "00-" + traceID + "-00f067aa0ba902b7-01"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	parts := strings.Split(body, "|")
+	require.Len(t, parts, 2)
+	// 服务端span继续调用方的链路
+	assert.Equal(t, traceID, parts[0])
+	// 出站请求携带同一链路的traceparent，父span为新建的客户端span
+	assert.True(t, strings.HasPrefix(parts[1], "00-"+traceID+"-"), parts[1])
+	assert.NotContains(t, parts[1], "00f067aa0ba902b7")
+
+	// 没有traceparent时开始新的链路
+	_, body = do(t, client, http.MethodGet, baseURL+"/tracing", nil, nil)
+	parts = strings.Split(body, "|")
+	require.Len(t, parts, 2)
+	assert.NotEqual(t, traceID, parts[0])
+	assert.True(t, strings.HasPrefix(parts[1], "00-"+parts[0]+"-"), parts[1])
+}
+
+// testHealth 只配置Health，在默认路径上暴露
+func testHealth(t *testing.T, factory HostFactory) {
+	s := start(t, factory, func(x *host.BaseWebHost) {
+		x.Health = &host.HealthOptions{}
+	}, func(h host.IWebHost) {
+		h.AddHealthCheck("suite", func(ctx context.Context) error { return nil })
+		h.AddHealthCheck("failing", func(ctx context.Context) error { return errors.New("dependency unavailable") })
+	})
+	defer s.shutdown(t)
+	client, baseURL := s.client, s.baseURL
+
+	resp, body := do(t, client, http.MethodGet, baseURL+"/healthz", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"status":"up"}`, body)
+
+	resp, body = do(t, client, http.MethodGet, baseURL+"/readyz", nil, nil)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	var report host.HealthReport
+	require.NoError(t, json.Unmarshal([]byte(body), &report))
+	assert.Equal(t, host.HealthStatus_Down, report.Status)
+	assert.Equal(t, host.HealthStatus_Up, report.Checks["suite"].Status)
+	assert.Equal(t, host.HealthStatus_Down, report.Checks["failing"].Status)
+	assert.Equal(t, "dependency unavailable", report.Checks["failing"].Error)
+	// 健康检查不经过全局中间件
+	assert.Empty(t, resp.Header.Get(host.Header_RequestID))
+	assertNativeRoute(t, s.host, "/healthz", "health.live")
+	assertNativeRoute(t, s.host, "/readyz", "health.ready")
+}
+
+// testCORS 只配置CORS
+func testCORS(t *testing.T, factory HostFactory) {
+	s := start(t, factory, func(x *host.BaseWebHost) {
+		x.CORS = &host.CORSOptions{
+			AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+			AllowedHeaders:   []string{"X-In"},
+			ExposedHeaders:   []string{"X-Out"},
+			AllowCredentials: true,
+			MaxAgeSeconds:    600,
+		}
+	}, func(h host.IWebHost) {
+		// 认证类的全局中间件，拒绝没有Authorization的请求
+		h.AddGlobalPreHandlers(true, func(ctx host.IHttpContext) {
+			if strings.HasPrefix(ctx.RequestPath(), "/cors/secure") && ctx.GetHeader("Authorization") == "" {
+				ctx.SetStatusCode(http.StatusUnauthorized)
+				return
+			}
+			ctx.Next()
+		})
+		registerCommon(h)
+		h.OPTIONS("/cors/options", func(ctx host.IHttpContext) {
+			ctx.WriteString("user options")
+		})
+		ping := func(ctx host.IHttpContext) {
+			ctx.WriteString("pong")
+		}
+		public := h.Group("/cors/public")
+		public.CORS(&host.CORSOptions{AllowedOrigins: []string{"*"}})
+		public.GET("/ping", ping)
+		private := h.Group("/cors/private")
+		private.CORS(nil)
+		private.GET("/ping", ping)
+		h.GET("/cors/secure/data", ping)
+	})
+	defer s.shutdown(t)
+	client, baseURL := s.client, s.baseURL
+
+	const origin = "https://app.example.com"
+	preflight := map[string]string{
+		host.Header_Origin:                      origin,
+		host.Header_AccessControlRequestMethod:  http.MethodGet,
+		host.Header_AccessControlRequestHeaders: "x-in",
+	}
+
+	// 没有OPTIONS路由的预检请求
+	resp, _ := do(t, client, http.MethodOptions, baseURL+"/header", nil, preflight)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, origin, resp.Header.Get(host.Header_AccessControlAllowOrigin))
+	assert.Equal(t, "true", resp.Header.Get(host.Header_AccessControlAllowCredentials))
+	assert.Contains(t, resp.Header.Get(host.Header_AccessControlAllowMethods), http.MethodGet)
+	assert.Equal(t, "X-In", resp.Header.Get(host.Header_AccessControlAllowHeaders))
+	assert.Equal(t, "600", resp.Header.Get(host.Header_AccessControlMaxAge))
+	assert.Contains(t, resp.Header.Get(host.Header_Vary), host.Header_Origin)
+
+	// 预检请求不经过用户的全局中间件
+	resp, _ = do(t, client, http.MethodOptions, baseURL+"/cors/secure/data", nil, preflight)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, origin, resp.Header.Get(host.Header_AccessControlAllowOrigin))
+	resp, _ = do(t, client, http.MethodGet, baseURL+"/cors/secure/data", nil, map[string]string{host.Header_Origin: origin})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// 不存在的路径不响应预检请求
+	resp, _ = do(t, client, http.MethodOptions, baseURL+"/does-not-exist", nil, preflight)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(host.Header_AccessControlAllowMethods))
+
+	// 不允许的请求头
+	resp, _ = do(t, client, http.MethodOptions, baseURL+"/header", nil, map[string]string{
+		host.Header_Origin:                      origin,
+		host.Header_AccessControlRequestMethod:  http.MethodGet,
+		host.Header_AccessControlRequestHeaders: "x-other",
+	})
+	assert.Empty(t, resp.Header.Get(host.Header_AccessControlAllowOrigin))
+
+	// 用户注册的OPTIONS路由不被覆盖
+	resp, body := do(t, client, http.MethodOptions, baseURL+"/cors/options", nil, preflight)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "user options", body)
+	assert.Equal(t, origin, resp.Header.Get(host.Header_AccessControlAllowOrigin))
+
+	// 通配子域名
+	resp, _ = do(t, client, http.MethodGet, baseURL+"/header", nil, map[string]string{"X-In": "c", host.Header_Origin: "https://a.b.example.org"})
+	assert.Equal(t, "https://a.b.example.org", resp.Header.Get(host.Header_AccessControlAllowOrigin))
+	assert.Equal(t, "X-Out", resp.Header.Get(host.Header_AccessControlExposeHeaders))
+
+	resp, _ = do(t, client, http.MethodGet, baseURL+"/header", nil, map[string]string{"X-In": "c", host.Header_Origin: "https://example.org"})
+	assert.Empty(t, resp.Header.Get(host.Header_AccessControlAllowOrigin))
+	assert.Contains(t, resp.Header.Get(host.Header_Vary), host.Header_Origin)
+
+	// 路由组覆盖
+	resp, _ = do(t, client, http.MethodGet, baseURL+"/cors/public/ping", nil, map[string]string{host.Header_Origin: "https://other.test"})
+	assert.Equal(t, "*", resp.Header.Get(host.Header_AccessControlAllowOrigin))
+	assert.Empty(t, resp.Header.Get(host.Header_AccessControlAllowCredentials))
+
+	resp, _ = do(t, client, http.MethodGet, baseURL+"/cors/private/ping", nil, map[string]string{host.Header_Origin: origin})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(host.Header_AccessControlAllowOrigin))
+}
+
+// testSecurityHeaders 只配置SecurityHeaders
+func testSecurityHeaders(t *testing.T, factory HostFactory) {
+	s := start(t, factory, func(x *host.BaseWebHost) {
+		x.SecurityHeaders = securityHeadersOptions()
+	}, func(h host.IWebHost) {
+		registerCommon(h)
+		h.GET("/nonce", func(ctx host.IHttpContext) {
+			ctx.WriteString(ctx.GetCSPNonce())
+		})
+	})
+	defer s.shutdown(t)
+	client, baseURL := s.client, s.baseURL
+
+	resp, nonce := do(t, client, http.MethodGet, baseURL+"/nonce", nil, nil)
+	assert.Equal(t, "max-age=31536000; includeSubDomains", resp.Header.Get(host.Header_StrictTransportSecurity))
+	assert.Equal(t, "nosniff", resp.Header.Get(host.Header_ContentTypeOptions))
+	assert.Equal(t, "DENY", resp.Header.Get(host.Header_FrameOptions))
+	assert.Equal(t, "camera=()", resp.Header.Get(host.Header_PermissionsPolicy))
+	assert.Empty(t, resp.Header.Get(host.Header_ReferrerPolicy))
+	assert.NotEmpty(t, nonce)
+	assert.Equal(t, "default-src 'self'; script-src 'self' 'nonce-"+nonce+"'", resp.Header.Get(host.Header_ContentSecurityPolicy))
+
+	_, other := do(t, client, http.MethodGet, baseURL+"/nonce", nil, nil)
+	assert.NotEqual(t, nonce, other)
+
+	// 错误响应同样带有
+	resp, _ = do(t, client, http.MethodGet, baseURL+"/error/internal", nil, nil)
+	assert.Equal(t, "nosniff", resp.Header.Get(host.Header_ContentTypeOptions))
+}
+
+// testCSRF 只配置CSRF（session模式）
+func testCSRF(t *testing.T, factory HostFactory) {
+	s := start(t, factory, func(x *host.BaseWebHost) {
+		x.CSRF = &host.CSRFOptions{
+			TrustedOrigins: []string{"https://trusted.test/"},
+			ExemptRoutes:   []string{"/csrf/webhook"},
+		}
+	}, func(h host.IWebHost) {
+		ok := func(ctx host.IHttpContext) {
+			ctx.WriteString("ok")
+		}
+		h.GET("/csrf/plain", ok)
+		h.GET("/csrf/form", func(ctx host.IHttpContext) {
+			ctx.WriteString(host.GetCSRFToken(ctx))
+		})
+		h.POST("/csrf/submit", ok)
+		h.POST("/csrf/webhook", ok)
+		skip := host.NewAction("POST/csrf/skip", "csrf_skip", ok)
+		skip.SkipCSRF = true
+		h.AddActions(skip)
+	})
+	defer s.shutdown(t)
+	client, baseURL := s.client, s.baseURL
+
+	// 不取令牌的请求不写Session
+	resp, _ := do(t, client, http.MethodGet, baseURL+"/csrf/plain", nil, nil)
+	assert.Empty(t, resp.Header.Values("Set-Cookie"))
+	// 没有令牌时非安全方法的请求被拒绝
+	resp, _ = do(t, client, http.MethodPost, baseURL+"/csrf/submit", nil, map[string]string{host.Header_CSRFToken: "token"})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, token := do(t, client, http.MethodGet, baseURL+"/csrf/form", nil, nil)
+	require.NotEmpty(t, token)
+	_, again := do(t, client, http.MethodGet, baseURL+"/csrf/form", nil, nil)
+	assert.Equal(t, token, again)
+
+	resp, body := do(t, client, http.MethodPost, baseURL+"/csrf/submit", nil, map[string]string{host.Header_CSRFToken: token})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ok", body)
+
+	resp, _ = do(t, client, http.MethodPost, baseURL+"/csrf/submit", strings.NewReader(url.Values{"_csrf": {token}}.Encode()), map[string]string{"Content-Type": "application/x-www-form-urlencoded"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, _ = do(t, client, http.MethodPost, baseURL+"/csrf/submit", nil, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp, _ = do(t, client, http.MethodPost, baseURL+"/csrf/submit", nil, map[string]string{host.Header_CSRFToken: token + "x"})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Origin检查
+	resp, _ = do(t, client, http.MethodPost, baseURL+"/csrf/submit", nil, map[string]string{host.Header_CSRFToken: token, host.Header_Origin: "https://evil.test"})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp, _ = do(t, client, http.MethodPost, baseURL+"/csrf/submit", nil, map[string]string{host.Header_CSRFToken: token, "Referer": "https://evil.test/page"})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp, _ = do(t, client, http.MethodPost, baseURL+"/csrf/submit", nil, map[string]string{host.Header_CSRFToken: token, host.Header_Origin: baseURL})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = do(t, client, http.MethodPost, baseURL+"/csrf/submit", nil, map[string]string{host.Header_CSRFToken: token, host.Header_Origin: "https://trusted.test"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 豁免的路由和SkipCSRF的Action
+	resp, _ = do(t, client, http.MethodPost, baseURL+"/csrf/webhook", nil, map[string]string{host.Header_Origin: "https://evil.test"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = do(t, client, http.MethodPost, baseURL+"/csrf/skip", nil, map[string]string{host.Header_Origin: "https://evil.test"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+// testAccessLog 只配置AccessLog，使用combined格式写入缓冲区
+func testAccessLog(t *testing.T, factory HostFactory) {
+	out := new(lockedBuffer)
+	s := start(t, factory, func(x *host.BaseWebHost) {
+		x.AccessLog = &host.AccessLogOptions{Format: host.AccessLogFormat_Combined, ExcludePaths: []string{"/error/*"}, Writer: out}
+	}, registerCommon)
+	defer s.shutdown(t)
+	client, baseURL := s.client, s.baseURL
+
+	do(t, client, http.MethodGet, baseURL+"/error/internal", nil, nil)
+	do(t, client, http.MethodGet, baseURL+"/header?x=1", nil, map[string]string{"X-In": "log", "Referer": "https://app.example.com/", "User-Agent": "suite/1.0"})
+
+	// 响应可能先于日志写入到达客户端
+	var line string
+	require.Eventually(t, func() bool {
+		line = out.String()
+		return line != ""
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Regexp(t, `^127\.0\.0\.1 - - \[[^\]]+\] "GET /header\?x=1 HTTP/1\.1" 200 3 "https://app\.example\.com/" "suite/1\.0"\n$`, line)
+}
+
+// testRoutes 只配置RouteTable，在默认路径上暴露
+func testRoutes(t *testing.T, factory HostFactory) {
+	s := start(t, factory, func(x *host.BaseWebHost) {
+		x.RouteTable = &host.RouteTableOptions{}
+	}, func(h host.IWebHost) {
+		registerRoot(h)
+		registerAPI(h)
+		registerFiles(h)
+	})
+	defer s.shutdown(t)
+	h, client, baseURL := s.host, s.client, s.baseURL
+
+	routes := make(map[string]*host.RouteInfo)
+	for _, route := range h.Routes() {
+		routes[route.Method+" "+route.Path] = route
+	}
+
+	root := routes["GET /"]
+	require.NotNil(t, root)
+	assert.Equal(t, "/", root.RouteKey)
+	assert.False(t, root.Native)
+	assert.Len(t, root.Handlers, 1)
+	assert.Contains(t, root.Middleware, "host.ErrorHandler")
+	assert.Contains(t, root.Middleware, "host.RequestIDHandler")
+
+	action := routes["GET /action"]
+	require.NotNil(t, action)
+	assert.Equal(t, "root_test_action", action.RouteKey)
+	assert.Equal(t, []string{"root", "test", "action"}, []string{action.Area, action.Controller, action.Action})
+
+	// 路由组和ActionGroup的中间件
+	daily := routes["GET /api/v1/reports/daily"]
+	require.NotNil(t, daily)
+	assert.Equal(t, "daily_report", daily.RouteKey)
+	assert.Len(t, daily.Handlers, 3)
+	assert.Len(t, routes["GET /api/v1/admin/users"].Handlers, 3)
+	require.NotNil(t, routes["GET /embed/{filepath:*}"])
+	assert.True(t, routes["GET /embed/{filepath:*}"].Native)
+
+	resp, body := do(t, client, http.MethodGet, baseURL+"/debug/routes", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var listed []*host.RouteInfo
+	require.NoError(t, json.Unmarshal([]byte(body), &listed))
+	assert.Len(t, listed, len(routes))
+	assert.Contains(t, body, `"route_key":"daily_report","area":"daily","controller":"report"`)
+}
+
+// testOpenAPI 配置OpenAPI，并配置SecurityHeaders以测试Swagger UI使用CSP nonce
+func testOpenAPI(t *testing.T, factory HostFactory) {
+	s := start(t, factory, func(x *host.BaseWebHost) {
+		x.OpenAPI = &host.OpenAPIOptions{
+			Title:         "Suite",
+			SwaggerUIPath: "/swagger",
+		}
+		x.SecurityHeaders = securityHeadersOptions()
+	}, func(h host.IWebHost) {
+		registerRoot(h)
+		registerParams(h)
+		registerBind(h)
+		registerFiles(h)
+	})
+	defer s.shutdown(t)
+	client, baseURL := s.client, s.baseURL
+
+	resp, body := do(t, client, http.MethodGet, baseURL+"/openapi.json", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var doc host.OpenAPIDocument
+	require.NoError(t, json.Unmarshal([]byte(body), &doc))
+	assert.Equal(t, "Suite", doc.Info.Title)
+	require.Contains(t, doc.Paths, "/users/{id}")
+	assert.Equal(t, "id", doc.Paths["/users/{id}"]["get"].Parameters[0].Name)
+	assert.Equal(t, "root_test_action", doc.Paths["/action"]["get"].OperationID)
+	assert.Contains(t, doc.Paths["/bind/{id}"], "post")
+	// 原生路由和文档端点不在文档中
+	assert.NotContains(t, doc.Paths, "/embed/{filepath}")
+	assert.NotContains(t, doc.Paths, "/openapi.json")
+	assert.NotContains(t, doc.Paths, "/swagger")
+
+	resp, body = do(t, client, http.MethodGet, baseURL+"/swagger", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, body, `<script src="/swagger/swagger-ui-bundle.js"`)
+	assert.Contains(t, body, `<link rel="stylesheet" href="/swagger/swagger-ui.css">`)
+	// 脚本带有当前请求的CSP nonce
+	csp := resp.Header.Get(host.Header_ContentSecurityPolicy)
+	nonce := csp[strings.Index(csp, "'nonce-")+7:]
+	nonce = nonce[:strings.IndexByte(nonce, '\'')]
+	assert.Contains(t, html.UnescapeString(body), `<script nonce="`+nonce+`">`)
+
+	// 嵌入的资源在SwaggerUIPath下提供，不在文档中
+	assert.NotContains(t, doc.Paths, "/swagger/{filepath}")
+	resp, body = do(t, client, http.MethodGet, baseURL+"/swagger/swagger-ui-bundle.js", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "javascript")
+	assert.Contains(t, body, "SwaggerUIBundle")
+	resp, body = do(t, client, http.MethodGet, baseURL+"/swagger/swagger-ui.css", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/css")
+	assert.Contains(t, body, ".swagger-ui")
+	resp, _ = do(t, client, http.MethodGet, baseURL+"/swagger/missing.js", nil, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// testRouteConfig 只配置RouteConfigs
+func testRouteConfig(t *testing.T, factory HostFactory) {
+	s := start(t, factory, func(x *host.BaseWebHost) {
+		x.RouteConfigs = routeConfigs()
+	}, func(h host.IWebHost) {
+		registerRoot(h)
+		h.RegisterHandler("config.order", writeTrace)
+		h.RegisterMiddleware("config.trace", func(ctx host.IHttpContext) {
+			ctx.SetItem("trace", ctx.GetItemString("trace")+"|config")
+			ctx.Next()
+		})
+	})
+	defer s.shutdown(t)
+	h, client, baseURL := s.host, s.client, s.baseURL
+
+	resp, body := do(t, client, http.MethodGet, baseURL+"/config/7", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "pre|config|config_order_get|7|suf", body)
+
+	for _, route := range h.Routes() {
+		if route.Path == "/config/{id}" {
+			assert.Equal(t, "order", route.Controller)
+			return
+		}
+	}
+	t.Error("configured route is not listed")
+}
+
+// securityHeadersOptions 安全响应头配置，nonce占位符由每个请求的nonce替换
+func securityHeadersOptions() *host.SecurityHeadersOptions {
+	return &host.SecurityHeadersOptions{
+		HSTSMaxAgeSeconds:     31536000,
+		HSTSIncludeSubdomains: true,
+		ReferrerPolicy:        host.SecurityHeaderDisabled,
+		PermissionsPolicy:     "camera=()",
+		ContentSecurityPolicy: "default-src 'self'; script-src 'self' 'nonce-{nonce}'",
+	}
+}
+
+// routeConfigs 路由配置，引用的Handler和中间件在注册路由时按名称注册
+func routeConfigs() []*host.RouteConfig {
+	var r []*host.RouteConfig
+	err := json.Unmarshal([]byte(`[
+		{"Method": "get", "Path": "/config/{id}", "RouteKey": "config_order_get", "Handler": "config.order", "Middleware": ["config.trace"]}
+	]`), &r)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// registerFiles 注册嵌入、磁盘目录和fs.FS三种静态文件路由
+func registerFiles(h host.IWebHost) {
+	h.ServeEmbedFiles("/embed/{filepath:*}", "testdata/static", _testdata)
+	_, file, _, _ := runtime.Caller(0)
+	h.ServeFiles("/files/{filepath:*}", filepath.Join(filepath.Dir(file), "testdata", "static"))
+	h.ServeStatic("/static/{filepath:*}", os.DirFS(filepath.Join(filepath.Dir(file), "testdata", "static")))
+}
+
+// assertNativeRoute 检查路由记录为不经过全局中间件的原生路由
+func assertNativeRoute(t *testing.T, h host.IWebHost, path, name string) {
+	for _, route := range h.Routes() {
+		if route.Method == http.MethodGet && route.Path == path {
+			assert.True(t, route.Native, path)
+			assert.Equal(t, []string{name}, route.Handlers, path)
+			assert.Empty(t, route.Middleware, path)
+			return
+		}
+	}
+	t.Errorf("native route %s is not listed", path)
+}
+
+// lockedBuffer 可并发读写的缓冲区
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (x *lockedBuffer) Write(p []byte) (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.buf.Write(p)
+}
+
+func (x *lockedBuffer) String() string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.buf.String()
+}
+
+// assertFree 检查地址未被监听
+func assertFree(t *testing.T, addr string) {
+	ln, err := net.Listen("tcp4", addr)
+	if assert.NoError(t, err, addr) {
+		ln.Close()
+	}
+}
@@ -0,0 +1,88 @@
+package hostsuite
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/syncfuture/host"
+	"github.com/syncfuture/host/hosttest"
+)
+
+// RunInMemory 通过hosttest在内存中运行两个宿主，测试请求构建、Cookie容器和跨宿主的登录跳转
+func RunInMemory(t *testing.T, factory HostFactory) {
+	app := factory(hosttest.DefaultHost+":80", nil)
+	auth := factory("auth.test:80", nil)
+
+	////////// 模拟的授权服务：记住登录用户，携带code跳回redirect_uri
+	auth.GET("/authorize", func(ctx host.IHttpContext) {
+		ctx.SetSession("user", "tom")
+		redirectURI := ctx.GetFormString("redirect_uri")
+		ctx.Redirect(redirectURI+"?code=code_"+ctx.GetSessionString("user")+"&state="+url.QueryEscape(ctx.GetFormString("state")), http.StatusFound)
+	})
+
+	////////// 客户端宿主
+	app.GET("/signin", func(ctx host.IHttpContext) {
+		ctx.SetSession("state", "s1")
+		ctx.Redirect("http://auth.test/authorize?state=s1&redirect_uri="+url.QueryEscape("http://"+hosttest.DefaultHost+"/callback"), http.StatusFound)
+	})
+	app.GET("/callback", func(ctx host.IHttpContext) {
+		if ctx.GetFormString("state") != ctx.GetSessionString("state") {
+			ctx.SetStatusCode(http.StatusBadRequest)
+			return
+		}
+		ctx.SetSession("user", strings.TrimPrefix(ctx.GetFormString("code"), "code_"))
+		ctx.Redirect("/me", http.StatusFound)
+	})
+	app.GET("/me", func(ctx host.IHttpContext) {
+		ctx.WriteString(ctx.GetSessionString("user"))
+	})
+	app.POST("/echo", func(ctx host.IHttpContext) {
+		var body map[string]interface{}
+		if err := ctx.ReadJSON(&body); err != nil {
+			ctx.SetStatusCode(http.StatusBadRequest)
+			return
+		}
+		body["authorization"] = ctx.GetHeader("Authorization")
+		body["cookie"] = ctx.GetCookieString("c")
+		body["q"] = ctx.GetFormString("q")
+		data, _ := json.Marshal(body)
+		ctx.WriteJsonBytes(data)
+	})
+
+	client := hosttest.Start(t, app)
+	client.StartHost("auth.test", auth)
+
+	t.Run("Request", func(t *testing.T) {
+		client.POST("/echo").
+			Query("q", "1").
+			BearerToken("t1").
+			Cookie("c", "v").
+			JSON(map[string]string{"name": "tom"}).
+			Send().
+			AssertStatus(http.StatusOK).
+			AssertJSON(`{"name":"tom","authorization":"Bearer t1","cookie":"v","q":"1"}`)
+	})
+
+	t.Run("Redirect", func(t *testing.T) {
+		resp := client.GET("/callback?state=x").Send()
+		resp.AssertStatus(http.StatusBadRequest)
+
+		client.GET("/signin").Send().
+			AssertRedirect("http://auth.test/authorize?state=s1&redirect_uri=" + url.QueryEscape("http://"+hosttest.DefaultHost+"/callback"))
+	})
+
+	t.Run("SignInFlow", func(t *testing.T) {
+		client.GET("/signin").FollowRedirects().Send().
+			AssertStatus(http.StatusOK).
+			AssertBody("tom")
+
+		// 两个宿主各自的会话Cookie都保存在容器中
+		client.GET("/me").Send().AssertBody("tom")
+		if len(client.Cookies("/")) == 0 || len(client.Cookies("http://auth.test/")) == 0 {
+			t.Fatal("session cookies are not kept")
+		}
+	})
+}
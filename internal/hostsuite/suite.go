@@ -0,0 +1,688 @@
+// Package hostsuite 各IWebHost实现共用的行为测试
+package hostsuite
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/fasthttp/websocket"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syncfuture/host"
+)
+
+//go:embed testdata
+var _testdata embed.FS
+
+type (
+	// HostFactory 使用指定监听地址创建宿主，configure不为nil时在构建宿主前调用，用于启用要测试的可选功能
+	HostFactory func(listenAddr string, configure func(*host.BaseWebHost)) host.IWebHost
+
+	// server 运行中的宿主
+	server struct {
+		host    host.IWebHost
+		addr    string
+		baseURL string
+		client  *http.Client
+		runErr  chan error
+	}
+
+	testForm struct {
+		Name string `schema:"name"`
+		Age  int    `schema:"age"`
+	}
+
+	testJson struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	testBind struct {
+		ID    int    `path:"id" json:"-"`
+		Lang  string `schema:"lang" json:"-" validate:"enum=en|zh"`
+		Name  string `schema:"name" json:"name" validate:"required,min=2,max=10"`
+		Email string `schema:"email" json:"email" validate:"regex=^[^@]+@[^@]+$"`
+	}
+)
+
+// Run 运行行为测试，基础功能在未启用可选功能的宿主上测试，每个可选功能在只配置了该功能的宿主上测试
+func Run(t *testing.T, factory HostFactory) {
+	sseDone := make(chan struct{}, 1)
+	disconnectStarted := make(chan struct{}, 1)
+	disconnectErr := make(chan error, 1)
+	s := start(t, factory, nil, func(h host.IWebHost) {
+		register(h, sseDone)
+		h.GET("/disconnect", func(ctx host.IHttpContext) {
+			disconnectStarted <- struct{}{}
+			select {
+			case <-ctx.Context().Done():
+				disconnectErr <- ctx.Context().Err()
+			case <-time.After(5 * time.Second):
+				disconnectErr <- nil
+			}
+		})
+	})
+	client, baseURL := s.client, s.baseURL
+
+	t.Run("RouteKeyAndGlobalHandlers", func(t *testing.T) {
+		resp, body := do(t, client, http.MethodGet, baseURL+"/", nil, nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "pre|/|suf", body)
+	})
+
+	t.Run("Action", func(t *testing.T) {
+		resp, body := do(t, client, http.MethodGet, baseURL+"/action", nil, nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "pre|root_test_action|suf", body)
+	})
+
+	t.Run("Params", func(t *testing.T) {
+		_, body := do(t, client, http.MethodGet, baseURL+"/users/42", nil, nil)
+		assert.Equal(t, "42", body)
+
+		// 正则约束和可选参数
+		resp, _ := do(t, client, http.MethodGet, baseURL+"/digits/12a", nil, nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		_, body = do(t, client, http.MethodGet, baseURL+"/digits/12", nil, nil)
+		assert.Equal(t, "12", body)
+		_, body = do(t, client, http.MethodGet, baseURL+"/optional", nil, nil)
+		assert.Equal(t, "opt:", body)
+		_, body = do(t, client, http.MethodGet, baseURL+"/optional/x", nil, nil)
+		assert.Equal(t, "opt:x", body)
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		_, body := do(t, client, http.MethodGet, baseURL+"/query?name=tom&age=3", nil, nil)
+		assert.Equal(t, "tom:3:tom", body)
+	})
+
+	t.Run("Form", func(t *testing.T) {
+		form := url.Values{"name": {"jerry"}, "age": {"5"}}
+		resp, body := do(t, client, http.MethodPost, baseURL+"/form", strings.NewReader(form.Encode()), map[string]string{
+			"Content-Type": "application/x-www-form-urlencoded",
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "jerry:5:jerry", body)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		resp, body := do(t, client, http.MethodPost, baseURL+"/json", strings.NewReader(`{"name":"spike","age":7}`), map[string]string{
+			"Content-Type": "application/json",
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json"))
+		assert.Equal(t, `{"name":"spike","age":8}`, body)
+	})
+
+	t.Run("StatusCodeAfterBody", func(t *testing.T) {
+		resp, body := do(t, client, http.MethodGet, baseURL+"/status", nil, nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "bad", body)
+	})
+
+	t.Run("Headers", func(t *testing.T) {
+		resp, body := do(t, client, http.MethodGet, baseURL+"/header", nil, map[string]string{"X-In": "hello"})
+		assert.Equal(t, "hello", body)
+		assert.Equal(t, "world", resp.Header.Get("X-Out"))
+	})
+
+	t.Run("Cookies", func(t *testing.T) {
+		do(t, client, http.MethodGet, baseURL+"/cookie/set", nil, nil)
+		_, body := do(t, client, http.MethodGet, baseURL+"/cookie/get", nil, nil)
+		assert.Equal(t, "v1", body)
+
+		do(t, client, http.MethodGet, baseURL+"/cookie/remove", nil, nil)
+		_, body = do(t, client, http.MethodGet, baseURL+"/cookie/get", nil, nil)
+		assert.Equal(t, "", body)
+	})
+
+	t.Run("Session", func(t *testing.T) {
+		do(t, client, http.MethodGet, baseURL+"/session/set", nil, nil)
+		_, body := do(t, client, http.MethodGet, baseURL+"/session/get", nil, nil)
+		assert.Equal(t, "s1", body)
+
+		do(t, client, http.MethodGet, baseURL+"/session/end", nil, nil)
+		_, body = do(t, client, http.MethodGet, baseURL+"/session/get", nil, nil)
+		assert.Equal(t, "", body)
+	})
+
+	t.Run("Redirect", func(t *testing.T) {
+		resp, _ := do(t, client, http.MethodGet, baseURL+"/redirect", nil, nil)
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+		assert.True(t, strings.HasSuffix(resp.Header.Get("Location"), "/target"))
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		resp, _ := do(t, client, http.MethodGet, baseURL+"/not-exists", nil, nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Group", func(t *testing.T) {
+		_, body := do(t, client, http.MethodGet, baseURL+"/api/v1/orders/7", nil, nil)
+		assert.Equal(t, "pre|api|/api/v1/orders/{id}|7|suf", body)
+
+		_, body = do(t, client, http.MethodGet, baseURL+"/api/v1/admin/users", nil, nil)
+		assert.Equal(t, "pre|api|admin|/api/v1/admin/users|suf", body)
+
+		_, body = do(t, client, http.MethodGet, baseURL+"/api/v1/reports/daily", nil, nil)
+		assert.Equal(t, "pre|api|report|daily_report|suf", body)
+
+		resp, _ := do(t, client, http.MethodGet, baseURL+"/api/v1/not-exists", nil, nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Bind", func(t *testing.T) {
+		resp, body := do(t, client, http.MethodPost, baseURL+"/bind/9?lang=zh", strings.NewReader(`{"name":"tom","email":"t@x.com"}`), map[string]string{
+			"Content-Type": "application/json; charset=utf-8",
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "9:zh:tom:t@x.com", body)
+
+		form := url.Values{"name": {"jerry"}}
+		_, body = do(t, client, http.MethodPost, baseURL+"/bind/10", strings.NewReader(form.Encode()), map[string]string{
+			"Content-Type": "application/x-www-form-urlencoded",
+		})
+		assert.Equal(t, "10::jerry:", body)
+
+		resp, body = do(t, client, http.MethodPost, baseURL+"/bind/11?lang=fr", strings.NewReader(`{"name":"t","email":"bad"}`), map[string]string{
+			"Content-Type": "application/json",
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, host.CType_ProblemJson, resp.Header.Get("Content-Type"))
+		var verr host.Problem
+		require.NoError(t, json.Unmarshal([]byte(body), &verr))
+		require.Len(t, verr.Errors, 3)
+		assert.Equal(t, "lang", verr.Errors[0].Field)
+		assert.Equal(t, "enum", verr.Errors[0].Rule)
+		assert.Equal(t, "name", verr.Errors[1].Field)
+		assert.Equal(t, "min", verr.Errors[1].Rule)
+		assert.Equal(t, "email", verr.Errors[2].Field)
+		assert.Equal(t, "regex", verr.Errors[2].Rule)
+
+		resp, _ = do(t, client, http.MethodPost, baseURL+"/bind/abc", strings.NewReader(`{"name":"tom"}`), map[string]string{
+			"Content-Type": "application/json",
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		resp, body := do(t, client, http.MethodGet, baseURL+"/error/notfound", nil, nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, host.CType_ProblemJson, resp.Header.Get("Content-Type"))
+		var p host.Problem
+		require.NoError(t, json.Unmarshal([]byte(body), &p))
+		assert.Equal(t, http.StatusNotFound, p.Status)
+		assert.Equal(t, "Not Found", p.Title)
+		assert.Equal(t, "order 3: not found", p.Detail)
+		assert.Equal(t, "/error/notfound", p.Instance)
+		assert.NotEmpty(t, p.ErrorID)
+
+		resp, body = do(t, client, http.MethodGet, baseURL+"/error/internal", nil, nil)
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		p = host.Problem{}
+		require.NoError(t, json.Unmarshal([]byte(body), &p))
+		assert.Equal(t, "internal server error", p.Detail)
+		assert.NotEmpty(t, p.ErrorID)
+		assert.Equal(t, resp.Header.Get(host.Header_RequestID), p.ErrorID)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		// 等待context的Handler、忽略context的Handler、出站请求超时均返回504
+		for _, path := range []string{"/timeout/wait", "/timeout/slow", "/timeout/outbound"} {
+			resp, body := do(t, client, http.MethodGet, baseURL+path, nil, nil)
+			assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode, path)
+			assert.Equal(t, host.CType_ProblemJson, resp.Header.Get("Content-Type"), path)
+			var p host.Problem
+			require.NoError(t, json.Unmarshal([]byte(body), &p), path)
+			assert.Equal(t, "request timed out", p.Detail, path)
+		}
+
+		resp, body := do(t, client, http.MethodGet, baseURL+"/timeout/fast", nil, nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "deadline", body)
+	})
+
+	t.Run("ClientDisconnect", func(t *testing.T) {
+		conn, err := net.Dial("tcp", s.addr)
+		require.NoError(t, err)
+		_, err = io.WriteString(conn, "GET /disconnect HTTP/1.1\r\nHost: "+s.addr+"\r\n\r\n")
+		require.NoError(t, err)
+		<-disconnectStarted
+		require.NoError(t, conn.Close())
+		assert.ErrorIs(t, <-disconnectErr, context.Canceled)
+	})
+
+	t.Run("RequestID", func(t *testing.T) {
+		resp, body := do(t, client, http.MethodGet, baseURL+"/requestid", nil, map[string]string{host.Header_RequestID: "req-1"})
+		assert.Equal(t, "req-1", resp.Header.Get(host.Header_RequestID))
+		assert.Equal(t, "req-1", body)
+
+		resp, body = do(t, client, http.MethodGet, baseURL+"/requestid", nil, map[string]string{host.Header_RequestID: "bad id!"})
+		generated := resp.Header.Get(host.Header_RequestID)
+		assert.NotEmpty(t, generated)
+		assert.NotEqual(t, "bad id!", generated)
+		assert.Equal(t, generated, body)
+
+		resp, _ = do(t, client, http.MethodGet, baseURL+"/requestid", nil, nil)
+		assert.NotEmpty(t, resp.Header.Get(host.Header_RequestID))
+		assert.NotEqual(t, generated, resp.Header.Get(host.Header_RequestID))
+	})
+
+	t.Run("SSE", func(t *testing.T) {
+		resp, body := do(t, client, http.MethodGet, baseURL+"/sse", nil, map[string]string{host.Header_LastEventID: "41"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, host.CType_EventStream, resp.Header.Get("Content-Type"))
+		assert.Equal(t, "retry: 3000\n\nid: 42\nevent: progress\ndata: a\ndata: b\n\ndata: done\n\n", body)
+	})
+
+	t.Run("SSEDisconnect", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/sse/endless", nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "data: tick\n", line)
+		cancel()
+		resp.Body.Close()
+
+		select {
+		case <-sseDone:
+		case <-time.After(5 * time.Second):
+			t.Fatal("SSE handler did not detect client disconnect")
+		}
+	})
+
+	t.Run("WebSocket", func(t *testing.T) {
+		wsURL := "ws://" + s.addr + "/ws/echo"
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"X-User": {"u1"}})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("hello")))
+		mt, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, websocket.TextMessage, mt)
+		assert.Equal(t, "pre|u1|/ws/echo|hello", string(data))
+
+		require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte{1, 2}))
+		mt, data, err = conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, websocket.BinaryMessage, mt)
+		assert.Equal(t, []byte{1, 2}, data)
+
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("bye")))
+		_, _, err = conn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation))
+		conn.Close()
+
+		_, resp, err = websocket.DefaultDialer.Dial(wsURL, nil)
+		assert.Error(t, err)
+		if assert.NotNil(t, resp) {
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		}
+	})
+
+	t.Run("Compression", func(t *testing.T) { testCompression(t, factory) })
+	t.Run("Static", func(t *testing.T) { testStatic(t, factory) })
+	t.Run("RateLimit", func(t *testing.T) { testRateLimit(t, factory) })
+	t.Run("GlobalRateLimit", func(t *testing.T) { testGlobalRateLimit(t, factory) })
+	t.Run("Metrics", func(t *testing.T) { testMetrics(t, factory) })
+	t.Run("MetricsServer", func(t *testing.T) { testMetricsServer(t, factory) })
+	t.Run("Tracing", func(t *testing.T) { testTracing(t, factory) })
+	t.Run("Health", func(t *testing.T) { testHealth(t, factory) })
+	t.Run("CORS", func(t *testing.T) { testCORS(t, factory) })
+	t.Run("SecurityHeaders", func(t *testing.T) { testSecurityHeaders(t, factory) })
+	t.Run("CSRF", func(t *testing.T) { testCSRF(t, factory) })
+	t.Run("AccessLog", func(t *testing.T) { testAccessLog(t, factory) })
+	t.Run("Routes", func(t *testing.T) { testRoutes(t, factory) })
+	t.Run("OpenAPI", func(t *testing.T) { testOpenAPI(t, factory) })
+	t.Run("RouteConfig", func(t *testing.T) { testRouteConfig(t, factory) })
+
+	t.Run("Shutdown", func(t *testing.T) {
+		s.shutdown(t)
+	})
+}
+
+// start 创建并运行宿主，configure启用要测试的可选功能，register注册路由
+func start(t *testing.T, factory HostFactory, configure func(*host.BaseWebHost), register func(h host.IWebHost)) *server {
+	addr := freeAddr(t)
+	h := factory(addr, configure)
+	register(h)
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- h.RunContext(context.Background())
+	}()
+	waitListening(t, addr)
+
+	jar, _ := cookiejar.New(nil)
+	return &server{
+		host:    h,
+		addr:    addr,
+		baseURL: "http://" + addr,
+		client: &http.Client{
+			Jar: jar,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		runErr: runErr,
+	}
+}
+
+// shutdown 关闭宿主并等待RunContext返回
+func (x *server) shutdown(t *testing.T) {
+	assert.NoError(t, x.host.Shutdown(5*time.Second))
+	select {
+	case err := <-x.runErr:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunContext did not return after Shutdown")
+	}
+}
+
+// register 注册基础功能测试使用的路由
+func register(h host.IWebHost, sseDone chan struct{}) {
+	registerRoot(h)
+	registerCommon(h)
+	registerParams(h)
+	registerBind(h)
+	registerAPI(h)
+
+	h.GET("/digits/{n:[0-9]+}", func(ctx host.IHttpContext) {
+		ctx.WriteString(ctx.GetParamString("n"))
+	})
+	h.GET("/optional/{name?}", func(ctx host.IHttpContext) {
+		ctx.WriteString("opt:" + ctx.GetParamString("name"))
+	})
+	h.GET("/query", func(ctx host.IHttpContext) {
+		var q testForm
+		if err := ctx.ReadQuery(&q); err != nil {
+			ctx.SetStatusCode(http.StatusBadRequest)
+			return
+		}
+		ctx.WriteString(q.Name + ":" + strconv.Itoa(q.Age) + ":" + ctx.GetFormString("name"))
+	})
+	h.POST("/form", func(ctx host.IHttpContext) {
+		var f testForm
+		if err := ctx.ReadForm(&f); err != nil {
+			ctx.SetStatusCode(http.StatusBadRequest)
+			return
+		}
+		ctx.WriteString(f.Name + ":" + strconv.Itoa(f.Age) + ":" + ctx.GetFormString("name"))
+	})
+	h.POST("/json", func(ctx host.IHttpContext) {
+		var j testJson
+		if err := ctx.ReadJSON(&j); err != nil {
+			ctx.SetStatusCode(http.StatusBadRequest)
+			return
+		}
+		j.Age++
+		data, _ := json.Marshal(j)
+		ctx.WriteJsonBytes(data)
+	})
+	h.GET("/status", func(ctx host.IHttpContext) {
+		ctx.WriteString("bad")
+		ctx.SetStatusCode(http.StatusBadRequest)
+	})
+	h.GET("/cookie/set", func(ctx host.IHttpContext) {
+		ctx.SetCookieKV("c1", "v1", func(c *http.Cookie) {
+			c.Path = "/"
+		})
+	})
+	h.GET("/cookie/get", func(ctx host.IHttpContext) {
+		ctx.WriteString(ctx.GetCookieString("c1"))
+	})
+	h.GET("/cookie/remove", func(ctx host.IHttpContext) {
+		ctx.RemoveCookie("c1", func(c *http.Cookie) {
+			c.Path = "/"
+		})
+	})
+	h.GET("/session/set", func(ctx host.IHttpContext) {
+		ctx.SetSession("s", "s1")
+	})
+	h.GET("/session/get", func(ctx host.IHttpContext) {
+		ctx.WriteString(ctx.GetSessionString("s"))
+	})
+	h.GET("/session/end", func(ctx host.IHttpContext) {
+		ctx.EndSession()
+	})
+	h.GET("/redirect", func(ctx host.IHttpContext) {
+		ctx.Redirect("/target", http.StatusFound)
+	})
+
+	h.GET("/sse", func(ctx host.IHttpContext) {
+		ctx.SSE(func(stream host.IEventStream) {
+			id, _ := strconv.Atoi(stream.LastEventID())
+			stream.Send("progress", strconv.Itoa(id+1), "a\nb")
+			stream.Send("", "", "done")
+		}, func(o *host.SSEOptions) {
+			o.Retry = 3 * time.Second
+		})
+	})
+	h.GET("/sse/endless", func(ctx host.IHttpContext) {
+		ctx.SSE(func(stream host.IEventStream) {
+			defer func() { sseDone <- struct{}{} }()
+			stream.Send("", "", "tick")
+			<-stream.Done()
+		}, func(o *host.SSEOptions) {
+			o.HeartbeatInterval = 20 * time.Millisecond
+		})
+	})
+
+	h.WS("/ws/echo", func(conn host.IWebSocketConn) {
+		for {
+			mt, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			switch {
+			case string(data) == "bye":
+				conn.Close(host.WSClose_PolicyViolation, "bye")
+				return
+			case mt == host.WSMessage_Text:
+				conn.WriteText(conn.GetItemString("trace") + "|" + conn.GetItemString(host.Ctx_UserID) + "|" + conn.GetRouteKey() + "|" + string(data))
+			default:
+				conn.WriteBinary(data)
+			}
+		}
+	}, func(ctx host.IHttpContext) {
+		userID := ctx.GetHeader("X-User")
+		if userID == "" {
+			ctx.Error(host.ErrUnauthorized)
+			return
+		}
+		ctx.SetItem(host.Ctx_UserID, userID)
+		ctx.Next()
+	})
+
+	waiting := host.NewAction("GET/timeout/wait", "timeout_wait", func(ctx host.IHttpContext) {
+		<-ctx.Context().Done()
+		ctx.Error(ctx.Context().Err())
+	})
+	slow := host.NewAction("GET/timeout/slow", "timeout_slow", func(ctx host.IHttpContext) {
+		time.Sleep(100 * time.Millisecond)
+		ctx.WriteString("late")
+	})
+	outbound := host.NewAction("GET/timeout/outbound", "timeout_outbound", func(ctx host.IHttpContext) {
+		client := &http.Client{Transport: host.NewTracingTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}), ctx.Context())}
+		if _, err := client.Get("http://downstream.test/"); err != nil {
+			ctx.Error(err)
+		}
+	})
+	fast := host.NewAction("GET/timeout/fast", "timeout_fast", func(ctx host.IHttpContext) {
+		if _, ok := ctx.Context().Deadline(); ok {
+			ctx.WriteString("deadline")
+		}
+	})
+	waiting.TimeoutMs, slow.TimeoutMs, outbound.TimeoutMs, fast.TimeoutMs = 50, 50, 50, 5000
+	h.AddActions(waiting, slow, outbound, fast)
+
+	h.GET("/requestid", func(ctx host.IHttpContext) {
+		ctx.WriteString(host.GetRequestID(ctx))
+	})
+}
+
+// registerRoot 注册全局前置、后置Handler和根路由
+func registerRoot(h host.IWebHost) {
+	h.AddGlobalPreHandlers(true, func(ctx host.IHttpContext) {
+		ctx.SetItem("trace", "pre")
+		ctx.Next()
+	})
+	h.AppendGlobalSufHandlers(true, func(ctx host.IHttpContext) {
+		ctx.WriteString("|suf")
+	})
+
+	h.GET("/", func(ctx host.IHttpContext) {
+		ctx.WriteString(ctx.GetItemString("trace") + "|" + ctx.GetRouteKey())
+		ctx.Next()
+	})
+	h.AddAction("GET/action", "root_test_action", func(ctx host.IHttpContext) {
+		ctx.WriteString(ctx.GetItemString("trace") + "|" + ctx.GetItemString(host.Ctx_RouteKey))
+		ctx.Next()
+	})
+}
+
+// registerCommon 注册多个功能测试共用的响应头和错误路由
+func registerCommon(h host.IWebHost) {
+	h.GET("/header", func(ctx host.IHttpContext) {
+		ctx.SetHeader("X-Out", "world")
+		ctx.WriteString(ctx.GetHeader("X-In"))
+	})
+	h.GET("/error/notfound", func(ctx host.IHttpContext) {
+		ctx.Error(fmt.Errorf("order 3: %w", host.ErrNotFound))
+	})
+	h.GET("/error/internal", func(ctx host.IHttpContext) {
+		ctx.Error(errors.New("db password is wrong"))
+	})
+}
+
+func registerParams(h host.IWebHost) {
+	h.GET("/users/{id}", func(ctx host.IHttpContext) {
+		ctx.WriteString(ctx.GetParamString("id"))
+	})
+}
+
+func registerBind(h host.IWebHost) {
+	h.POST("/bind/{id}", host.BindErrorHandler, func(ctx host.IHttpContext) {
+		var b testBind
+		if err := ctx.Bind(&b); err != nil {
+			return
+		}
+		ctx.WriteString(strconv.Itoa(b.ID) + ":" + b.Lang + ":" + b.Name + ":" + b.Email)
+	})
+}
+
+// registerAPI 注册嵌套的路由组和ActionGroup
+func registerAPI(h host.IWebHost) {
+	api := h.Group("/api/v1/", func(ctx host.IHttpContext) {
+		ctx.SetItem("trace", ctx.GetItemString("trace")+"|api")
+		ctx.Next()
+	})
+	api.GET("/orders/{id}", writeTrace)
+	api.Group("admin", func(ctx host.IHttpContext) {
+		ctx.SetItem("trace", ctx.GetItemString("trace")+"|admin")
+		ctx.Next()
+	}).GET("/users", writeTrace)
+	api.AddActionGroups(host.NewPrefixedActionGroup("/reports", []host.RequestHandler{func(ctx host.IHttpContext) {
+		ctx.SetItem("trace", ctx.GetItemString("trace")+"|report")
+		ctx.Next()
+	}}, []*host.Action{
+		host.NewAction("GET/daily", "daily_report", writeTrace),
+	}))
+}
+
+// writeTrace 输出经过的中间件、RouteKey和id参数
+func writeTrace(ctx host.IHttpContext) {
+	ctx.WriteString(ctx.GetItemString("trace") + "|" + ctx.GetRouteKey())
+	if id := ctx.GetParamString("id"); id != "" {
+		ctx.WriteString("|" + id)
+	}
+	ctx.Next()
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func freeAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func waitListening(t *testing.T, addr string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("host is not listening on %s", addr)
+}
+
+func do(t *testing.T, client *http.Client, method, url string, body io.Reader, headers map[string]string) (*http.Response, string) {
+	req, err := http.NewRequest(method, url, body)
+	require.NoError(t, err)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(data)
+}
+
+func decompress(t *testing.T, encoding, body string) string {
+	var r io.Reader
+	var err error
+	switch encoding {
+	case host.Encoding_Gzip:
+		r, err = gzip.NewReader(strings.NewReader(body))
+	case host.Encoding_Deflate:
+		r, err = zlib.NewReader(strings.NewReader(body))
+	case host.Encoding_Brotli:
+		r = brotli.NewReader(strings.NewReader(body))
+	case host.Encoding_Zstd:
+		r, err = zstd.NewReader(strings.NewReader(body))
+	default:
+		return body
+	}
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(data)
+}
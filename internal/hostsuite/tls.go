@@ -0,0 +1,185 @@
+package hostsuite
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syncfuture/host"
+)
+
+// TLSHostFactory 使用指定监听地址和TLS配置创建宿主
+type TLSHostFactory func(listenAddr string, options *host.TLSOptions) host.IWebHost
+
+// RunTLS 运行TLS、双向TLS和证书重新加载的测试
+func RunTLS(t *testing.T, factory TLSHostFactory) {
+	dir := t.TempDir()
+	ca, caKey := newTestCA(t, "suite-ca")
+	otherCA, otherCAKey := newTestCA(t, "other-ca")
+	writePEM(t, filepath.Join(dir, "ca.pem"), "CERTIFICATE", ca.Raw)
+	writeTestCert(t, dir, "server", "server-1", ca, caKey, x509.ExtKeyUsageServerAuth)
+	writeTestCert(t, dir, "client", "suite-client", ca, caKey, x509.ExtKeyUsageClientAuth)
+	writeTestCert(t, dir, "other", "other-client", otherCA, otherCAKey, x509.ExtKeyUsageClientAuth)
+
+	options := &host.TLSOptions{
+		CertFile:              filepath.Join(dir, "server.pem"),
+		KeyFile:               filepath.Join(dir, "server.key"),
+		ClientCAFile:          filepath.Join(dir, "ca.pem"),
+		ClientAuth:            host.TLSClientAuth_VerifyIfGiven,
+		ReloadIntervalSeconds: 1,
+	}
+
+	addr := freeAddr(t)
+	h := factory(addr, options)
+	h.GET("/peer", func(ctx host.IHttpContext) {
+		cert := ctx.GetPeerCertificate()
+		if cert == nil {
+			ctx.WriteString("none")
+			return
+		}
+		ctx.WriteString(cert.Subject.CommonName)
+	})
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- h.RunContext(context.Background())
+	}()
+	waitListening(t, addr)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	newClient := func(certName string) *http.Client {
+		config := &tls.Config{RootCAs: roots}
+		if certName != "" {
+			cert, err := tls.LoadX509KeyPair(filepath.Join(dir, certName+".pem"), filepath.Join(dir, certName+".key"))
+			require.NoError(t, err)
+			// 总是发送证书，否则客户端不发送不是由服务端所列CA签发的证书
+			config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return &cert, nil
+			}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: config, DisableKeepAlives: true}}
+	}
+	baseURL := "https://" + addr
+
+	t.Run("WithoutClientCertificate", func(t *testing.T) {
+		resp, body := do(t, newClient(""), http.MethodGet, baseURL+"/peer", nil, nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "none", body)
+		assert.Equal(t, "server-1", resp.TLS.PeerCertificates[0].Subject.CommonName)
+	})
+
+	t.Run("ClientCertificate", func(t *testing.T) {
+		_, body := do(t, newClient("client"), http.MethodGet, baseURL+"/peer", nil, nil)
+		assert.Equal(t, "suite-client", body)
+	})
+
+	t.Run("UntrustedClientCertificate", func(t *testing.T) {
+		resp, err := newClient("other").Get(baseURL + "/peer")
+		if err == nil {
+			// TLS 1.3中客户端证书在握手后验证，错误在读取响应时出现
+			_, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		assert.Error(t, err)
+	})
+
+	t.Run("Reload", func(t *testing.T) {
+		writeTestCert(t, dir, "server", "server-2", ca, caKey, x509.ExtKeyUsageServerAuth)
+
+		deadline := time.Now().Add(5 * time.Second)
+		var name string
+		for time.Now().Before(deadline) {
+			resp, err := newClient("").Get(baseURL + "/peer")
+			require.NoError(t, err)
+			resp.Body.Close()
+			name = resp.TLS.PeerCertificates[0].Subject.CommonName
+			if name == "server-2" {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		assert.Equal(t, "server-2", name)
+	})
+
+	t.Run("InvalidOptions", func(t *testing.T) {
+		_, err := host.NewTLSReloader(&host.TLSOptions{CertFile: options.CertFile, KeyFile: options.KeyFile, MinVersion: "1.1"})
+		assert.Error(t, err)
+		_, err = host.NewTLSReloader(&host.TLSOptions{CertFile: options.CertFile, KeyFile: options.KeyFile, CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}})
+		assert.Error(t, err)
+		_, err = host.NewTLSReloader(&host.TLSOptions{CertFile: options.CertFile, KeyFile: options.KeyFile, ClientAuth: host.TLSClientAuth_RequireAndVerify})
+		assert.Error(t, err)
+	})
+
+	t.Run("Shutdown", func(t *testing.T) {
+		assert.NoError(t, h.Shutdown(5*time.Second))
+		select {
+		case err := <-runErr:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("RunContext did not return after Shutdown")
+		}
+	})
+}
+
+func newTestCA(t *testing.T, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+// writeTestCert 写入由ca签发的证书name.pem和私钥name.key
+func writeTestCert(t *testing.T, dir, name, commonName string, ca *x509.Certificate, caKey *ecdsa.PrivateKey, usage x509.ExtKeyUsage) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	// 先写私钥，重新加载时证书与私钥不匹配会保留旧配置并在下次检查时重试
+	writePEM(t, filepath.Join(dir, name+".key"), "PRIVATE KEY", keyDER)
+	writePEM(t, filepath.Join(dir, name+".pem"), "CERTIFICATE", der)
+}
+
+func writePEM(t *testing.T, file, blockType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(file, data, 0600))
+}
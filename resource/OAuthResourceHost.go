@@ -74,16 +74,15 @@ func (x *OAuthResourceHost) BuildOAuthResourceHost() {
 func (x *OAuthResourceHost) AuthHandler(ctx host.IHttpContext) {
 	authHeader := ctx.GetHeader(shttp.HEADER_AUTH)
 	if authHeader == "" {
-		ctx.SetStatusCode(http.StatusUnauthorized)
-		ctx.WriteString("Authorization header is missing")
+		ctx.Error(host.NewProblem(http.StatusUnauthorized, "Authorization header is missing"))
 		return
 	}
 
 	// verify authorization header
 	array := strings.Split(authHeader, " ")
 	if len(array) != 2 || array[0] != host.AuthType_Bearer {
-		ctx.SetStatusCode(http.StatusBadRequest)
-		slog.Warnf("'%s'invalid authorization header format. '%s'", ctx.GetRemoteIP(), authHeader)
+		ctx.Error(host.NewProblem(http.StatusBadRequest, "invalid authorization header format"))
+		host.GetLogger(ctx).Warnf("'%s'invalid authorization header format. '%s'", ctx.GetRemoteIP(), authHeader)
 		return
 	}
 	token := array[1]
@@ -91,38 +90,35 @@ func (x *OAuthResourceHost) AuthHandler(ctx host.IHttpContext) {
 	// verify signature
 	jwtClaims, err := jwt.RSACheck(u.StrToBytes(token), x.PublicKey)
 	if err != nil {
-		ctx.SetStatusCode(http.StatusUnauthorized)
-		slog.Warn("'"+ctx.GetRemoteIP()+"'", err)
+		ctx.Error(host.NewProblem(http.StatusUnauthorized, "invalid token signature"))
+		host.GetLogger(ctx).Warn("'"+ctx.GetRemoteIP()+"'", err)
 		return
 	}
 
 	// validate time limits
 	isNotExpired := jwtClaims.Valid(time.Now().UTC())
 	if !isNotExpired {
-		ctx.SetStatusCode(http.StatusUnauthorized)
 		msgCode := "current time not in token's valid period"
-		ctx.WriteString(msgCode)
-		slog.Warnf("%s. Remote IP:[%s]", msgCode, ctx.GetRemoteIP())
+		ctx.Error(host.NewProblem(http.StatusUnauthorized, msgCode))
+		host.GetLogger(ctx).Warnf("%s. Remote IP:[%s]", msgCode, ctx.GetRemoteIP())
 		return
 	}
 
 	// validate aud
 	isValidAudience := x.OAuthOptions.ValidAudiences != nil && sslice.HasAnyStr(x.OAuthOptions.ValidAudiences, jwtClaims.Audiences)
 	if !isValidAudience {
-		ctx.SetStatusCode(http.StatusUnauthorized)
 		msgCode := "invalid audience"
-		ctx.WriteString(msgCode)
-		slog.Warnf("%s. Required: %v, has: %v, IP:[%s]", msgCode, x.OAuthOptions.ValidAudiences, jwtClaims.Audiences, ctx.GetRemoteIP())
+		ctx.Error(host.NewProblem(http.StatusUnauthorized, msgCode))
+		host.GetLogger(ctx).Warnf("%s. Required: %v, has: %v, IP:[%s]", msgCode, x.OAuthOptions.ValidAudiences, jwtClaims.Audiences, ctx.GetRemoteIP())
 		return
 	}
 
 	// validate iss
 	isValidIssuer := x.OAuthOptions.ValidIssuers != nil && sslice.HasStr(x.OAuthOptions.ValidIssuers, jwtClaims.Issuer)
 	if !isValidIssuer {
-		ctx.SetStatusCode(http.StatusUnauthorized)
 		msgCode := "invalid issuer"
-		ctx.WriteString(msgCode)
-		slog.Warnf("%s. Required: %v, has: %v, IP:[%s]", msgCode, x.OAuthOptions.ValidIssuers, jwtClaims.Issuer, ctx.GetRemoteIP())
+		ctx.Error(host.NewProblem(http.StatusUnauthorized, msgCode))
+		host.GetLogger(ctx).Warnf("%s. Required: %v, has: %v, IP:[%s]", msgCode, x.OAuthOptions.ValidIssuers, jwtClaims.Issuer, ctx.GetRemoteIP())
 		return
 	}
 
@@ -174,6 +170,5 @@ func (x *OAuthResourceHost) AuthHandler(ctx host.IHttpContext) {
 	}
 
 	// Not allow
-	ctx.SetStatusCode(http.StatusUnauthorized)
-	ctx.WriteString(msgCode)
+	ctx.Error(host.NewProblem(http.StatusUnauthorized, msgCode))
 }
@@ -15,6 +15,8 @@ func RegisterServiceInfo(cp sconfig.IConfigProvider) {
 	serviceName := cp.GetString("Consul.Service.Name")
 	serviceCheckTimeout := cp.GetString("Consul.Service.Check.Timeout")
 	serviceCheckInterval := cp.GetString("Consul.Service.Check.Interval")
+	serviceCheckHTTP := cp.GetString("Consul.Service.Check.HTTP") // 就绪检查路径，如/readyz
+	serviceCheckGRPC := cp.GetBool("Consul.Service.Check.GRPC")   // 使用gRPC健康检查服务
 	serviceHost := cp.GetString("Consul.Service.Host")
 	servicePort := cp.GetInt("Consul.Service.Port")
 	serviceID := fmt.Sprintf("%v:%v", serviceHost, servicePort)
@@ -27,6 +29,20 @@ func RegisterServiceInfo(cp sconfig.IConfigProvider) {
 	u.LogFatal(err)
 	consulAgent := consulClient.Agent()
 
+	// 健康检查，默认TCP
+	check := &api.AgentServiceCheck{
+		Interval:                       serviceCheckInterval, // 健康检查间隔
+		DeregisterCriticalServiceAfter: serviceCheckTimeout,  // 注销时间，相当于过期时间
+	}
+	switch {
+	case serviceCheckHTTP != "":
+		check.HTTP = fmt.Sprintf("http://%s:%d%s", serviceHost, servicePort, serviceCheckHTTP)
+	case serviceCheckGRPC:
+		check.GRPC = fmt.Sprintf("%s:%d", serviceHost, servicePort)
+	default:
+		check.TCP = fmt.Sprintf("%s:%d", serviceHost, servicePort)
+	}
+
 	// 在服务中心登记服务
 	err = consulAgent.ServiceRegister(&api.AgentServiceRegistration{
 		ID:   serviceID,   // 服务节点的名称
@@ -34,11 +50,7 @@ func RegisterServiceInfo(cp sconfig.IConfigProvider) {
 		// Tags:    r.Tag,                                        // tag，可以为空
 		Address: serviceHost, // 服务 IP
 		Port:    servicePort, // 服务端口
-		Check: &api.AgentServiceCheck{ // 健康检查
-			Interval:                       serviceCheckInterval, // 健康检查间隔
-			TCP:                            fmt.Sprintf("%s:%d", serviceHost, servicePort),
-			DeregisterCriticalServiceAfter: serviceCheckTimeout, // 注销时间，相当于过期时间
-		},
+		Check:   check,
 	})
 	u.LogFatal(err)
 }
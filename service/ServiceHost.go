@@ -17,6 +17,7 @@ type IServiceHost interface {
 
 type ServiceHost struct {
 	host.BaseHost
+	host.Lifecycle
 	ListenAddr string
 	Host       string
 	Port       int
@@ -1,7 +1,10 @@
 package sfasthttp
 
 import (
+	"net/http"
+
 	"github.com/syncfuture/go/sconfig"
+	"github.com/syncfuture/host"
 	"github.com/syncfuture/host/client"
 )
 
@@ -29,11 +32,15 @@ func NewFHOAuthClientHost(cp sconfig.IConfigProvider, options ...ClientHostOptio
 func (x *FHOAuthClientHost) BuildFHOAuthClientHost() {
 	x.BuildOAuthClientHost()
 	x.FHWebHost.CookieEncryptor = x.SecureCookieHost.GetCookieEncryptor()
+	x.FHWebHost.UseRedisConfig(x.RedisConfig)
+	x.FHWebHost.UseRouteProvider(x.RouteProvider)
 	x.FHWebHost.buildFHWebHost()
+	x.RegisterMiddleware(host.Middleware_Auth, x.AuthHandler)
+	x.AddHealthCheck("oauth", host.HttpHealthCheck(x.OAuthOptions.Endpoint.TokenURL))
 
 	////////// oauth client endpoints
-	x.Router.GET(x.SignInPath, x.FHWebHost.BuildNativeHandler(x.SignInPath, x.OAuthClientHandler.SignInHandler))
-	x.Router.GET(x.SignInCallbackPath, x.FHWebHost.BuildNativeHandler(x.SignInPath, x.OAuthClientHandler.SignInCallbackHandler))
-	x.Router.GET(x.SignOutPath, x.FHWebHost.BuildNativeHandler(x.SignInPath, x.OAuthClientHandler.SignOutHandler))
-	x.Router.GET(x.SignOutCallbackPath, x.FHWebHost.BuildNativeHandler(x.SignInPath, x.OAuthClientHandler.SignOutCallbackHandler))
+	x.addRoute(http.MethodGet, x.SignInPath, x.SignInPath, []host.RequestHandler{x.OAuthClientHandler.SignInHandler})
+	x.addRoute(http.MethodGet, x.SignInCallbackPath, x.SignInPath, []host.RequestHandler{x.OAuthClientHandler.SignInCallbackHandler})
+	x.addRoute(http.MethodGet, x.SignOutPath, x.SignInPath, []host.RequestHandler{x.OAuthClientHandler.SignOutHandler})
+	x.addRoute(http.MethodGet, x.SignOutCallbackPath, x.SignInPath, []host.RequestHandler{x.OAuthClientHandler.SignOutCallbackHandler})
 }
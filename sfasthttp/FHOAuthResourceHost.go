@@ -2,6 +2,7 @@ package sfasthttp
 
 import (
 	"github.com/syncfuture/go/sconfig"
+	"github.com/syncfuture/host"
 	"github.com/syncfuture/host/resource"
 )
 
@@ -31,5 +32,8 @@ func NewFHOAuthResourceHost(cp sconfig.IConfigProvider, options ...ResourceHostO
 
 func (x *FHOAuthResourceHost) BuildFHOAuthResourceHost() {
 	x.BuildOAuthResourceHost()
+	x.FHWebHost.UseRedisConfig(x.RedisConfig)
+	x.FHWebHost.UseRouteProvider(x.RouteProvider)
 	x.FHWebHost.buildFHWebHost()
+	x.RegisterMiddleware(host.Middleware_Auth, x.AuthHandler)
 }
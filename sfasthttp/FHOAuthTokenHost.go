@@ -1,6 +1,8 @@
 package sfasthttp
 
 import (
+	"net/http"
+
 	"github.com/syncfuture/go/sconfig"
 	"github.com/syncfuture/host/token"
 )
@@ -29,10 +31,12 @@ func NewFHOAuthTokenHost(cp sconfig.IConfigProvider, options ...TokenHostOption)
 func (x *FHOAuthTokenHost) BuildFHOAuthTokenHost() {
 	x.BuildOAuthTokenHost()
 	x.FHWebHost.CookieEncryptor = x.SecureCookieHost.GetCookieEncryptor()
+	x.FHWebHost.UseRedisConfig(x.RedisConfig)
+	x.FHWebHost.UseRouteProvider(x.RouteProvider)
 	x.FHWebHost.buildFHWebHost()
 
-	x.Router.POST(x.TokenEndpoint, x.TokenHost.TokenRequestHandler)
-	x.Router.GET(x.AuthorizeEndpoint, x.TokenHost.AuthorizeRequestHandler)
-	x.Router.GET(x.EndSessionEndpoint, x.TokenHost.EndSessionRequestHandler)
-	x.Router.POST(x.EndSessionEndpoint, x.TokenHost.ClearTokenRequestHandler)
+	x.handleNative(http.MethodPost, x.TokenEndpoint, "TokenRequestHandler", x.TokenHost.TokenRequestHandler)
+	x.handleNative(http.MethodGet, x.AuthorizeEndpoint, "AuthorizeRequestHandler", x.TokenHost.AuthorizeRequestHandler)
+	x.handleNative(http.MethodGet, x.EndSessionEndpoint, "EndSessionRequestHandler", x.TokenHost.EndSessionRequestHandler)
+	x.handleNative(http.MethodPost, x.EndSessionEndpoint, "ClearTokenRequestHandler", x.TokenHost.ClearTokenRequestHandler)
 }
@@ -1,22 +1,31 @@
 package sfasthttp
 
 import (
+	"context"
+	"crypto/tls"
 	"embed"
+	iofs "io/fs"
 	"mime"
 	"net/http"
+	"os"
 	fp "path/filepath"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/fasthttp/router"
 	"github.com/fasthttp/session/v2"
 	"github.com/fasthttp/session/v2/providers/memory"
+	"github.com/fasthttp/websocket"
 	"github.com/syncfuture/go/sconfig"
+	"github.com/syncfuture/go/serr"
 	"github.com/syncfuture/go/slog"
 	"github.com/syncfuture/go/ssecurity"
 	"github.com/syncfuture/go/u"
 	"github.com/syncfuture/host"
 	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
 )
 
 const (
@@ -42,7 +51,10 @@ type FHWebHost struct {
 	HttpHandler     host.RequestHandler
 	PanicHandler    host.RequestHandler
 	CookieEncryptor ssecurity.ICookieEncryptor
+	WebSocket       *host.WebSocketOptions
+	WSUpgrader      *websocket.FastHTTPUpgrader
 	fsHandler       fasthttp.RequestHandler
+	server          atomic.Pointer[fasthttp.Server]
 }
 
 func NewFHWebHost(cp sconfig.IConfigProvider, options ...WebHostOption) host.IWebHost {
@@ -59,6 +71,7 @@ func NewFHWebHost(cp sconfig.IConfigProvider, options ...WebHostOption) host.IWe
 }
 
 func (x *FHWebHost) buildFHWebHost() {
+	x.UseCookieEncryptor(x.CookieEncryptor)
 	x.BuildBaseWebHost()
 
 	if x.IndexName == "" {
@@ -75,15 +88,27 @@ func (x *FHWebHost) buildFHWebHost() {
 		x.Router.PanicHandler = func(ctx *fasthttp.RequestCtx, err interface{}) {
 			if x.PanicHandler != nil {
 				newCtx := NewFastHttpContext(ctx, x.SessionManager, x.CookieEncryptor)
+				defer newCtx.Reset() // 结束Context()的连接检查
 				newCtx.SetItem(host.Ctx_Panic, err)
 				x.PanicHandler(newCtx)
 				return
 			}
 			ctx.SetStatusCode(500)
-			slog.Errorf("%s -> %s", ctx.URI().String(), err)
+			slog.Errorf("[%v] %s -> %s", ctx.UserValue(host.Ctx_RequestID), ctx.URI().String(), err)
 		}
 	}
 
+	////////// 响应没有OPTIONS路由的预检请求，不影响用户注册的OPTIONS路由
+	// 在用户添加全局中间件之前构建，预检请求只经过宿主自身的中间件，不经过认证等用户中间件
+	if x.Router.GlobalOPTIONS == nil {
+		x.Router.GlobalOPTIONS = x.BuildNativeHandler(host.RouteKey_CORSPreflight, x.CORSPolicies.PreflightHandler)
+	}
+
+	////////// websocket upgrader
+	if x.WSUpgrader == nil {
+		x.WSUpgrader = x.newWSUpgrader()
+	}
+
 	////////// session provider
 	if x.SessionProvider == nil {
 		provider, err := memory.New(memory.Config{})
@@ -116,41 +141,53 @@ func (x *FHWebHost) buildFHWebHost() {
 		x.MaxRequestBodySize = fasthttp.DefaultMaxRequestBodySize
 	}
 
-	////////// CORS
-	if x.CORS != nil {
-		x.AddGlobalPreHandlers(true, func(ctx host.IHttpContext) {
-			if x.CORS.AllowedOrigin != "" {
-				ctx.SetHeader("Access-Control-Allow-Origin", x.CORS.AllowedOrigin)
-			}
-			ctx.Next()
-		})
+	////////// 指标，不经过全局中间件
+	if x.Metrics != nil && x.Metrics.ListenAddr == "" {
+		x.handleNative(http.MethodGet, x.Metrics.GetPath(), "metrics", fasthttpadaptor.NewFastHTTPHandler(host.NewMetricsHttpHandler()))
+	}
 
-		x.OPTIONS("/{filepath:*}", func(ctx host.IHttpContext) {
-			// if x.CORS.AllowedOrigin != "" {	// 上面的全局中间件已经添加
-			// 	ctx.SetHeader("Access-Control-Allow-Origin", x.CORS.AllowedOrigin)
-			// }
-			if x.CORS.AllowedMethods != "" {
-				ctx.SetHeader("Access-Control-Allow-Methods", x.CORS.AllowedMethods)
-			}
-			if x.CORS.AllowedHeaders != "" {
-				ctx.SetHeader("Access-Control-Allow-Headers", x.CORS.AllowedHeaders)
+	////////// 健康检查，不经过全局中间件
+	if x.HealthChecker != nil {
+		x.handleNative(http.MethodGet, x.Health.GetLivePath(), "health.live", fasthttpadaptor.NewFastHTTPHandler(x.HealthChecker.LiveHttpHandler()))
+		x.handleNative(http.MethodGet, x.Health.GetReadyPath(), "health.ready", fasthttpadaptor.NewFastHTTPHandler(x.HealthChecker.ReadyHttpHandler()))
+	}
+
+	////////// 路由列表
+	if x.RouteTable != nil {
+		x.GET(x.RouteTable.GetPath(), append(slices.Clone(x.RouteTable.Handlers), x.RouteTableHandler)...)
+	}
+
+	////////// OpenAPI文档
+	if x.OpenAPI != nil {
+		x.GET(x.OpenAPI.GetPath(), append(slices.Clone(x.OpenAPI.Handlers), x.OpenAPIHandler)...)
+		if x.OpenAPI.SwaggerUIPath != "" {
+			x.GET(x.OpenAPI.SwaggerUIPath, append(slices.Clone(x.OpenAPI.Handlers), x.SwaggerUIHandler)...)
+			if assetsPath := x.OpenAPI.GetSwaggerUIAssetsPath(); assetsPath != "" {
+				x.ServeStatic(assetsPath, host.SwaggerUIAssets())
 			}
-		})
+		}
 	}
 }
 
+// addRoute 注册经过全局中间件的路由并记录到路由表
+func (x *FHWebHost) addRoute(method, path, routeKey string, handlers []host.RequestHandler) {
+	x.RecordRoute(method, path, routeKey, handlers)
+	x.Router.Handle(method, path, x.BuildNativeHandler(routeKey, handlers...))
+}
+
+// handleNative 注册不经过全局中间件的原生路由并记录到路由表
+func (x *FHWebHost) handleNative(method, path, name string, handler fasthttp.RequestHandler) {
+	x.RecordNativeRoute(method, path, name)
+	x.Router.Handle(method, path, handler)
+}
+
 func (x *FHWebHost) BuildNativeHandler(routeKey string, handlers ...host.RequestHandler) fasthttp.RequestHandler {
 	if len(handlers) == 0 {
 		slog.Fatal("handlers are missing")
 	}
 
 	// 注册全局中间件
-	if len(x.GlobalPreHandlers) > 0 {
-		handlers = append(x.GlobalPreHandlers, handlers...)
-	}
-	if len(x.GlobalSufHandlers) > 0 {
-		handlers = append(handlers, x.GlobalSufHandlers...)
-	}
+	handlers = x.BuildHandlerChain(handlers)
 
 	return fasthttp.RequestHandler(func(ctx *fasthttp.RequestCtx) {
 		newCtx := NewFastHttpContext(ctx, x.SessionManager, x.CookieEncryptor, handlers...)
@@ -171,67 +208,153 @@ func (x *FHWebHost) NewFSHandler(root string, stripSlashes int) host.RequestHand
 	}
 }
 
+func (x *FHWebHost) Group(prefix string, handlers ...host.RequestHandler) host.IRouteGroup {
+	return host.NewRouteGroup(x, prefix, handlers...)
+}
+
 func (x *FHWebHost) GET(path string, handlers ...host.RequestHandler) {
-	x.Router.GET(path, x.BuildNativeHandler(path, handlers...))
+	x.addRoute(http.MethodGet, path, path, handlers)
 }
 func (x *FHWebHost) POST(path string, handlers ...host.RequestHandler) {
-	x.Router.POST(path, x.BuildNativeHandler(path, handlers...))
+	x.addRoute(http.MethodPost, path, path, handlers)
 }
 func (x *FHWebHost) PUT(path string, handlers ...host.RequestHandler) {
-	x.Router.PUT(path, x.BuildNativeHandler(path, handlers...))
+	x.addRoute(http.MethodPut, path, path, handlers)
 }
 func (x *FHWebHost) PATCH(path string, handlers ...host.RequestHandler) {
-	x.Router.PATCH(path, x.BuildNativeHandler(path, handlers...))
+	x.addRoute(http.MethodPatch, path, path, handlers)
 }
 func (x *FHWebHost) DELETE(path string, handlers ...host.RequestHandler) {
-	x.Router.DELETE(path, x.BuildNativeHandler(path, handlers...))
+	x.addRoute(http.MethodDelete, path, path, handlers)
 }
 func (x *FHWebHost) OPTIONS(path string, handlers ...host.RequestHandler) {
-	x.Router.OPTIONS(path, x.BuildNativeHandler(path, handlers...))
+	x.addRoute(http.MethodOptions, path, path, handlers)
 }
 
-func (x *FHWebHost) ServeFiles(webPath, physiblePath string) {
-	x.Router.ServeFiles(webPath, physiblePath)
+func (x *FHWebHost) WS(path string, handler host.WebSocketHandler, handlers ...host.RequestHandler) {
+	x.GET(path, append(handlers, func(ctx host.IHttpContext) {
+		c := ctx.(*FastHttpContext)
+		items := c.snapshotItems()
+		shutdown := c.ctx.Done()
+
+		err := x.WSUpgrader.Upgrade(c.ctx, func(conn *websocket.Conn) {
+			host.NewWebSocketConn(conn, items, shutdown).Serve(handler)
+		})
+		if err != nil {
+			// Upgrader已写入错误响应
+			host.GetLogger(ctx).Debugf("websocket upgrade failed: %s -> %v", ctx.RequestPath(), err)
+		}
+	})...)
 }
 
-func (x *FHWebHost) ServeEmbedFiles(webPath, physiblePath string, emd embed.FS) {
+func (x *FHWebHost) newWSUpgrader() *websocket.FastHTTPUpgrader {
+	r := &websocket.FastHTTPUpgrader{
+		HandshakeTimeout: x.WebSocket.GetHandshakeTimeout(),
+	}
+	if x.WebSocket != nil {
+		r.ReadBufferSize = x.WebSocket.ReadBufferSize
+		r.WriteBufferSize = x.WebSocket.WriteBufferSize
+		r.Subprotocols = x.WebSocket.Subprotocols
+		r.EnableCompression = x.WebSocket.EnableCompression
+		if len(x.WebSocket.AllowedOrigins) > 0 {
+			r.CheckOrigin = func(ctx *fasthttp.RequestCtx) bool {
+				allowed, _ := x.WebSocket.CheckOrigin(string(ctx.Request.Header.Peek("Origin")))
+				return allowed
+			}
+		}
+	}
+	return r
+}
+
+func (x *FHWebHost) ServeFiles(webPath, physiblePath string) {
+	if x.Compression == nil || !x.Compression.Precompressed {
+		x.RecordNativeRoute(http.MethodGet, webPath, "ServeFiles "+physiblePath)
+		x.Router.ServeFiles(webPath, physiblePath)
+		return
+	}
+
+	// 与Router.ServeFiles一致，先尝试预压缩文件
 	if !strings.HasSuffix(webPath, _suffix) {
 		panic("path must end with " + _suffix + " in path '" + webPath + "'")
 	}
+	prefix := webPath[:len(webPath)-len(_suffix)]
+	fs := &fasthttp.FS{
+		Root:               physiblePath,
+		IndexNames:         []string{x.IndexName},
+		GenerateIndexPages: true,
+		AcceptByteRange:    true,
+		PathRewrite:        fasthttp.NewPathSlashesStripper(strings.Count(prefix, "/")),
+	}
+	fileHandler := fs.NewRequestHandler()
+	fsys := os.DirFS(physiblePath)
 
-	x.Router.GET(webPath, func(ctx *fasthttp.RequestCtx) {
+	x.handleNative(http.MethodGet, webPath, "ServeFiles "+physiblePath, func(ctx *fasthttp.RequestCtx) {
 		filepath := ctx.UserValue(_filepath).(string)
-		if filepath == "" {
-			filepath = x.IndexName
+		if filepath == "" || strings.HasSuffix(filepath, "/") {
+			filepath += x.IndexName
 		}
+		if x.servePrecompressed(ctx, fsys, filepath) {
+			return
+		}
+		fileHandler(ctx)
+	})
+}
 
-		filepath = physiblePath + "/" + filepath
-
-		file, err := emd.Open(filepath) // embed file doesn't need to close
-		if err == nil {
-			ext := fp.Ext(filepath)
-			cType := mime.TypeByExtension(ext)
+// servePrecompressed 客户端支持时返回filepath.br/filepath.gz
+func (x *FHWebHost) servePrecompressed(ctx *fasthttp.RequestCtx, fsys iofs.FS, filepath string) bool {
+	ctx.Response.Header.Add(host.Header_Vary, host.Header_AcceptEncoding)
+	if !iofs.ValidPath(filepath) {
+		return false
+	}
 
-			if cType != "" {
-				ctx.SetContentType(cType)
-			}
-			ctx.Response.SetBodyStream(file, -1)
-			return
+	file, encoding, err := x.Compression.OpenStaticFile(fsys, filepath, string(ctx.Request.Header.Peek(host.Header_AcceptEncoding)))
+	if err != nil || encoding == "" {
+		if file != nil {
+			file.Close()
 		}
+		return false
+	}
 
-		ctx.SetStatusCode(404)
-		ctx.WriteString("NOT FOUND")
-	})
+	if cType := mime.TypeByExtension(fp.Ext(filepath)); cType != "" {
+		ctx.SetContentType(cType)
+	}
+	ctx.Response.Header.Set(host.Header_ContentEncoding, encoding)
+	ctx.Response.SetBodyStream(file, -1)
+	return true
+}
+
+func (x *FHWebHost) ServeEmbedFiles(webPath, physiblePath string, emd embed.FS) {
+	fsys, err := iofs.Sub(emd, physiblePath)
+	u.LogFatal(err)
+	x.serveStatic(webPath, fsys, "ServeEmbedFiles "+physiblePath)
+}
+
+func (x *FHWebHost) ServeStatic(webPath string, fsys iofs.FS) {
+	x.serveStatic(webPath, fsys, "ServeStatic")
+}
+
+// serveStatic 使用host.StaticFileServer查找文件，在RequestCtx上直接处理条件请求和Range，以流的形式发送文件
+func (x *FHWebHost) serveStatic(webPath string, fsys iofs.FS, name string) {
+	if !strings.HasSuffix(webPath, _suffix) {
+		panic("path must end with " + _suffix + " in path '" + webPath + "'")
+	}
+
+	handler := newStaticHandler(host.NewStaticFileServer(fsys, x.IndexName, x.Static, x.Compression))
+	x.handleNative(http.MethodGet, webPath, name, handler)
+	x.handleNative(http.MethodHead, webPath, name, handler)
 }
 
 func (x *FHWebHost) Run() error {
+	return x.RunContext(context.Background())
+}
+
+func (x *FHWebHost) RunContext(ctx context.Context) error {
 	////////// 注册Actions到路由
+	x.AddConfiguredActions()
 	for _, v := range x.Actions {
 		x.RegisterActionsToRouter(v)
 	}
-
-	////////// 开始Serve
-	slog.Infof("Listening on %s", x.ListenAddr)
+	x.CheckRouteKeys()
 
 	var handler fasthttp.RequestHandler
 	if x.HttpHandler == nil {
@@ -246,28 +369,54 @@ func (x *FHWebHost) Run() error {
 		ReadBufferSize:     x.ReadBufferSize, // 提高这个值，解决Http 431错误
 		MaxRequestBodySize: x.MaxRequestBodySize,
 		Logger:             slog.DebugLogger,
+		CloseOnShutdown:    true, // 关闭时通知keep-alive连接断开
+	}
+	x.server.Store(s)
+
+	////////// 开始Serve
+	tlsConfig, err := x.BuildTLSConfig()
+	if err != nil {
+		return err
+	}
+	ln, err := x.Listen("tcp4")
+	if err != nil {
+		return err
 	}
-	return s.ListenAndServe(x.ListenAddr)
+
+	////////// 指标服务，监听成功后再启动，失败时关闭已打开的监听
+	if err := x.StartMetricsServer(); err != nil {
+		ln.Close()
+		return err
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+		slog.Infof("Listening on %s (TLS)", x.ListenAddr)
+	} else {
+		slog.Infof("Listening on %s", x.ListenAddr)
+	}
+
+	return x.RunUntilSignal(ctx, func() error {
+		return serr.WithStack(s.Serve(ln))
+	}, x.Shutdown)
+}
+
+// Shutdown 停止接收新连接，等待处理中的请求完成（最多timeout），然后执行关闭钩子
+func (x *FHWebHost) Shutdown(timeout time.Duration) error {
+	return x.GracefulShutdown(timeout, func(ctx context.Context) error {
+		s := x.server.Load()
+		if s == nil {
+			return nil
+		}
+		return serr.WithStack(s.ShutdownWithContext(ctx))
+	})
 }
 
 func (x *FHWebHost) RegisterActionsToRouter(action *host.Action) {
-	index := strings.Index(action.Route, "/")
-	method := action.Route[:index]
-	path := action.Route[index:]
+	method, path := host.SplitRoute(action.Route)
 
 	switch method {
-	case http.MethodPost:
-		x.Router.POST(path, x.BuildNativeHandler(action.RouteKey, action.Handlers...))
-	case http.MethodGet:
-		x.Router.GET(path, x.BuildNativeHandler(action.RouteKey, action.Handlers...))
-	case http.MethodPut:
-		x.Router.PUT(path, x.BuildNativeHandler(action.RouteKey, action.Handlers...))
-	case http.MethodPatch:
-		x.Router.PATCH(path, x.BuildNativeHandler(action.RouteKey, action.Handlers...))
-	case http.MethodDelete:
-		x.Router.DELETE(path, x.BuildNativeHandler(action.RouteKey, action.Handlers...))
-	case http.MethodOptions:
-		x.Router.OPTIONS(path, x.BuildNativeHandler(action.RouteKey, action.Handlers...))
+	case http.MethodPost, http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		x.Router.Handle(method, path, x.BuildNativeHandler(action.RouteKey, action.Handlers...))
 	default:
 		panic("does not support method " + method)
 	}
@@ -1,17 +1,20 @@
 package sfasthttp
 
 import (
+	"bufio"
+	"context"
+	"crypto/x509"
 	"encoding/json"
 	"mime/multipart"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/fasthttp/session/v2"
 	"github.com/gorilla/schema"
 	"github.com/syncfuture/go/sconv"
 	"github.com/syncfuture/go/serr"
 	"github.com/syncfuture/go/shttp"
-	"github.com/syncfuture/go/slog"
 	"github.com/syncfuture/go/spool"
 	"github.com/syncfuture/go/ssecurity"
 	"github.com/syncfuture/go/u"
@@ -19,6 +22,9 @@ import (
 	"github.com/valyala/fasthttp"
 )
 
+// _connProbeInterval Context()检查客户端是否断开的间隔
+const _connProbeInterval = 200 * time.Millisecond
+
 var (
 	_ctxPool = &sync.Pool{
 		New: func() interface{} {
@@ -36,6 +42,8 @@ func init() {
 
 type FastHttpContext struct {
 	ctx             *fasthttp.RequestCtx
+	goctx           context.Context
+	cancel          context.CancelFunc
 	sess            *session.Session
 	sessStore       *session.Store
 	mapPool         *sync.Pool
@@ -74,6 +82,16 @@ func (x *FastHttpContext) Write(p []byte) (n int, err error) {
 func (x *FastHttpContext) SetItem(key string, value interface{}) {
 	x.ctx.SetUserValue(key, value)
 }
+
+// snapshotItems 复制Items，供ctx回收后使用
+func (x *FastHttpContext) snapshotItems() map[string]interface{} {
+	r := make(map[string]interface{})
+	x.ctx.VisitUserValues(func(key []byte, value interface{}) {
+		r[string(key)] = value
+	})
+	return r
+}
+
 func (x *FastHttpContext) GetItem(key string) interface{} {
 	return x.ctx.UserValue(key)
 }
@@ -98,6 +116,37 @@ func (x *FastHttpContext) GetRouteKey() string {
 	return x.GetItemString(host.Ctx_RouteKey)
 }
 
+// Context 服务关闭时取消，支持的平台上定期检查连接，客户端断开时也取消，请求结束时（Reset）取消
+func (x *FastHttpContext) Context() context.Context {
+	if x.goctx == nil {
+		probe := newConnProbe(x.ctx.Conn())
+		if probe == nil {
+			x.goctx = serverContext{Context: context.Background(), done: x.ctx.Done()}
+			return x.goctx
+		}
+
+		goctx, cancel := context.WithCancel(context.Background())
+		x.goctx, x.cancel = goctx, cancel
+		go watchConn(probe, x.ctx.Done(), goctx.Done(), cancel)
+	}
+	return x.goctx
+}
+func (x *FastHttpContext) SetContext(c context.Context) {
+	x.goctx = c
+}
+
+func (x *FastHttpContext) GetCSPNonce() string {
+	return x.GetItemString(host.Ctx_CSPNonce)
+}
+
+func (x *FastHttpContext) GetPeerCertificate() *x509.Certificate {
+	state := x.ctx.TLSConnectionState()
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	return state.PeerCertificates[0]
+}
+
 func (x *FastHttpContext) setCookie(cookie *http.Cookie) {
 	c := fasthttp.AcquireCookie()
 	defer func() {
@@ -136,11 +185,11 @@ func (x *FastHttpContext) GetCookieString(key string) string {
 
 func (x *FastHttpContext) SetEncryptedCookieKV(key, value string, options ...func(*http.Cookie)) {
 	if x.cookieEncryptor == nil {
-		slog.Warn("cookieEncryptor is nil, this context does not suppot cookie encryption")
+		host.GetLogger(x).Warn("cookieEncryptor is nil, this context does not suppot cookie encryption")
 		return
 	}
 	encryptedString, err := x.cookieEncryptor.Encrypt(key, value)
-	if u.LogError(err) {
+	if host.GetLogger(x).LogError(err) {
 		return
 	}
 
@@ -149,14 +198,14 @@ func (x *FastHttpContext) SetEncryptedCookieKV(key, value string, options ...fun
 
 func (x *FastHttpContext) GetEncryptedCookieString(key string) (r string) {
 	if x.cookieEncryptor == nil {
-		slog.Warn("cookieEncryptor is nil, this context does not suppot cookie encryption")
+		host.GetLogger(x).Warn("cookieEncryptor is nil, this context does not suppot cookie encryption")
 		return
 	}
 
 	encryptedString := x.GetCookieString(key)
 	if encryptedString != "" {
 		err := x.cookieEncryptor.Decrypt(key, encryptedString, &r)
-		u.LogError(err)
+		host.GetLogger(x).LogError(err)
 	}
 
 	return
@@ -185,21 +234,21 @@ func (x *FastHttpContext) RemoveCookie(key string, options ...func(*http.Cookie)
 
 func (x *FastHttpContext) SetSession(key, value string) {
 	store, err := x.sess.Get(x.ctx)
-	if u.LogError(err) {
+	if host.GetLogger(x).LogError(err) {
 		return
 	}
 	defer func() {
-		u.LogError(x.sess.Save(x.ctx, store))
+		host.GetLogger(x).LogError(x.sess.Save(x.ctx, store))
 	}()
 	store.Set(key, value)
 }
 func (x *FastHttpContext) GetSessionString(key string) string {
 	store, err := x.sess.Get(x.ctx)
-	if u.LogError(err) {
+	if host.GetLogger(x).LogError(err) {
 		return ""
 	}
 	defer func() {
-		u.LogError(x.sess.Save(x.ctx, store))
+		host.GetLogger(x).LogError(x.sess.Save(x.ctx, store))
 	}()
 
 	if r, ok := store.Get(key).(string); ok {
@@ -210,11 +259,11 @@ func (x *FastHttpContext) GetSessionString(key string) string {
 }
 func (x *FastHttpContext) RemoveSession(key string) {
 	store, err := x.sess.Get(x.ctx)
-	if u.LogError(err) {
+	if host.GetLogger(x).LogError(err) {
 		return
 	}
 	defer func() {
-		u.LogError(x.sess.Save(x.ctx, store))
+		host.GetLogger(x).LogError(x.sess.Save(x.ctx, store))
 	}()
 	store.Delete(key)
 }
@@ -315,6 +364,10 @@ func (x *FastHttpContext) ReadFormMap() (map[string][]string, error) {
 	return dic, nil
 }
 
+func (x *FastHttpContext) Bind(objPtr interface{}) error {
+	return host.Bind(x, objPtr)
+}
+
 func (x *FastHttpContext) SetHeader(key, value string) {
 	x.ctx.Response.Header.Set(key, value)
 }
@@ -326,6 +379,24 @@ func (x *FastHttpContext) GetHeader(key string) string {
 func (x *FastHttpContext) SetStatusCode(statusCode int) {
 	x.ctx.SetStatusCode(statusCode)
 }
+func (x *FastHttpContext) GetStatusCode() int {
+	return x.ctx.Response.StatusCode()
+}
+func (x *FastHttpContext) GetResponseHeader(key string) string {
+	return string(x.ctx.Response.Header.Peek(key))
+}
+func (x *FastHttpContext) GetResponseBody() []byte {
+	if x.IsBodyStream() {
+		return nil
+	}
+	return x.ctx.Response.Body()
+}
+func (x *FastHttpContext) SetResponseBody(body []byte) {
+	x.ctx.Response.SetBody(body)
+}
+func (x *FastHttpContext) IsBodyStream() bool {
+	return x.ctx.Response.IsBodyStream() || x.ctx.Hijacked()
+}
 func (x *FastHttpContext) SetContentType(cType string) {
 	x.ctx.SetContentType(cType)
 }
@@ -350,6 +421,15 @@ func (x *FastHttpContext) RequestURL() string {
 func (x *FastHttpContext) RequestPath() string {
 	return u.BytesToStr(x.ctx.URI().Path())
 }
+func (x *FastHttpContext) RequestMethod() string {
+	return string(x.ctx.Method())
+}
+func (x *FastHttpContext) RequestURI() string {
+	return string(x.ctx.RequestURI())
+}
+func (x *FastHttpContext) RequestProtocol() string {
+	return string(x.ctx.Request.Header.Protocol())
+}
 func (x *FastHttpContext) GetRemoteIP() string {
 	return x.ctx.RemoteIP().String()
 }
@@ -358,6 +438,22 @@ func (x *FastHttpContext) UserAgent() string {
 	return u.BytesToStr(x.ctx.UserAgent())
 }
 
+func (x *FastHttpContext) SSE(handler host.SSEHandler, options ...func(*host.SSEOptions)) {
+	opts := host.NewSSEOptions(options...)
+	lastEventID := string(x.ctx.Request.Header.Peek(host.Header_LastEventID))
+	shutdown := x.ctx.Done()
+
+	host.SetSSEHeaders(x)
+	x.ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		stream := host.NewEventStream(w, w.Flush, lastEventID)
+		stream.Serve(handler, opts, shutdown)
+	})
+}
+
+func (x *FastHttpContext) Error(err error) {
+	x.SetItem(host.Ctx_Error, err)
+}
+
 func (x *FastHttpContext) Redirect(url string, statusCode int) {
 	x.ctx.Redirect(url, statusCode)
 }
@@ -377,7 +473,12 @@ func (x *FastHttpContext) Next() {
 	}
 }
 func (x *FastHttpContext) Reset() {
+	if x.cancel != nil {
+		x.cancel()
+		x.cancel = nil
+	}
 	x.ctx = nil
+	x.goctx = nil
 	x.sess = nil
 	x.sessStore = nil
 	x.cookieEncryptor = nil
@@ -386,3 +487,41 @@ func (x *FastHttpContext) Reset() {
 	x.handlerCount = 0
 	x.handlerIndex = 0
 }
+
+// watchConn 每隔_connProbeInterval检查连接，客户端断开或服务关闭时调用cancel，done关闭（请求结束）后返回
+func watchConn(probe *connProbe, shutdown, done <-chan struct{}, cancel context.CancelFunc) {
+	ticker := time.NewTicker(_connProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-shutdown:
+			cancel()
+			return
+		case <-ticker.C:
+			if probe.closed() {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// serverContext 同RequestCtx.Done()在服务关闭时取消，不使用RequestCtx.Value，因为UserValue不是并发安全的
+type serverContext struct {
+	context.Context
+	done <-chan struct{}
+}
+
+func (x serverContext) Done() <-chan struct{} {
+	return x.done
+}
+func (x serverContext) Err() error {
+	select {
+	case <-x.done:
+		return context.Canceled
+	default:
+		return nil
+	}
+}
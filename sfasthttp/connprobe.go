@@ -0,0 +1,49 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package sfasthttp
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// connProbe 以MSG_PEEK检查客户端是否已关闭连接，不读取连接上的数据
+type connProbe struct {
+	raw syscall.RawConn
+	buf [1]byte
+}
+
+// newConnProbe 连接不是TCP或Unix socket（如内存中的连接）时返回nil
+func newConnProbe(conn net.Conn) *connProbe {
+	if c, ok := conn.(interface{ NetConn() net.Conn }); ok {
+		conn = c.NetConn() // *tls.Conn
+	}
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return nil
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return nil
+	}
+	return &connProbe{raw: raw}
+}
+
+// closed 读到EOF或连接出错时返回true，连接上有未读数据（如管线化的下一个请求）时返回false
+func (x *connProbe) closed() bool {
+	var closed bool
+	err := x.raw.Read(func(fd uintptr) bool {
+		// Go的socket是非阻塞的，没有数据时返回EAGAIN
+		n, _, err := syscall.Recvfrom(int(fd), x.buf[:], syscall.MSG_PEEK)
+		switch {
+		case err == nil:
+			closed = n == 0
+		case errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.EINTR):
+		default:
+			closed = true
+		}
+		return true // 不等待可读
+	})
+	return closed || err != nil
+}
@@ -0,0 +1,16 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package sfasthttp
+
+import "net"
+
+// connProbe 此平台不检查客户端断开，Context()只在服务关闭时取消
+type connProbe struct{}
+
+func newConnProbe(conn net.Conn) *connProbe {
+	return nil
+}
+
+func (x *connProbe) closed() bool {
+	return false
+}
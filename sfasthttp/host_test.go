@@ -1,15 +1,22 @@
 package sfasthttp
 
 import (
+	"bytes"
+	"net/http"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/Lukiya/oauth2go/model"
 	"github.com/fasthttp/session/v2/providers/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/syncfuture/go/sconfig"
 	"github.com/syncfuture/go/slog"
 	"github.com/syncfuture/go/u"
 	"github.com/syncfuture/host"
+	"github.com/syncfuture/host/internal/hostsuite"
+	"github.com/valyala/fasthttp"
 )
 
 func TestWebHost(t *testing.T) {
@@ -80,3 +87,110 @@ type testClaimsGenerator struct{}
 func (x *testClaimsGenerator) Generate(grantType string, client model.IClient, scopes []string, username string) *map[string]interface{} {
 	return &map[string]interface{}{}
 }
+
+func TestWebHostSuite(t *testing.T) {
+	hostsuite.Run(t, newSuiteHost)
+}
+
+func TestWebHostTLS(t *testing.T) {
+	hostsuite.RunTLS(t, func(listenAddr string, options *host.TLSOptions) host.IWebHost {
+		h := new(FHWebHost)
+		h.ListenAddr = listenAddr
+		h.TLS = options
+		h.buildFHWebHost()
+		return h
+	})
+}
+
+func TestWebHostCSRFDoubleSubmit(t *testing.T) {
+	hostsuite.RunCSRFDoubleSubmit(t, func(listenAddr string, options *host.CSRFOptions, secureCookie *host.SecureCookieHost) host.IWebHost {
+		h := new(FHWebHost)
+		h.ListenAddr = listenAddr
+		h.CSRF = options
+		h.CookieEncryptor = secureCookie.GetCookieEncryptor()
+		h.buildFHWebHost()
+		return h
+	})
+}
+
+func TestInMemoryHost(t *testing.T) {
+	hostsuite.RunInMemory(t, newSuiteHost)
+}
+
+func newSuiteHost(listenAddr string, configure func(*host.BaseWebHost)) host.IWebHost {
+	h := new(FHWebHost)
+	h.ListenAddr = listenAddr
+	if configure != nil {
+		configure(&h.BaseWebHost)
+	}
+	h.buildFHWebHost()
+	return h
+}
+
+func TestStaticHandler(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	handler := newStaticHandler(host.NewStaticFileServer(fstest.MapFS{"data": {Data: data, ModTime: modTime}}, "", nil, nil))
+	serve := func(headers ...string) *fasthttp.RequestCtx {
+		ctx := new(fasthttp.RequestCtx)
+		ctx.Request.SetRequestURI("/data")
+		for i := 0; i < len(headers); i += 2 {
+			ctx.Request.Header.Set(headers[i], headers[i+1])
+		}
+		ctx.SetUserValue(_filepath, "data")
+		handler(ctx)
+		return ctx
+	}
+
+	// 文件以流的形式发送，不读入内存
+	ctx := serve()
+	assert.Equal(t, http.StatusOK, ctx.Response.StatusCode())
+	assert.True(t, ctx.Response.IsBodyStream())
+	assert.Equal(t, len(data), ctx.Response.Header.ContentLength())
+	assert.Equal(t, "text/plain; charset=utf-8", string(ctx.Response.Header.ContentType()))
+	assert.Equal(t, "bytes", string(ctx.Response.Header.Peek("Accept-Ranges")))
+	assert.Equal(t, modTime.Format(http.TimeFormat), string(ctx.Response.Header.Peek("Last-Modified")))
+	assert.Equal(t, data, ctx.Response.Body())
+	etag := string(ctx.Response.Header.Peek("ETag"))
+	require.NotEmpty(t, etag)
+
+	ctx = serve("Range", "bytes=10-19")
+	assert.Equal(t, http.StatusPartialContent, ctx.Response.StatusCode())
+	assert.True(t, ctx.Response.IsBodyStream())
+	assert.Equal(t, "bytes 10-19/10000", string(ctx.Response.Header.Peek("Content-Range")))
+	assert.Equal(t, data[10:20], ctx.Response.Body())
+
+	ctx = serve("Range", "bytes=9990-20000", "If-Range", etag)
+	assert.Equal(t, http.StatusPartialContent, ctx.Response.StatusCode())
+	assert.Equal(t, data[9990:], ctx.Response.Body())
+	ctx = serve("Range", "bytes=0-1", "If-Range", modTime.Format(http.TimeFormat))
+	assert.Equal(t, http.StatusPartialContent, ctx.Response.StatusCode())
+
+	// 多个范围时返回整个文件
+	ctx = serve("Range", "bytes=0-1,5-6")
+	assert.Equal(t, http.StatusOK, ctx.Response.StatusCode())
+	assert.Equal(t, len(data), ctx.Response.Header.ContentLength())
+
+	// 条件请求
+	for _, c := range []struct {
+		headers []string
+		status  int
+	}{
+		{[]string{"If-None-Match", etag}, http.StatusNotModified},
+		{[]string{"If-None-Match", `"other", W/` + etag}, http.StatusNotModified},
+		{[]string{"If-None-Match", `"other"`}, http.StatusOK},
+		{[]string{"If-None-Match", `"other"`, "If-Modified-Since", modTime.Format(http.TimeFormat)}, http.StatusOK},
+		{[]string{"If-Modified-Since", modTime.Format(http.TimeFormat)}, http.StatusNotModified},
+		{[]string{"If-Modified-Since", modTime.Add(-time.Second).Format(http.TimeFormat)}, http.StatusOK},
+		{[]string{"If-Match", "*"}, http.StatusOK},
+		{[]string{"If-Match", "W/" + etag}, http.StatusPreconditionFailed},
+		{[]string{"If-Unmodified-Since", modTime.Add(-time.Second).Format(http.TimeFormat)}, http.StatusPreconditionFailed},
+		{[]string{"If-Unmodified-Since", modTime.Format(http.TimeFormat)}, http.StatusOK},
+	} {
+		ctx = serve(c.headers...)
+		assert.Equal(t, c.status, ctx.Response.StatusCode(), c.headers)
+		if c.status != http.StatusOK {
+			assert.Empty(t, ctx.Response.Body(), c.headers)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package sfasthttp
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/syncfuture/go/slog"
+	"github.com/syncfuture/host"
+	"github.com/valyala/fasthttp"
+)
+
+// _sniffLen 没有扩展名对应的Content-Type时用于检测类型的字节数，与http.DetectContentType一致
+const _sniffLen = 512
+
+// newStaticHandler 直接在RequestCtx上使用host.StaticFileServer，处理条件请求和单个Range，响应以流的形式发送，不缓冲整个文件
+// 多个Range时忽略Range返回整个文件
+func newStaticHandler(server *host.StaticFileServer) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		name, _ := ctx.UserValue(_filepath).(string)
+		file := server.Resolve(name, string(ctx.Request.Header.Peek(host.Header_AcceptEncoding)), bytes.Contains(ctx.Request.Header.Peek("Accept"), []byte("text/html")))
+		if file == nil {
+			ctx.SetStatusCode(http.StatusNotFound)
+			ctx.SetBodyString("NOT FOUND")
+			return
+		}
+
+		content, err := readerAt(file.File)
+		if err != nil {
+			file.File.Close()
+			ctx.SetStatusCode(http.StatusInternalServerError)
+			slog.Error(err)
+			return
+		}
+
+		etag := file.Header.Get("ETag")
+		modTime := file.Info.ModTime()
+		size := file.Info.Size()
+		header := &ctx.Response.Header
+		for key, values := range file.Header {
+			if key == "Content-Type" || key == host.Header_ContentEncoding {
+				continue // 304时不发送
+			}
+			for _, value := range values {
+				header.Add(key, value)
+			}
+		}
+		if !modTime.IsZero() {
+			header.Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+		}
+
+		status := checkPreconditions(&ctx.Request.Header, etag, modTime)
+		if status != http.StatusOK {
+			file.File.Close()
+			if status == http.StatusNotModified {
+				header.Del("Last-Modified")
+			}
+			ctx.SetStatusCode(status)
+			return
+		}
+
+		if cType := file.Header.Get("Content-Type"); cType != "" {
+			header.SetContentType(cType)
+		} else {
+			buf := make([]byte, min(size, _sniffLen))
+			n, _ := content.ReadAt(buf, 0)
+			header.SetContentType(http.DetectContentType(buf[:n]))
+		}
+		if encoding := file.Header.Get(host.Header_ContentEncoding); encoding != "" {
+			header.Set(host.Header_ContentEncoding, encoding)
+		}
+		header.Set("Accept-Ranges", "bytes")
+
+		start, length := int64(0), size
+		if rangeHeader := string(ctx.Request.Header.Peek("Range")); rangeHeader != "" && checkIfRange(&ctx.Request.Header, etag, modTime) {
+			var status int
+			start, length, status = parseRange(rangeHeader, size)
+			switch status {
+			case http.StatusRequestedRangeNotSatisfiable:
+				file.File.Close()
+				header.Set("Content-Range", "bytes */"+strconv.FormatInt(size, 10))
+				ctx.SetStatusCode(status)
+				return
+			case http.StatusPartialContent:
+				header.Set("Content-Range", "bytes "+strconv.FormatInt(start, 10)+"-"+strconv.FormatInt(start+length-1, 10)+"/"+strconv.FormatInt(size, 10))
+				ctx.SetStatusCode(status)
+			}
+		}
+
+		// 发送完成后fasthttp关闭流，HEAD请求只发送Content-Length
+		ctx.Response.SetBodyStream(struct {
+			io.Reader
+			io.Closer
+		}{io.NewSectionReader(content, start, length), file.File}, int(length))
+	}
+}
+
+// readerAt 嵌入文件和磁盘文件实现io.ReaderAt，其它文件读入内存
+func readerAt(file io.Reader) (io.ReaderAt, error) {
+	if r, ok := file.(io.ReaderAt); ok {
+		return r, nil
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// checkPreconditions 按RFC 9110的顺序检查If-Match、If-Unmodified-Since、If-None-Match和If-Modified-Since，
+// 返回200表示继续处理，否则返回304或412
+func checkPreconditions(h *fasthttp.RequestHeader, etag string, modTime time.Time) int {
+	if ifMatch := string(h.Peek("If-Match")); ifMatch != "" {
+		if !matchETag(ifMatch, etag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if t, ok := parseHTTPTime(h.Peek("If-Unmodified-Since")); ok && !modTime.IsZero() && modTime.Truncate(time.Second).After(t) {
+		return http.StatusPreconditionFailed
+	}
+
+	if ifNoneMatch := string(h.Peek("If-None-Match")); ifNoneMatch != "" {
+		if matchETag(ifNoneMatch, etag, true) {
+			return http.StatusNotModified
+		}
+	} else if t, ok := parseHTTPTime(h.Peek("If-Modified-Since")); ok && !modTime.IsZero() && !modTime.Truncate(time.Second).After(t) {
+		return http.StatusNotModified
+	}
+	return http.StatusOK
+}
+
+// checkIfRange 没有If-Range或If-Range与当前文件一致时Range有效
+func checkIfRange(h *fasthttp.RequestHeader, etag string, modTime time.Time) bool {
+	ifRange := string(h.Peek("If-Range"))
+	switch {
+	case ifRange == "":
+		return true
+	case strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/"):
+		return matchETag(ifRange, etag, false)
+	}
+	t, ok := parseHTTPTime([]byte(ifRange))
+	return ok && !modTime.IsZero() && modTime.Truncate(time.Second).Equal(t)
+}
+
+// matchETag header为逗号分隔的ETag列表或*，weak为true时使用弱比较（忽略W/前缀），否则弱ETag不匹配
+func matchETag(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+			etag = strings.TrimPrefix(etag, "W/")
+		} else if strings.HasPrefix(candidate, "W/") || strings.HasPrefix(etag, "W/") {
+			continue
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRange 解析单个bytes范围，返回起始位置、长度和状态码：206，416（范围不可满足），
+// 200（多个范围或无法解析，忽略Range返回整个文件）
+func parseRange(header string, size int64) (start, length int64, status int) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, size, http.StatusOK
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, size, http.StatusOK
+	}
+
+	if first == "" {
+		// 后缀范围，如bytes=-500
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, size, http.StatusOK
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, http.StatusRequestedRangeNotSatisfiable
+		}
+		n = min(n, size)
+		return size - n, n, http.StatusPartialContent
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, size, http.StatusOK
+	}
+	if start >= size {
+		return 0, 0, http.StatusRequestedRangeNotSatisfiable
+	}
+	end := size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return 0, size, http.StatusOK
+		}
+		end = min(end, size-1)
+	}
+	return start, end - start + 1, http.StatusPartialContent
+}
+
+func parseHTTPTime(value []byte) (time.Time, bool) {
+	if len(value) == 0 {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(string(value))
+	return t, err == nil
+}
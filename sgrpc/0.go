@@ -9,10 +9,14 @@ import (
 	"github.com/syncfuture/go/sconfig"
 	"github.com/syncfuture/go/sconv"
 	"github.com/syncfuture/go/serr"
+	"github.com/syncfuture/go/sid"
 	"github.com/syncfuture/go/u"
 	"github.com/syncfuture/host"
 	_ "github.com/syncfuture/host/sconsul"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
 )
@@ -22,7 +26,8 @@ import (
 type contextKey string
 
 const (
-	Header_Token = "token"
+	Header_Token     = "token"
+	Header_RequestID = "x-request-id" // metadata的key均为小写
 	// Ctx_Claims   = "claims"
 	Ctx_Claims contextKey = "claims"
 )
@@ -36,37 +41,111 @@ const (
 // 	return grpc.NewServer(uIntOpt, sIntOpt)
 // }
 
-// DialWithHttpContextToken 拨号，发送令牌
-func DialWithHttpContextToken(addr string, ctx host.IHttpContext) (r *grpc.ClientConn, err error) {
+// DialWithHttpContextToken 拨号，发送令牌、请求ID和链路追踪上下文，一元调用随ctx.Context()超时或取消，opts在默认选项之后应用，如WithClientTLS
+func DialWithHttpContextToken(addr string, ctx host.IHttpContext, opts ...grpc.DialOption) (r *grpc.ClientConn, err error) {
+	requestID := host.GetRequestID(ctx)
+	parent := host.GetTraceContext(ctx)
+	dialOptions := []grpc.DialOption{
+		// grpc.WithInsecure(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(sendRequestIDUnaryInterceptor(requestID), sendTraceUnaryInterceptor(parent), linkContextUnaryInterceptor(parent)),
+		grpc.WithChainStreamInterceptor(sendRequestIDStreamInterceptor(requestID), sendTraceStreamInterceptor(parent)),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}
 	j := ctx.GetItem(host.Ctx_Token) // RL00002
-	if j != nil {
-		token, ok := j.(string)
-		if ok {
-			r, err = grpc.Dial(
-				addr,
-				// grpc.WithInsecure(),
-				grpc.WithTransportCredentials(insecure.NewCredentials()),
-				grpc.WithPerRPCCredentials(newTokenCredential(token, false)),
-			)
-		}
+	if token, ok := j.(string); ok {
+		dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(newTokenCredential(token, false)))
 	}
 
-	if r == nil {
-		r, err = grpc.Dial(
-			addr,
-			// grpc.WithInsecure(),
-			grpc.WithTransportCredentials(insecure.NewCredentials()),
-		)
+	r, err = grpc.Dial(addr, append(dialOptions, opts...)...)
+	return r, serr.WithStack(err)
+}
+
+// appendRequestID 将请求ID加入发出的metadata，优先使用ctx中的请求ID（服务间继续传递），其次使用requestID
+func appendRequestID(ctx context.Context, requestID string) context.Context {
+	if id := host.RequestIDFromContext(ctx); id != "" {
+		requestID = id
+	}
+	if requestID == "" {
+		return ctx
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(Header_RequestID)) > 0 {
+		return ctx
 	}
+	return metadata.AppendToOutgoingContext(ctx, Header_RequestID, requestID)
+}
 
-	return r, serr.WithStack(err)
+func sendRequestIDUnaryInterceptor(requestID string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(appendRequestID(ctx, requestID), method, req, reply, cc, opts...)
+	}
+}
+
+func sendRequestIDStreamInterceptor(requestID string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(appendRequestID(ctx, requestID), desc, cc, method, opts...)
+	}
+}
+
+// withParentSpan 调用的ctx中没有span时，以parent中的span（通常是HTTP请求的span）为父span
+func withParentSpan(ctx, parent context.Context) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	return trace.ContextWithSpan(ctx, trace.SpanFromContext(parent))
+}
+
+func sendTraceUnaryInterceptor(parent context.Context) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(withParentSpan(ctx, parent), method, req, reply, cc, opts...)
+	}
 }
 
-// receiveTokenMiddleware 接收令牌中间件
+func sendTraceStreamInterceptor(parent context.Context) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(withParentSpan(ctx, parent), desc, cc, method, opts...)
+	}
+}
+
+// linkContextUnaryInterceptor 使一元调用随parent（HTTP请求的context）超时或取消，流式调用需直接使用ctx.Context()
+func linkContextUnaryInterceptor(parent context.Context) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, stop := host.LinkContext(ctx, parent)
+		defer stop()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// receiveRequestID 从metadata中取出请求ID，没有或不合法时生成，附加给context并通过响应头返回
+func receiveRequestID(ctx context.Context) context.Context {
+	var requestID string
+	if metas, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := metas.Get(Header_RequestID); len(ids) > 0 {
+			requestID = ids[0]
+		}
+	}
+	if !host.IsValidRequestID(requestID) {
+		requestID = sid.GenerateID()
+	}
+
+	grpc.SetHeader(ctx, metadata.Pairs(Header_RequestID, requestID)) // 非gRPC调用（如单元测试）时会失败，忽略
+	return host.ContextWithRequestID(ctx, requestID)
+}
+
+// GetRequestID 获取receiveTokenMiddleware附加的请求ID
+func GetRequestID(ctx context.Context) string {
+	return host.RequestIDFromContext(ctx)
+}
+
+// receiveTokenMiddleware 接收令牌中间件，同时接收请求ID
 func receiveTokenMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	ctx = receiveRequestID(ctx)
+
 	claims, err := receiveTokenMiddleware_ExtractClaims(ctx) // 从收到的令牌中提取出Claims
 	if err != nil || claims == nil {
-		u.LogError(err)
+		if err != nil {
+			host.NewRequestLogger(GetRequestID(ctx)).Error(err)
+		}
 		return handler(ctx, req)
 	}
 
@@ -133,10 +212,22 @@ func DialConsul(consulAddr, serviceName string, args map[string]string, cp sconf
 
 	maxCallRecvMsgSize := 10 * 1024 * 1024
 	maxCallSendMsgSize := 10 * 1024 * 1024
+	transportCredentials := insecure.NewCredentials()
 
 	if cp != nil {
 		maxCallRecvMsgSize = cp.GetIntDefault("MaxCallRecvMsgSize", maxCallRecvMsgSize)
 		maxCallSendMsgSize = cp.GetIntDefault("MaxCallSendMsgSize", maxCallSendMsgSize)
+
+		// 配置GRPCClientTLS时以TLS连接服务
+		var tlsOptions *host.TLSClientOptions
+		cp.GetStruct("GRPCClientTLS", &tlsOptions)
+		if tlsOptions != nil {
+			tlsConfig, err := tlsOptions.BuildTLSConfig()
+			if err != nil {
+				return nil, err
+			}
+			transportCredentials = credentials.NewTLS(tlsConfig)
+		}
 	}
 
 	r, err := grpc.Dial(
@@ -144,11 +235,15 @@ func DialConsul(consulAddr, serviceName string, args map[string]string, cp sconf
 		//不能block => blockkingPicker打开，在调用轮询时picker_wrapper => picker时若block则不进行robin操作直接返回失败
 		//grpc.WithBlock(),
 		// grpc.WithInsecure(),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(transportCredentials),
 		// insecure.NewCredentials(),
 		//指定初始化round_robin => balancer (后续可以自行定制balancer和 register、resolver 同样的方式)
 		// grpc.WithBalancerName(roundrobin.Name),
 		grpc.WithDefaultServiceConfig(`{"loadBalancingPolicy":"round_robin"}`),
+		// 转发ctx中的请求ID
+		grpc.WithChainUnaryInterceptor(sendRequestIDUnaryInterceptor("")),
+		grpc.WithChainStreamInterceptor(sendRequestIDStreamInterceptor("")),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()), // 创建客户端span并通过metadata传播traceparent
 		//grpc.WithDefaultServiceConfig(`{"loadBalancingConfig": [{"round_robin":{}}]}`),
 		// TODO: 增加收发字节限制配置
 		grpc.WithDefaultCallOptions(
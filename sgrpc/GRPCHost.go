@@ -1,15 +1,22 @@
 package sgrpc
 
 import (
+	"context"
 	"net"
+	"time"
 
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	panichandler "github.com/kazegusuri/grpc-panic-handler"
 	"github.com/syncfuture/go/sconfig"
 	"github.com/syncfuture/go/serr"
 	"github.com/syncfuture/go/slog"
+	"github.com/syncfuture/go/sredis"
+	"github.com/syncfuture/host"
 	"github.com/syncfuture/host/service"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type GRPCOption func(*GRPCServiceHost)
@@ -17,6 +24,8 @@ type GRPCOption func(*GRPCServiceHost)
 type IGRPCServiceHost interface {
 	service.IServiceHost
 	GetGRPCServer() *grpc.Server
+	// AddHealthCheck 添加就绪检查，未配置Health时忽略
+	AddHealthCheck(name string, check host.HealthCheckFunc)
 }
 
 type GRPCServiceHost struct {
@@ -24,6 +33,15 @@ type GRPCServiceHost struct {
 	GRPCServer     *grpc.Server
 	MaxRecvMsgSize int
 	MaxSendMsgSize int
+	// TLS 配置后以TLS监听，配置ClientCAFile时验证调用方证书，服务中通过GetPeerCertificate(ctx)取得
+	TLS *host.TLSOptions
+	// Metrics 指标配置，gRPC宿主只能通过ListenAddr在单独的端口暴露
+	Metrics *host.MetricsOptions
+	// Tracing 链路追踪配置，从metadata中的traceparent继续调用方的链路
+	Tracing *host.TracingOptions
+	// Health 健康检查配置，配置后注册标准gRPC健康检查服务(grpc.health.v1.Health)
+	Health        *host.HealthOptions
+	HealthChecker *host.HealthChecker `json:"-"`
 }
 
 func NewGRPCServiceHost(cp sconfig.IConfigProvider, options ...GRPCOption) IGRPCServiceHost {
@@ -52,18 +70,66 @@ func (x *GRPCServiceHost) BuildGRPCServiceHost() {
 	}
 
 	// GRPC Server
-	unaryHandler := grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(panichandler.UnaryPanicHandler, receiveTokenMiddleware))
-	streamHandler := grpc.StreamInterceptor(panichandler.StreamPanicHandler)
+	unaryInterceptors := []grpc.UnaryServerInterceptor{panichandler.UnaryPanicHandler, receiveTokenMiddleware}
+	streamInterceptors := []grpc.StreamServerInterceptor{panichandler.StreamPanicHandler}
+	if x.Metrics != nil {
+		if x.Metrics.ListenAddr == "" {
+			slog.Fatal("Metrics.ListenAddr cannot be empty")
+		}
+		// 放在最外层，以便统计到panic处理转换后的错误
+		unaryInterceptors = append([]grpc.UnaryServerInterceptor{metricsUnaryInterceptor}, unaryInterceptors...)
+		streamInterceptors = append([]grpc.StreamServerInterceptor{metricsStreamInterceptor}, streamInterceptors...)
+	}
+	unaryHandler := grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(unaryInterceptors...))
+	streamHandler := grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(streamInterceptors...))
 	panichandler.InstallPanicHandler(func(r interface{}) {
 		slog.Error(r)
 	})
 
-	x.GRPCServer = grpc.NewServer(
+	serverOptions := []grpc.ServerOption{
 		grpc.MaxRecvMsgSize(x.MaxRecvMsgSize),
 		grpc.MaxSendMsgSize(x.MaxSendMsgSize),
 		unaryHandler,
 		streamHandler,
-	)
+	}
+	if x.Tracing != nil {
+		if x.Tracing.ServiceName == "" {
+			x.Tracing.ServiceName = x.Name
+		}
+		provider, err := x.Tracing.BuildTracerProvider()
+		if err != nil {
+			slog.Fatalf("%+v", err)
+		}
+		x.AddShutdownHooks(provider.Shutdown)
+		serverOptions = append(serverOptions, grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	}
+
+	if x.TLS != nil {
+		reloader, err := host.NewTLSReloader(x.TLS, "h2")
+		if err != nil {
+			slog.Fatalf("%+v", err)
+		}
+		x.AddShutdownHooks(reloader.Start())
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(reloader.TLSConfig())))
+	}
+
+	x.GRPCServer = grpc.NewServer(serverOptions...)
+
+	// 健康检查，宿主关闭期间返回NOT_SERVING
+	if x.Health != nil {
+		x.HealthChecker = host.NewHealthChecker(x.Health, x.IsShuttingDown)
+		if x.RedisConfig != nil {
+			x.HealthChecker.AddCheck("redis", host.RedisHealthCheck(sredis.NewClient(x.RedisConfig)))
+		}
+		healthpb.RegisterHealthServer(x.GRPCServer, &healthServer{checker: x.HealthChecker, serviceName: x.Name})
+	}
+}
+
+// AddHealthCheck 添加就绪检查，未配置Health时忽略
+func (x *GRPCServiceHost) AddHealthCheck(name string, check host.HealthCheckFunc) {
+	if x.HealthChecker != nil {
+		x.HealthChecker.AddCheck(name, check)
+	}
 }
 
 func (x *GRPCServiceHost) GetGRPCServer() *grpc.Server {
@@ -71,11 +137,46 @@ func (x *GRPCServiceHost) GetGRPCServer() *grpc.Server {
 }
 
 func (x *GRPCServiceHost) Run() error {
+	return x.RunContext(context.Background())
+}
+
+func (x *GRPCServiceHost) RunContext(ctx context.Context) error {
 	listen, err := net.Listen("tcp", x.ListenAddr)
 	if err != nil {
 		return serr.WithStack(err)
 	}
 
+	// 指标服务在监听成功后启动，失败时关闭已打开的监听
+	if x.Metrics != nil {
+		hook, err := x.Metrics.StartServer()
+		if err != nil {
+			listen.Close()
+			return err
+		}
+		x.AddShutdownHooks(hook)
+	}
+
 	slog.Infof("Listening at %v\n", x.ListenAddr)
-	return serr.WithStack(x.GRPCServer.Serve(listen))
+	return x.RunUntilSignal(ctx, func() error {
+		return serr.WithStack(x.GRPCServer.Serve(listen))
+	}, x.Shutdown)
+}
+
+// Shutdown 停止接收新连接，等待处理中的RPC完成（最多timeout，超时则强制关闭），然后执行关闭钩子
+func (x *GRPCServiceHost) Shutdown(timeout time.Duration) error {
+	return x.GracefulShutdown(timeout, func(ctx context.Context) error {
+		stopped := make(chan struct{})
+		go func() {
+			x.GRPCServer.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			x.GRPCServer.Stop() // 超时，强制关闭
+			return serr.WithStack(ctx.Err())
+		}
+	})
 }
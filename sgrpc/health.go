@@ -0,0 +1,74 @@
+package sgrpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/syncfuture/host"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	_healthWatchInterval = time.Second
+)
+
+// healthServer 标准gRPC健康检查服务，状态取自HealthChecker的就绪检查
+// service为空或等于宿主名称时返回整体状态，其余返回NotFound
+type healthServer struct {
+	checker     *host.HealthChecker
+	serviceName string
+}
+
+func (x *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if !x.isKnown(req.Service) {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.Service)
+	}
+	return &healthpb.HealthCheckResponse{Status: x.servingStatus(ctx)}, nil
+}
+
+// Watch 状态变化时推送，检查间隔为1秒
+func (x *healthServer) Watch(req *healthpb.HealthCheckRequest, stream grpc.ServerStreamingServer[healthpb.HealthCheckResponse]) error {
+	ctx := stream.Context()
+	if !x.isKnown(req.Service) {
+		// 按规范，未知服务返回SERVICE_UNKNOWN并保持连接
+		if err := stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVICE_UNKNOWN}); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		return status.FromContextError(ctx.Err()).Err()
+	}
+
+	ticker := time.NewTicker(_healthWatchInterval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		if current := x.servingStatus(ctx); current != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: current}); err != nil {
+				return err
+			}
+			last = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (x *healthServer) isKnown(service string) bool {
+	return service == "" || service == x.serviceName
+}
+
+// servingStatus 检查不随RPC取消，避免客户端断开导致缓存失败的结果，检查本身有超时
+func (x *healthServer) servingStatus(ctx context.Context) healthpb.HealthCheckResponse_ServingStatus {
+	if x.checker.Ready(context.WithoutCancel(ctx)).Status == host.HealthStatus_Up {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
@@ -0,0 +1,32 @@
+package sgrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncfuture/host"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestHealthServerCheck(t *testing.T) {
+	checker := host.NewHealthChecker(&host.HealthOptions{TimeoutMs: 1000, CacheMs: 60000}, func() bool { return false })
+	checker.AddCheck("db", func(ctx context.Context) error {
+		return ctx.Err()
+	})
+	x := &healthServer{checker: checker, serviceName: "orders"}
+
+	// 调用方已取消时检查仍然执行，失败的结果不会被缓存
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	resp, err := x.Check(canceled, &healthpb.HealthCheckRequest{Service: "orders"})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	resp, err = x.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	_, err = x.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "other"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,57 @@
+package sgrpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/syncfuture/host"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	_grpcHandledTotal = promauto.With(host.MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "Total number of RPCs completed on the server by method and code.",
+	}, []string{"method", "code"})
+	_grpcHandlingSeconds = promauto.With(host.MetricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "RPC latency on the server by method and code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+	_grpcInFlight = promauto.With(host.MetricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "grpc_server_in_flight",
+		Help: "Number of RPCs being handled by method.",
+	}, []string{"method"})
+)
+
+// metricsUnaryInterceptor 统计一元调用
+func metricsUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	done := observeRPC(info.FullMethod)
+	resp, err := handler(ctx, req)
+	done(err)
+	return resp, err
+}
+
+// metricsStreamInterceptor 统计流式调用，耗时为整个流的持续时间
+func metricsStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	done := observeRPC(info.FullMethod)
+	err := handler(srv, ss)
+	done(err)
+	return err
+}
+
+func observeRPC(method string) func(err error) {
+	inFlight := _grpcInFlight.WithLabelValues(method)
+	inFlight.Inc()
+	start := time.Now()
+
+	return func(err error) {
+		inFlight.Dec()
+		code := status.Code(err).String()
+		_grpcHandledTotal.WithLabelValues(method, code).Inc()
+		_grpcHandlingSeconds.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,33 @@
+package sgrpc
+
+import (
+	"context"
+	"crypto/x509"
+
+	"github.com/syncfuture/host"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// GetPeerCertificate 双向TLS时调用方的客户端证书，未启用TLS或调用方未提供证书时为nil
+func GetPeerCertificate(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return nil
+	}
+	return info.State.PeerCertificates[0]
+}
+
+// WithClientTLS 以TLS连接服务端的拨号选项，可传给DialWithHttpContextToken
+func WithClientTLS(options *host.TLSClientOptions) (grpc.DialOption, error) {
+	config, err := options.BuildTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(config)), nil
+}
@@ -0,0 +1,654 @@
+package snethttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gorilla/schema"
+	"github.com/syncfuture/go/sconv"
+	"github.com/syncfuture/go/serr"
+	"github.com/syncfuture/go/shttp"
+	"github.com/syncfuture/go/spool"
+	"github.com/syncfuture/go/ssecurity"
+	"github.com/syncfuture/go/u"
+	"github.com/syncfuture/host"
+)
+
+const _maxMultipartMemory = 32 << 20
+
+var (
+	_ctxPool = &sync.Pool{
+		New: func() interface{} {
+			return &NetHttpContext{
+				items: make(map[string]interface{}),
+			}
+		},
+	}
+	_cookiePool = spool.NewSyncCookiePool()
+	_decoder    = schema.NewDecoder()
+)
+
+func init() {
+	_decoder.IgnoreUnknownKeys(true)
+}
+
+// NetHttpContext : IHttpContext, http.ResponseWriter
+// 响应先写入缓冲区，处理链执行完毕后再统一输出，与fasthttp的行为保持一致
+type NetHttpContext struct {
+	w               http.ResponseWriter
+	r               *http.Request
+	sess            *SessionManager
+	sessID          string
+	sessValues      map[string]string
+	sessDirty       bool
+	cookieEncryptor ssecurity.ICookieEncryptor
+	items           map[string]interface{}
+	reqBody         []byte
+	bodyRead        bool
+	statusCode      int
+	body            bytes.Buffer
+	bodyStream      io.Reader
+	sseHandler      host.SSEHandler
+	sseOptions      *host.SSEOptions
+	wsHandler       host.WebSocketHandler
+	wsUpgrader      *websocket.Upgrader
+	shutdown        <-chan struct{}
+	goctx           context.Context
+	handlers        []host.RequestHandler
+	handlerIndex    int
+	handlerCount    int
+}
+
+func NewNetHttpContext(w http.ResponseWriter, r *http.Request, sess *SessionManager, cookieEncryptor ssecurity.ICookieEncryptor, handlers ...host.RequestHandler) host.IHttpContext {
+	x := _ctxPool.Get().(*NetHttpContext)
+	x.w = w
+	x.r = r
+	x.sess = sess
+	x.cookieEncryptor = cookieEncryptor
+	x.handlers = handlers
+	x.handlerCount = len(handlers)
+	return x
+}
+
+func (x *NetHttpContext) GetInnerContext() interface{} {
+	return x.r
+}
+
+// GetRequest 原始请求
+func (x *NetHttpContext) GetRequest() *http.Request {
+	return x.r
+}
+
+// GetResponseWriter 原始ResponseWriter，直接写入会绕过缓冲区
+func (x *NetHttpContext) GetResponseWriter() http.ResponseWriter {
+	return x.w
+}
+
+// Header http.ResponseWriter
+func (x *NetHttpContext) Header() http.Header {
+	return x.w.Header()
+}
+
+// WriteHeader http.ResponseWriter
+func (x *NetHttpContext) WriteHeader(statusCode int) {
+	x.statusCode = statusCode
+}
+
+func (x *NetHttpContext) Write(p []byte) (n int, err error) {
+	return x.body.Write(p)
+}
+
+func (x *NetHttpContext) SetItem(key string, value interface{}) {
+	x.items[key] = value
+}
+func (x *NetHttpContext) GetItem(key string) interface{} {
+	return x.items[key]
+}
+func (x *NetHttpContext) GetItemString(key string) string {
+	v := x.items[key]
+	return sconv.ToString(v)
+}
+func (x *NetHttpContext) GetItemInt(key string) int {
+	v := x.items[key]
+	return sconv.ToInt(v)
+}
+func (x *NetHttpContext) GetItemInt32(key string) int32 {
+	v := x.items[key]
+	return sconv.ToInt32(v)
+}
+func (x *NetHttpContext) GetItemInt64(key string) int64 {
+	v := x.items[key]
+	return sconv.ToInt64(v)
+}
+
+func (x *NetHttpContext) GetRouteKey() string {
+	return x.GetItemString(host.Ctx_RouteKey)
+}
+
+// Context 默认为Request.Context()，客户端断开或ServeHTTP返回时取消
+func (x *NetHttpContext) Context() context.Context {
+	if x.goctx != nil {
+		return x.goctx
+	}
+	return x.r.Context()
+}
+func (x *NetHttpContext) SetContext(c context.Context) {
+	x.goctx = c
+}
+
+func (x *NetHttpContext) GetCSPNonce() string {
+	return x.GetItemString(host.Ctx_CSPNonce)
+}
+
+func (x *NetHttpContext) GetPeerCertificate() *x509.Certificate {
+	if x.r.TLS == nil || len(x.r.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return x.r.TLS.PeerCertificates[0]
+}
+
+func (x *NetHttpContext) setCookie(cookie *http.Cookie) {
+	x.removeSetCookie(cookie.Name)
+	http.SetCookie(x.w, cookie)
+}
+
+// removeSetCookie 删除响应中已设置的同名Cookie
+func (x *NetHttpContext) removeSetCookie(name string) {
+	header := x.w.Header()
+	setCookies := header.Values("Set-Cookie")
+	if len(setCookies) == 0 {
+		return
+	}
+
+	header.Del("Set-Cookie")
+	prefix := name + "="
+	for _, v := range setCookies {
+		if !strings.HasPrefix(v, prefix) {
+			header.Add("Set-Cookie", v)
+		}
+	}
+}
+
+func (x *NetHttpContext) SetCookieKV(key, value string, options ...func(*http.Cookie)) {
+	c := _cookiePool.GetCookie()
+	defer func() {
+		_cookiePool.PutCookie(c)
+	}()
+
+	c.Name = key
+	c.Value = value
+
+	for _, o := range options {
+		o(c)
+	}
+
+	x.setCookie(c)
+}
+func (x *NetHttpContext) GetCookieString(key string) string {
+	c, err := x.r.Cookie(key)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
+func (x *NetHttpContext) SetEncryptedCookieKV(key, value string, options ...func(*http.Cookie)) {
+	if x.cookieEncryptor == nil {
+		host.GetLogger(x).Warn("cookieEncryptor is nil, this context does not suppot cookie encryption")
+		return
+	}
+	encryptedString, err := x.cookieEncryptor.Encrypt(key, value)
+	if host.GetLogger(x).LogError(err) {
+		return
+	}
+
+	x.SetCookieKV(key, encryptedString, options...)
+}
+
+func (x *NetHttpContext) GetEncryptedCookieString(key string) (r string) {
+	if x.cookieEncryptor == nil {
+		host.GetLogger(x).Warn("cookieEncryptor is nil, this context does not suppot cookie encryption")
+		return
+	}
+
+	encryptedString := x.GetCookieString(key)
+	if encryptedString != "" {
+		err := x.cookieEncryptor.Decrypt(key, encryptedString, &r)
+		host.GetLogger(x).LogError(err)
+	}
+
+	return
+}
+
+func (x *NetHttpContext) RemoveCookie(key string, options ...func(*http.Cookie)) {
+	c := _cookiePool.GetCookie()
+	defer func() {
+		_cookiePool.PutCookie(c)
+	}()
+	c.Name = key
+	c.Value = ""
+	c.Expires = time.Unix(0, 0)
+	c.MaxAge = -1
+
+	for _, o := range options {
+		o(c)
+	}
+
+	x.setCookie(c)
+}
+
+func (x *NetHttpContext) loadSession() {
+	if x.sessValues != nil {
+		return
+	}
+
+	x.sessID = x.GetCookieString(x.sess.CookieName)
+	if x.sessID != "" {
+		x.sessValues = x.sess.Store.Get(x.sessID)
+	}
+	if x.sessValues == nil {
+		x.sessValues = make(map[string]string)
+	}
+}
+
+// saveSession 保存Session，新Session需要下发Cookie
+func (x *NetHttpContext) saveSession() {
+	if !x.sessDirty {
+		return
+	}
+
+	isNew := x.sessID == ""
+	if isNew {
+		x.sessID = newSessionID()
+	}
+	err := x.sess.Store.Save(x.sessID, x.sessValues, x.sess.getStoreExpiration())
+	if host.GetLogger(x).LogError(err) {
+		return
+	}
+
+	if isNew || x.sess.Expiration > 0 {
+		x.SetCookieKV(x.sess.CookieName, x.sessID, func(c *http.Cookie) {
+			c.Path = "/"
+			c.HttpOnly = true
+			if x.sess.Expiration > 0 {
+				c.Expires = time.Now().Add(x.sess.Expiration)
+			}
+		})
+	}
+}
+
+func (x *NetHttpContext) SetSession(key, value string) {
+	x.loadSession()
+	x.sessValues[key] = value
+	x.sessDirty = true
+}
+func (x *NetHttpContext) GetSessionString(key string) string {
+	x.loadSession()
+	return x.sessValues[key]
+}
+func (x *NetHttpContext) RemoveSession(key string) {
+	x.loadSession()
+	delete(x.sessValues, key)
+	x.sessDirty = true
+}
+func (x *NetHttpContext) EndSession() {
+	x.loadSession()
+	if x.sessID != "" {
+		host.GetLogger(x).LogError(x.sess.Store.Destroy(x.sessID))
+		x.RemoveCookie(x.sess.CookieName, func(c *http.Cookie) {
+			c.Path = "/"
+		})
+	}
+	x.sessID = ""
+	x.sessValues = make(map[string]string)
+	x.sessDirty = false
+}
+
+// readBody 读取并缓存请求体，以便多次读取
+func (x *NetHttpContext) readBody() []byte {
+	if x.bodyRead {
+		return x.reqBody
+	}
+	x.bodyRead = true
+
+	if x.r.Body != nil {
+		data, err := io.ReadAll(x.r.Body)
+		host.GetLogger(x).LogError(err)
+		x.reqBody = data
+		x.r.Body = io.NopCloser(bytes.NewReader(data))
+	}
+
+	return x.reqBody
+}
+
+func (x *NetHttpContext) parseForm() error {
+	if x.r.Form != nil {
+		return nil
+	}
+
+	x.readBody()
+	if strings.HasPrefix(x.r.Header.Get("Content-Type"), "multipart/form-data") {
+		err := x.r.ParseMultipartForm(_maxMultipartMemory)
+		x.r.Body = io.NopCloser(bytes.NewReader(x.reqBody))
+		return serr.WithStack(err)
+	}
+
+	err := x.r.ParseForm()
+	x.r.Body = io.NopCloser(bytes.NewReader(x.reqBody))
+	return serr.WithStack(err)
+}
+
+func (x *NetHttpContext) GetFormString(key string) string {
+	host.GetLogger(x).LogError(x.parseForm())
+	return x.r.FormValue(key)
+}
+func (x *NetHttpContext) GetFormStringDefault(key, d string) (r string) {
+	r = x.GetFormString(key)
+	if r == "" {
+		r = d
+	}
+	return
+}
+
+func (x *NetHttpContext) GetFormFile(key string) (*multipart.FileHeader, error) {
+	if err := x.parseForm(); err != nil {
+		return nil, err
+	}
+	file, r, err := x.r.FormFile(key)
+	if err != nil {
+		return nil, serr.WithStack(err)
+	}
+	file.Close()
+	return r, nil
+}
+
+func (x *NetHttpContext) GetMultipartForm() (*multipart.Form, error) {
+	if err := x.parseForm(); err != nil {
+		return nil, err
+	}
+	if x.r.MultipartForm == nil {
+		return nil, serr.WithStack(http.ErrNotMultipart)
+	}
+	return x.r.MultipartForm, nil
+}
+
+func (x *NetHttpContext) GetBodyString() string {
+	return u.BytesToStr(x.readBody())
+}
+func (x *NetHttpContext) GetBodyBytes() []byte {
+	return x.readBody()
+}
+
+func (x *NetHttpContext) GetParamString(key string) string {
+	if v := x.r.PathValue(key); v != "" {
+		return v
+	}
+	return x.GetItemString(key)
+}
+func (x *NetHttpContext) GetParamInt(key string) int {
+	v := x.GetParamString(key)
+	return sconv.ToInt(v)
+}
+func (x *NetHttpContext) GetParamInt32(key string) int32 {
+	v := x.GetParamString(key)
+	return sconv.ToInt32(v)
+}
+func (x *NetHttpContext) GetParamInt64(key string) int64 {
+	v := x.GetParamString(key)
+	return sconv.ToInt64(v)
+}
+
+func (x *NetHttpContext) ReadJSON(objPtr interface{}) error {
+	data := x.readBody()
+	err := json.Unmarshal(data, objPtr)
+	return serr.WithStack(err)
+}
+func (x *NetHttpContext) ReadQuery(objPtr interface{}) error {
+	err := _decoder.Decode(objPtr, x.r.URL.Query())
+	return serr.WithStack(err)
+}
+func (x *NetHttpContext) ReadForm(objPtr interface{}) error {
+	if err := x.parseForm(); err != nil {
+		return err
+	}
+	err := _decoder.Decode(objPtr, x.r.PostForm)
+	return serr.WithStack(err)
+}
+
+func (x *NetHttpContext) ReadFormMap() (map[string][]string, error) {
+	if err := x.parseForm(); err != nil {
+		return nil, err
+	}
+
+	dic := make(map[string][]string, len(x.r.PostForm))
+	for k, v := range x.r.PostForm {
+		if len(v) > 0 {
+			dic[k] = []string{v[len(v)-1]}
+		}
+	}
+
+	return dic, nil
+}
+
+func (x *NetHttpContext) Bind(objPtr interface{}) error {
+	return host.Bind(x, objPtr)
+}
+
+func (x *NetHttpContext) SetHeader(key, value string) {
+	x.w.Header().Set(key, value)
+}
+func (x *NetHttpContext) GetHeader(key string) string {
+	if strings.EqualFold(key, "Host") {
+		return x.r.Host
+	}
+	return x.r.Header.Get(key)
+}
+
+func (x *NetHttpContext) SetStatusCode(statusCode int) {
+	x.statusCode = statusCode
+}
+func (x *NetHttpContext) GetStatusCode() int {
+	if x.statusCode == 0 {
+		return http.StatusOK
+	}
+	return x.statusCode
+}
+func (x *NetHttpContext) GetResponseHeader(key string) string {
+	return x.w.Header().Get(key)
+}
+func (x *NetHttpContext) GetResponseBody() []byte {
+	if x.IsBodyStream() {
+		return nil
+	}
+	return x.body.Bytes()
+}
+func (x *NetHttpContext) SetResponseBody(body []byte) {
+	x.body.Reset()
+	x.body.Write(body)
+	x.w.Header().Del("Content-Length")
+}
+func (x *NetHttpContext) IsBodyStream() bool {
+	return x.bodyStream != nil || x.sseHandler != nil || x.wsHandler != nil
+}
+func (x *NetHttpContext) SetContentType(cType string) {
+	x.w.Header().Set("Content-Type", cType)
+}
+func (x *NetHttpContext) WriteString(body string) (int, error) {
+	r, err := x.body.WriteString(body)
+	return r, serr.WithStack(err)
+}
+func (x *NetHttpContext) WriteBytes(body []byte) (int, error) {
+	r, err := x.body.Write(body)
+	return r, serr.WithStack(err)
+}
+
+func (x *NetHttpContext) WriteJsonBytes(body []byte) (int, error) {
+	x.SetContentType(shttp.CTYPE_JSON)
+	r, err := x.body.Write(body)
+	return r, serr.WithStack(err)
+}
+
+func (x *NetHttpContext) RequestURL() string {
+	scheme := "http"
+	if x.r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + x.r.Host + x.r.URL.RequestURI()
+}
+func (x *NetHttpContext) RequestPath() string {
+	return x.r.URL.Path
+}
+func (x *NetHttpContext) RequestMethod() string {
+	return x.r.Method
+}
+func (x *NetHttpContext) RequestURI() string {
+	return x.r.RequestURI
+}
+func (x *NetHttpContext) RequestProtocol() string {
+	return x.r.Proto
+}
+func (x *NetHttpContext) GetRemoteIP() string {
+	ip, _, err := net.SplitHostPort(x.r.RemoteAddr)
+	if err != nil {
+		return x.r.RemoteAddr
+	}
+	return ip
+}
+
+func (x *NetHttpContext) UserAgent() string {
+	return x.r.UserAgent()
+}
+
+// SSE 与fasthttp一致，handler在处理链执行完毕后（flush时）运行
+func (x *NetHttpContext) SSE(handler host.SSEHandler, options ...func(*host.SSEOptions)) {
+	host.SetSSEHeaders(x)
+	x.sseHandler = handler
+	x.sseOptions = host.NewSSEOptions(options...)
+}
+
+func (x *NetHttpContext) serveSSE() {
+	rc := http.NewResponseController(x.w)
+	x.w.WriteHeader(x.statusCode)
+	if host.GetLogger(x).LogError(rc.Flush()) {
+		return
+	}
+
+	stream := host.NewEventStream(x.w, rc.Flush, x.r.Header.Get(host.Header_LastEventID))
+	stream.Serve(x.sseHandler, x.sseOptions, x.r.Context().Done(), x.shutdown)
+}
+
+func (x *NetHttpContext) serveWebSocket() {
+	// 升级响应由Upgrader直接写出，只能通过参数带上Session等Cookie
+	var header http.Header
+	if cookies := x.w.Header().Values("Set-Cookie"); len(cookies) > 0 {
+		header = http.Header{"Set-Cookie": cookies}
+	}
+
+	conn, err := x.wsUpgrader.Upgrade(x.w, x.r, header)
+	if err != nil {
+		// Upgrader已写入错误响应
+		host.GetLogger(x).Debugf("websocket upgrade failed: %s -> %v", x.r.URL.Path, err)
+		return
+	}
+
+	host.NewWebSocketConn(conn, maps.Clone(x.items), x.shutdown).Serve(x.wsHandler)
+}
+
+func (x *NetHttpContext) Error(err error) {
+	x.SetItem(host.Ctx_Error, err)
+}
+
+func (x *NetHttpContext) Redirect(url string, statusCode int) {
+	x.w.Header().Set("Location", url)
+	x.statusCode = statusCode
+}
+func (x *NetHttpContext) CopyBodyAndStatusCode(resp *http.Response) {
+	x.statusCode = resp.StatusCode
+	x.bodyStream = resp.Body
+}
+
+// flush 保存Session并输出缓冲的响应
+func (x *NetHttpContext) flush() {
+	x.saveSession()
+
+	if x.statusCode == 0 {
+		x.statusCode = http.StatusOK
+	}
+
+	if x.sseHandler != nil {
+		x.serveSSE()
+		return
+	}
+	if x.wsHandler != nil {
+		x.serveWebSocket()
+		return
+	}
+
+	if x.bodyStream != nil {
+		x.w.WriteHeader(x.statusCode)
+		_, err := io.Copy(x.w, x.bodyStream)
+		host.GetLogger(x).LogError(err)
+		if c, ok := x.bodyStream.(io.Closer); ok {
+			c.Close()
+		}
+		return
+	}
+
+	if x.body.Len() > 0 && x.w.Header().Get("Content-Length") == "" {
+		x.w.Header().Set("Content-Length", strconv.Itoa(x.body.Len()))
+	}
+	x.w.WriteHeader(x.statusCode)
+	if x.body.Len() > 0 {
+		_, err := x.w.Write(x.body.Bytes())
+		host.GetLogger(x).LogError(err)
+	}
+}
+
+func (x *NetHttpContext) Next() {
+	if x.handlers == nil {
+		return
+	}
+
+	if x.handlerIndex < x.handlerCount-1 {
+		x.handlerIndex++
+		x.handlers[x.handlerIndex](x)
+	}
+}
+func (x *NetHttpContext) Reset() {
+	x.w = nil
+	x.r = nil
+	x.goctx = nil
+	x.sess = nil
+	x.sessID = ""
+	x.sessValues = nil
+	x.sessDirty = false
+	x.cookieEncryptor = nil
+	for k := range x.items { // this will compile to use "mapclear" internal function
+		delete(x.items, k)
+	}
+	x.reqBody = nil
+	x.bodyRead = false
+	x.statusCode = 0
+	x.body.Reset()
+	x.bodyStream = nil
+	x.sseHandler = nil
+	x.sseOptions = nil
+	x.wsHandler = nil
+	x.wsUpgrader = nil
+	x.shutdown = nil
+	x.handlers = nil
+	x.handlerCount = 0
+	x.handlerIndex = 0
+}
@@ -0,0 +1,46 @@
+package snethttp
+
+import (
+	"net/http"
+
+	"github.com/syncfuture/go/sconfig"
+	"github.com/syncfuture/host"
+	"github.com/syncfuture/host/client"
+)
+
+type ClientHostOption func(*NetHttpOAuthClientHost)
+
+type NetHttpOAuthClientHost struct {
+	client.OAuthClientHost
+	NetHttpWebHost
+}
+
+func NewNetHttpOAuthClientHost(cp sconfig.IConfigProvider, options ...ClientHostOption) client.IOAuthClientHost {
+	x := new(NetHttpOAuthClientHost)
+	cp.GetStruct("@this", &x)
+	x.ConfigProvider = cp
+
+	for _, o := range options {
+		o(x)
+	}
+
+	x.BuildNetHttpOAuthClientHost()
+
+	return x
+}
+
+func (x *NetHttpOAuthClientHost) BuildNetHttpOAuthClientHost() {
+	x.BuildOAuthClientHost()
+	x.NetHttpWebHost.CookieEncryptor = x.SecureCookieHost.GetCookieEncryptor()
+	x.NetHttpWebHost.UseRedisConfig(x.RedisConfig)
+	x.NetHttpWebHost.UseRouteProvider(x.RouteProvider)
+	x.NetHttpWebHost.buildNetHttpWebHost()
+	x.RegisterMiddleware(host.Middleware_Auth, x.AuthHandler)
+	x.AddHealthCheck("oauth", host.HttpHealthCheck(x.OAuthOptions.Endpoint.TokenURL))
+
+	////////// oauth client endpoints
+	x.addRoute(http.MethodGet, x.SignInPath, x.SignInPath, []host.RequestHandler{x.OAuthClientHandler.SignInHandler})
+	x.addRoute(http.MethodGet, x.SignInCallbackPath, x.SignInPath, []host.RequestHandler{x.OAuthClientHandler.SignInCallbackHandler})
+	x.addRoute(http.MethodGet, x.SignOutPath, x.SignInPath, []host.RequestHandler{x.OAuthClientHandler.SignOutHandler})
+	x.addRoute(http.MethodGet, x.SignOutCallbackPath, x.SignInPath, []host.RequestHandler{x.OAuthClientHandler.SignOutCallbackHandler})
+}
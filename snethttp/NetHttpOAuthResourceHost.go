@@ -0,0 +1,36 @@
+package snethttp
+
+import (
+	"github.com/syncfuture/go/sconfig"
+	"github.com/syncfuture/host"
+	"github.com/syncfuture/host/resource"
+)
+
+type ResourceHostOption func(*NetHttpOAuthResourceHost)
+
+type NetHttpOAuthResourceHost struct {
+	resource.OAuthResourceHost
+	NetHttpWebHost
+}
+
+func NewNetHttpOAuthResourceHost(cp sconfig.IConfigProvider, options ...ResourceHostOption) resource.IOAuthResourceHost {
+	r := new(NetHttpOAuthResourceHost)
+	cp.GetStruct("@this", &r)
+	r.ConfigProvider = cp
+
+	for _, o := range options {
+		o(r)
+	}
+
+	r.BuildNetHttpOAuthResourceHost()
+
+	return r
+}
+
+func (x *NetHttpOAuthResourceHost) BuildNetHttpOAuthResourceHost() {
+	x.BuildOAuthResourceHost()
+	x.NetHttpWebHost.UseRedisConfig(x.RedisConfig)
+	x.NetHttpWebHost.UseRouteProvider(x.RouteProvider)
+	x.NetHttpWebHost.buildNetHttpWebHost()
+	x.RegisterMiddleware(host.Middleware_Auth, x.AuthHandler)
+}
@@ -0,0 +1,43 @@
+package snethttp
+
+import (
+	"net/http"
+
+	"github.com/syncfuture/go/sconfig"
+	"github.com/syncfuture/host/token"
+)
+
+type TokenHostOption func(*NetHttpOAuthTokenHost)
+
+type NetHttpOAuthTokenHost struct {
+	token.OAuthTokenHost
+	NetHttpWebHost
+}
+
+func NewNetHttpOAuthTokenHost(cp sconfig.IConfigProvider, options ...TokenHostOption) token.IOAuthTokenHost {
+	r := new(NetHttpOAuthTokenHost)
+	cp.GetStruct("@this", &r)
+	r.ConfigProvider = cp
+
+	for _, o := range options {
+		o(r)
+	}
+
+	r.BuildNetHttpOAuthTokenHost()
+
+	return r
+}
+
+func (x *NetHttpOAuthTokenHost) BuildNetHttpOAuthTokenHost() {
+	x.BuildOAuthTokenHost()
+	x.NetHttpWebHost.CookieEncryptor = x.SecureCookieHost.GetCookieEncryptor()
+	x.NetHttpWebHost.UseRedisConfig(x.RedisConfig)
+	x.NetHttpWebHost.UseRouteProvider(x.RouteProvider)
+	x.NetHttpWebHost.buildNetHttpWebHost()
+
+	// oauth2go.TokenHost只提供fasthttp实现，通过适配器挂载
+	x.handleNative(http.MethodPost, x.TokenEndpoint, "TokenRequestHandler", NewFastHttpHandlerAdaptor(x.TokenHost.TokenRequestHandler))
+	x.handleNative(http.MethodGet, x.AuthorizeEndpoint, "AuthorizeRequestHandler", NewFastHttpHandlerAdaptor(x.TokenHost.AuthorizeRequestHandler))
+	x.handleNative(http.MethodGet, x.EndSessionEndpoint, "EndSessionRequestHandler", NewFastHttpHandlerAdaptor(x.TokenHost.EndSessionRequestHandler))
+	x.handleNative(http.MethodPost, x.EndSessionEndpoint, "ClearTokenRequestHandler", NewFastHttpHandlerAdaptor(x.TokenHost.ClearTokenRequestHandler))
+}
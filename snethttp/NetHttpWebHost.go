@@ -0,0 +1,476 @@
+package snethttp
+
+import (
+	"context"
+	"crypto/tls"
+	"embed"
+	"errors"
+	"io"
+	iofs "io/fs"
+	"mime"
+	"net/http"
+	"os"
+	fp "path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/syncfuture/go/sconfig"
+	"github.com/syncfuture/go/serr"
+	"github.com/syncfuture/go/slog"
+	"github.com/syncfuture/go/ssecurity"
+	"github.com/syncfuture/go/u"
+	"github.com/syncfuture/host"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+const (
+	_filepath = "filepath"
+	_suffix   = "/{" + _filepath + ":*}"
+)
+
+type WebHostOption func(*NetHttpWebHost)
+
+// NetHttpWebHost : IWebHost, 基于标准库net/http
+type NetHttpWebHost struct {
+	host.BaseWebHost
+	// 独有属性
+	IndexName          string
+	SessionCookieName  string
+	SessionExpSeconds  int
+	MaxHeaderBytes     int
+	MaxRequestBodySize int64
+	// H2C 是否支持不加密的HTTP/2
+	H2C            bool
+	Mux            *http.ServeMux
+	SessionStore   ISessionStore
+	SessionManager *SessionManager
+	// Http请求Handler，如果指定此Hanlder，则Mux的Handler不起作用
+	HttpHandler     host.RequestHandler
+	PanicHandler    host.RequestHandler
+	CookieEncryptor ssecurity.ICookieEncryptor
+	WebSocket       *host.WebSocketOptions
+	WSUpgrader      *websocket.Upgrader
+	// Middlewares 标准库中间件，按顺序包裹在最外层
+	Middlewares      []func(http.Handler) http.Handler
+	server           atomic.Pointer[http.Server]
+	preflightHandler http.HandlerFunc
+	// patterns 已注册的ServeMux模式对应的路由
+	patterns map[string]string
+	// methods 已注册路由使用的方法
+	methods map[string]struct{}
+}
+
+func NewNetHttpWebHost(cp sconfig.IConfigProvider, options ...WebHostOption) host.IWebHost {
+	r := new(NetHttpWebHost)
+	cp.GetStruct("@this", &r)
+
+	for _, o := range options {
+		o(r)
+	}
+
+	r.buildNetHttpWebHost()
+
+	return r
+}
+
+func (x *NetHttpWebHost) buildNetHttpWebHost() {
+	x.UseCookieEncryptor(x.CookieEncryptor)
+	x.BuildBaseWebHost()
+
+	if x.IndexName == "" {
+		x.IndexName = "index.html"
+	}
+
+	if x.SessionCookieName == "" {
+		x.SessionCookieName = "go.cookie1"
+	}
+
+	////////// mux
+	if x.Mux == nil {
+		x.Mux = http.NewServeMux()
+	}
+
+	////////// 响应没有OPTIONS路由的预检请求，不影响用户注册的OPTIONS路由
+	// 在用户添加全局中间件之前构建，预检请求只经过宿主自身的中间件，不经过认证等用户中间件
+	x.preflightHandler = x.BuildNativeHandler(host.RouteKey_CORSPreflight, x.CORSPolicies.PreflightHandler)
+
+	////////// session store
+	if x.SessionStore == nil {
+		x.SessionStore = NewMemorySessionStore()
+	}
+
+	////////// session manager
+	if x.SessionManager == nil {
+		var expiration time.Duration
+		if x.SessionExpSeconds > 0 {
+			expiration = time.Second * time.Duration(x.SessionExpSeconds)
+		}
+		x.SessionManager = NewSessionManager(x.SessionStore, x.SessionCookieName, expiration)
+	}
+
+	////////// websocket upgrader
+	if x.WSUpgrader == nil {
+		x.WSUpgrader = x.newWSUpgrader()
+	}
+
+	if x.MaxRequestBodySize <= 0 {
+		x.MaxRequestBodySize = 4 * 1024 * 1024 // 与fasthttp.DefaultMaxRequestBodySize一致
+	}
+
+	////////// 指标，不经过全局中间件
+	if x.Metrics != nil && x.Metrics.ListenAddr == "" {
+		x.handleNative(http.MethodGet, x.Metrics.GetPath(), "metrics", host.NewMetricsHttpHandler())
+	}
+
+	////////// 健康检查，不经过全局中间件
+	if x.HealthChecker != nil {
+		x.handleNative(http.MethodGet, x.Health.GetLivePath(), "health.live", x.HealthChecker.LiveHttpHandler())
+		x.handleNative(http.MethodGet, x.Health.GetReadyPath(), "health.ready", x.HealthChecker.ReadyHttpHandler())
+	}
+
+	////////// 路由列表
+	if x.RouteTable != nil {
+		x.GET(x.RouteTable.GetPath(), append(slices.Clone(x.RouteTable.Handlers), x.RouteTableHandler)...)
+	}
+
+	////////// OpenAPI文档
+	if x.OpenAPI != nil {
+		x.GET(x.OpenAPI.GetPath(), append(slices.Clone(x.OpenAPI.Handlers), x.OpenAPIHandler)...)
+		if x.OpenAPI.SwaggerUIPath != "" {
+			x.GET(x.OpenAPI.SwaggerUIPath, append(slices.Clone(x.OpenAPI.Handlers), x.SwaggerUIHandler)...)
+			if assetsPath := x.OpenAPI.GetSwaggerUIAssetsPath(); assetsPath != "" {
+				x.ServeStatic(assetsPath, host.SwaggerUIAssets())
+			}
+		}
+	}
+}
+
+// Use 添加标准库中间件
+func (x *NetHttpWebHost) Use(middlewares ...func(http.Handler) http.Handler) {
+	x.Middlewares = append(x.Middlewares, middlewares...)
+}
+
+func (x *NetHttpWebHost) BuildNativeHandler(routeKey string, handlers ...host.RequestHandler) http.HandlerFunc {
+	if len(handlers) == 0 {
+		slog.Fatal("handlers are missing")
+	}
+
+	// 注册全局中间件
+	handlers = x.BuildHandlerChain(handlers)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		newCtx := NewNetHttpContext(w, r, x.SessionManager, x.CookieEncryptor, handlers...).(*NetHttpContext)
+		newCtx.SetItem(host.Ctx_RouteKey, routeKey)
+		newCtx.shutdown = x.ShuttingDown()
+		defer func() {
+			newCtx.Reset()
+			_ctxPool.Put(newCtx)
+		}()
+		handlers[0](newCtx) // 开始执行第一个Handler
+		newCtx.flush()
+	}
+}
+
+// handle 注册到Mux，path使用fasthttp/router风格
+func (x *NetHttpWebHost) handle(method, path string, handler http.Handler) {
+	if x.patterns == nil {
+		x.patterns = make(map[string]string)
+		x.methods = make(map[string]struct{})
+	}
+	x.methods[method] = struct{}{}
+	for _, p := range convertPath(path) {
+		key := method + " " + p.pattern
+		if existing, ok := x.patterns[key]; ok {
+			slog.Fatal("route '" + method + path + "' conflicts with route '" + existing + "'")
+		}
+		x.patterns[key] = method + path
+		x.Mux.Handle(key, p.handler(handler))
+	}
+}
+
+// addRoute 注册经过全局中间件的路由并记录到路由表
+func (x *NetHttpWebHost) addRoute(method, path, routeKey string, handlers []host.RequestHandler) {
+	x.RecordRoute(method, path, routeKey, handlers)
+	x.handle(method, path, x.BuildNativeHandler(routeKey, handlers...))
+}
+
+// handleNative 注册不经过全局中间件的原生路由并记录到路由表
+func (x *NetHttpWebHost) handleNative(method, path, name string, handler http.Handler) {
+	x.RecordNativeRoute(method, path, name)
+	x.handle(method, path, handler)
+}
+
+func (x *NetHttpWebHost) NewFSHandler(root string, stripSlashes int) host.RequestHandler {
+	fs := http.FileServer(http.Dir(root))
+	return func(ctx host.IHttpContext) {
+		c := ctx.(*NetHttpContext)
+		r := c.GetRequest()
+		path := r.URL.Path
+		for i := 0; i < stripSlashes && len(path) > 0; i++ {
+			path = path[1:]
+			if n := strings.IndexByte(path, '/'); n >= 0 {
+				path = path[n:]
+			} else {
+				path = "/"
+			}
+		}
+
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = path
+		r2.URL.RawPath = ""
+		fs.ServeHTTP(c, r2)
+	}
+}
+
+func (x *NetHttpWebHost) Group(prefix string, handlers ...host.RequestHandler) host.IRouteGroup {
+	return host.NewRouteGroup(x, prefix, handlers...)
+}
+
+func (x *NetHttpWebHost) GET(path string, handlers ...host.RequestHandler) {
+	x.addRoute(http.MethodGet, path, path, handlers)
+}
+func (x *NetHttpWebHost) POST(path string, handlers ...host.RequestHandler) {
+	x.addRoute(http.MethodPost, path, path, handlers)
+}
+func (x *NetHttpWebHost) PUT(path string, handlers ...host.RequestHandler) {
+	x.addRoute(http.MethodPut, path, path, handlers)
+}
+func (x *NetHttpWebHost) PATCH(path string, handlers ...host.RequestHandler) {
+	x.addRoute(http.MethodPatch, path, path, handlers)
+}
+func (x *NetHttpWebHost) DELETE(path string, handlers ...host.RequestHandler) {
+	x.addRoute(http.MethodDelete, path, path, handlers)
+}
+func (x *NetHttpWebHost) OPTIONS(path string, handlers ...host.RequestHandler) {
+	x.addRoute(http.MethodOptions, path, path, handlers)
+}
+
+func (x *NetHttpWebHost) WS(path string, handler host.WebSocketHandler, handlers ...host.RequestHandler) {
+	x.GET(path, append(handlers, func(ctx host.IHttpContext) {
+		// 与SSE一致，在处理链执行完毕后（flush时）升级
+		c := ctx.(*NetHttpContext)
+		c.wsHandler = handler
+		c.wsUpgrader = x.WSUpgrader
+	})...)
+}
+
+func (x *NetHttpWebHost) newWSUpgrader() *websocket.Upgrader {
+	r := &websocket.Upgrader{
+		HandshakeTimeout: x.WebSocket.GetHandshakeTimeout(),
+	}
+	if x.WebSocket != nil {
+		r.ReadBufferSize = x.WebSocket.ReadBufferSize
+		r.WriteBufferSize = x.WebSocket.WriteBufferSize
+		r.Subprotocols = x.WebSocket.Subprotocols
+		r.EnableCompression = x.WebSocket.EnableCompression
+		if len(x.WebSocket.AllowedOrigins) > 0 {
+			r.CheckOrigin = func(r *http.Request) bool {
+				allowed, _ := x.WebSocket.CheckOrigin(r.Header.Get("Origin"))
+				return allowed
+			}
+		}
+	}
+	return r
+}
+
+func (x *NetHttpWebHost) ServeFiles(webPath, physiblePath string) {
+	if !strings.HasSuffix(webPath, _suffix) {
+		panic("path must end with " + _suffix + " in path '" + webPath + "'")
+	}
+
+	prefix := strings.TrimSuffix(webPath, _suffix)
+	fileHandler := http.StripPrefix(prefix, http.FileServer(http.Dir(physiblePath)))
+	if x.Compression == nil || !x.Compression.Precompressed {
+		x.handleNative(http.MethodGet, webPath, "ServeFiles "+physiblePath, fileHandler)
+		return
+	}
+
+	// 先尝试预压缩文件
+	fsys := os.DirFS(physiblePath)
+	x.handleNative(http.MethodGet, webPath, "ServeFiles "+physiblePath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filepath := r.PathValue(_filepath)
+		if filepath == "" || strings.HasSuffix(filepath, "/") {
+			filepath += x.IndexName
+		}
+		if x.servePrecompressed(w, r, fsys, filepath) {
+			return
+		}
+		fileHandler.ServeHTTP(w, r)
+	}))
+}
+
+// servePrecompressed 客户端支持时返回filepath.br/filepath.gz
+func (x *NetHttpWebHost) servePrecompressed(w http.ResponseWriter, r *http.Request, fsys iofs.FS, filepath string) bool {
+	w.Header().Add(host.Header_Vary, host.Header_AcceptEncoding)
+	if !iofs.ValidPath(filepath) {
+		return false
+	}
+
+	file, encoding, err := x.Compression.OpenStaticFile(fsys, filepath, r.Header.Get(host.Header_AcceptEncoding))
+	if err != nil || encoding == "" {
+		if file != nil {
+			file.Close()
+		}
+		return false
+	}
+	defer file.Close()
+
+	if cType := mime.TypeByExtension(fp.Ext(filepath)); cType != "" {
+		w.Header().Set("Content-Type", cType)
+	}
+	w.Header().Set(host.Header_ContentEncoding, encoding)
+	_, err = io.Copy(w, file)
+	u.LogError(err)
+	return true
+}
+
+func (x *NetHttpWebHost) ServeEmbedFiles(webPath, physiblePath string, emd embed.FS) {
+	fsys, err := iofs.Sub(emd, physiblePath)
+	u.LogFatal(err)
+	x.serveStatic(webPath, fsys, "ServeEmbedFiles "+physiblePath)
+}
+
+func (x *NetHttpWebHost) ServeStatic(webPath string, fsys iofs.FS) {
+	x.serveStatic(webPath, fsys, "ServeStatic")
+}
+
+func (x *NetHttpWebHost) serveStatic(webPath string, fsys iofs.FS, name string) {
+	if !strings.HasSuffix(webPath, _suffix) {
+		panic("path must end with " + _suffix + " in path '" + webPath + "'")
+	}
+
+	prefix := strings.TrimSuffix(webPath, _suffix)
+	handler := http.StripPrefix(prefix, host.NewStaticFileServer(fsys, x.IndexName, x.Static, x.Compression))
+	x.handleNative(http.MethodGet, webPath, name, handler)
+	x.handleNative(http.MethodHead, webPath, name, handler)
+}
+
+// ServeHTTP http.Handler
+func (x *NetHttpWebHost) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		if err := recover(); err != nil {
+			if x.PanicHandler != nil {
+				newCtx := NewNetHttpContext(w, r, x.SessionManager, x.CookieEncryptor).(*NetHttpContext)
+				defer func() {
+					newCtx.Reset()
+					_ctxPool.Put(newCtx)
+				}()
+				newCtx.SetItem(host.Ctx_Panic, err)
+				newCtx.SetItem(host.Ctx_RequestID, w.Header().Get(host.Header_RequestID))
+				x.PanicHandler(newCtx)
+				newCtx.flush()
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			slog.Errorf("[%s] %s -> %s", w.Header().Get(host.Header_RequestID), r.URL.String(), err)
+		}
+	}()
+
+	r.Body = http.MaxBytesReader(w, r.Body, x.MaxRequestBodySize)
+	if x.preflightHandler != nil && host.IsPreflightRequest(r) {
+		if _, pattern := x.Mux.Handler(r); pattern == "" && x.hasRoute(r) {
+			x.preflightHandler(w, r)
+			return
+		}
+	}
+	x.Mux.ServeHTTP(w, r)
+}
+
+// hasRoute 是否有任意方法的路由匹配r的路径，与fasthttp/router一样只为存在的路径响应预检请求
+func (x *NetHttpWebHost) hasRoute(r *http.Request) bool {
+	probe := *r
+	for method := range x.methods {
+		probe.Method = method
+		if _, pattern := x.Mux.Handler(&probe); pattern != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (x *NetHttpWebHost) Run() error {
+	return x.RunContext(context.Background())
+}
+
+func (x *NetHttpWebHost) RunContext(ctx context.Context) error {
+	////////// 注册Actions到路由
+	x.AddConfiguredActions()
+	for _, v := range x.Actions {
+		x.RegisterActionsToRouter(v)
+	}
+	x.CheckRouteKeys()
+
+	var handler http.Handler = x
+	if x.HttpHandler != nil {
+		handler = x.BuildNativeHandler("General", x.HttpHandler)
+	}
+	for i := len(x.Middlewares) - 1; i >= 0; i-- {
+		handler = x.Middlewares[i](handler)
+	}
+	if x.H2C {
+		handler = h2c.NewHandler(handler, new(http2.Server))
+	}
+
+	s := &http.Server{
+		Handler:        handler,
+		MaxHeaderBytes: x.MaxHeaderBytes,
+	}
+	x.server.Store(s)
+
+	////////// 开始Serve
+	tlsConfig, err := x.BuildTLSConfig("h2", "http/1.1")
+	if err != nil {
+		return err
+	}
+	ln, err := x.Listen("tcp")
+	if err != nil {
+		return err
+	}
+
+	////////// 指标服务，监听成功后再启动，失败时关闭已打开的监听
+	if err := x.StartMetricsServer(); err != nil {
+		ln.Close()
+		return err
+	}
+	if tlsConfig != nil {
+		s.TLSConfig = tlsConfig
+		ln = tls.NewListener(ln, tlsConfig)
+		slog.Infof("Listening on %s (TLS)", x.ListenAddr)
+	} else {
+		slog.Infof("Listening on %s", x.ListenAddr)
+	}
+
+	return x.RunUntilSignal(ctx, func() error {
+		err := s.Serve(ln)
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return serr.WithStack(err)
+	}, x.Shutdown)
+}
+
+// Shutdown 停止接收新连接，等待处理中的请求完成（最多timeout），然后执行关闭钩子
+func (x *NetHttpWebHost) Shutdown(timeout time.Duration) error {
+	return x.GracefulShutdown(timeout, func(ctx context.Context) error {
+		s := x.server.Load()
+		if s == nil {
+			return nil
+		}
+		return serr.WithStack(s.Shutdown(ctx))
+	})
+}
+
+func (x *NetHttpWebHost) RegisterActionsToRouter(action *host.Action) {
+	method, path := host.SplitRoute(action.Route)
+
+	switch method {
+	case http.MethodPost, http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		x.handle(method, path, x.BuildNativeHandler(action.RouteKey, action.Handlers...))
+	default:
+		panic("does not support method " + method)
+	}
+}
@@ -0,0 +1,118 @@
+package snethttp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// 未设置过期时间的Session在服务端保留的时长
+const _defaultSessionStoreExpiration = 24 * time.Hour
+
+type (
+	// ISessionStore Session存储
+	ISessionStore interface {
+		Get(id string) map[string]string
+		Save(id string, values map[string]string, expiration time.Duration) error
+		Destroy(id string) error
+	}
+
+	SessionManager struct {
+		Store      ISessionStore
+		CookieName string
+		// Expiration <= 0 时，Cookie随浏览器关闭失效
+		Expiration time.Duration
+	}
+)
+
+func NewSessionManager(store ISessionStore, cookieName string, expiration time.Duration) *SessionManager {
+	return &SessionManager{
+		Store:      store,
+		CookieName: cookieName,
+		Expiration: expiration,
+	}
+}
+
+func (x *SessionManager) getStoreExpiration() time.Duration {
+	if x.Expiration > 0 {
+		return x.Expiration
+	}
+	return _defaultSessionStoreExpiration
+}
+
+func newSessionID() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type memorySession struct {
+	values   map[string]string
+	expireAt time.Time
+}
+
+// MemorySessionStore 内存Session存储
+type MemorySessionStore struct {
+	sessions map[string]*memorySession
+	lock     sync.RWMutex
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string]*memorySession),
+	}
+}
+
+// Get 返回Session数据的拷贝，不存在或已过期返回nil
+func (x *MemorySessionStore) Get(id string) map[string]string {
+	x.lock.RLock()
+	s, ok := x.sessions[id]
+	x.lock.RUnlock()
+
+	if !ok {
+		return nil
+	}
+	if time.Now().After(s.expireAt) {
+		x.Destroy(id)
+		return nil
+	}
+
+	r := make(map[string]string, len(s.values))
+	for k, v := range s.values {
+		r[k] = v
+	}
+	return r
+}
+
+func (x *MemorySessionStore) Save(id string, values map[string]string, expiration time.Duration) error {
+	copied := make(map[string]string, len(values))
+	for k, v := range values {
+		copied[k] = v
+	}
+
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	now := time.Now()
+	x.sessions[id] = &memorySession{
+		values:   copied,
+		expireAt: now.Add(expiration),
+	}
+
+	// 顺便清理过期Session
+	for k, v := range x.sessions {
+		if now.After(v.expireAt) {
+			delete(x.sessions, k)
+		}
+	}
+
+	return nil
+}
+
+func (x *MemorySessionStore) Destroy(id string) error {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	delete(x.sessions, id)
+	return nil
+}
@@ -0,0 +1,58 @@
+package snethttp
+
+import (
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/syncfuture/go/slog"
+	"github.com/syncfuture/go/u"
+	"github.com/valyala/fasthttp"
+)
+
+// NewFastHttpHandlerAdaptor 将fasthttp.RequestHandler适配为http.Handler
+// 用于复用只提供fasthttp实现的处理器，例如oauth2go.TokenHost
+func NewFastHttpHandlerAdaptor(h fasthttp.RequestHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if u.LogError(err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var req fasthttp.Request
+		req.Header.SetMethod(r.Method)
+		req.SetRequestURI(r.URL.RequestURI())
+		req.Header.SetHost(r.Host)
+		if r.TLS != nil {
+			req.URI().SetScheme("https")
+		}
+		for k, values := range r.Header {
+			for _, v := range values {
+				req.Header.Add(k, v)
+			}
+		}
+		req.SetBody(body)
+
+		remoteAddr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr)
+		if err != nil {
+			remoteAddr = nil
+		}
+
+		var ctx fasthttp.RequestCtx
+		ctx.Init(&req, remoteAddr, slog.DebugLogger)
+		h(&ctx)
+
+		header := w.Header()
+		ctx.Response.Header.VisitAll(func(k, v []byte) {
+			switch key := string(k); key {
+			case fasthttp.HeaderContentLength, fasthttp.HeaderConnection, fasthttp.HeaderServer:
+			default:
+				header.Add(key, string(v))
+			}
+		})
+		w.WriteHeader(ctx.Response.StatusCode())
+		_, err = w.Write(ctx.Response.Body())
+		u.LogError(err)
+	}
+}
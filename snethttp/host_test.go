@@ -0,0 +1,72 @@
+package snethttp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncfuture/host"
+	"github.com/syncfuture/host/internal/hostsuite"
+)
+
+func TestWebHostSuite(t *testing.T) {
+	hostsuite.Run(t, newSuiteHost)
+}
+
+func TestConvertPath(t *testing.T) {
+	cases := map[string][]string{
+		"/":                       {"/{$}"},
+		"/users/":                 {"/users/{$}"},
+		"/users/{id}":             {"/users/{_0}"},
+		"/users/{id:[0-9]+}/edit": {"/users/{_0}/edit"},
+		"/static/{filepath:*}":    {"/static/{_0...}"},
+		"/opt/{name?}":            {"/opt", "/opt/{_0}"},
+		"/{name?:[a-z]{2}}":       {"/{$}", "/{_0}"},
+	}
+	for in, expected := range cases {
+		var patterns []string
+		for _, p := range convertPath(in) {
+			patterns = append(patterns, p.pattern)
+		}
+		assert.Equal(t, expected, patterns, in)
+	}
+
+	paths := convertPath("/users/{id:[0-9]+}/{name}")
+	assert.Equal(t, []string{"id", "name"}, paths[0].params)
+	assert.Equal(t, "^(?:[0-9]+)$", paths[0].constraints[0].String())
+	assert.Nil(t, paths[0].constraints[1])
+}
+
+func TestWebHostTLS(t *testing.T) {
+	hostsuite.RunTLS(t, func(listenAddr string, options *host.TLSOptions) host.IWebHost {
+		h := new(NetHttpWebHost)
+		h.ListenAddr = listenAddr
+		h.TLS = options
+		h.buildNetHttpWebHost()
+		return h
+	})
+}
+
+func TestWebHostCSRFDoubleSubmit(t *testing.T) {
+	hostsuite.RunCSRFDoubleSubmit(t, func(listenAddr string, options *host.CSRFOptions, secureCookie *host.SecureCookieHost) host.IWebHost {
+		h := new(NetHttpWebHost)
+		h.ListenAddr = listenAddr
+		h.CSRF = options
+		h.CookieEncryptor = secureCookie.GetCookieEncryptor()
+		h.buildNetHttpWebHost()
+		return h
+	})
+}
+
+func TestInMemoryHost(t *testing.T) {
+	hostsuite.RunInMemory(t, newSuiteHost)
+}
+
+func newSuiteHost(listenAddr string, configure func(*host.BaseWebHost)) host.IWebHost {
+	h := new(NetHttpWebHost)
+	h.ListenAddr = listenAddr
+	if configure != nil {
+		configure(&h.BaseWebHost)
+	}
+	h.buildNetHttpWebHost()
+	return h
+}
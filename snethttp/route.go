@@ -0,0 +1,113 @@
+package snethttp
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/syncfuture/go/slog"
+)
+
+// muxPath 路由路径对应的ServeMux模式，模式中的参数按位置命名，匹配后检查正则约束并以原名称设置
+type muxPath struct {
+	pattern string
+	params  []string
+	// constraints 与params对应，nil表示无约束
+	constraints []*regexp.Regexp
+}
+
+// convertPath 将fasthttp/router风格的路径（{name}、{name?}、{name:regex}、{name?:regex}、{name:*}）转为http.ServeMux模式，
+// 与fasthttp/router一样，可选参数展开为不含该参数和含该参数的路径，ServeMux无法表示的路径（参数不占整段等）直接终止
+// 模式中的参数按位置命名，因此只有参数名或约束不同的路由会得到相同的模式，与fasthttp/router一样视为冲突
+func convertPath(path string) []*muxPath {
+	var r []*muxPath
+	current := new(muxPath)
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, segment := range segments {
+		if !strings.ContainsAny(segment, "{}") {
+			current.pattern += "/" + segment
+			continue
+		}
+		if segment[0] != '{' || segment[len(segment)-1] != '}' || strings.Count(segment, "{") != strings.Count(segment, "}") {
+			slog.Fatal("route '" + path + "': a parameter must take a whole path segment on the net/http backend")
+		}
+
+		name, regex, _ := strings.Cut(segment[1:len(segment)-1], ":")
+		name, optional := strings.CutSuffix(name, "?")
+		if name == "" {
+			slog.Fatal("route '" + path + "': parameter name is missing")
+		}
+		if optional {
+			r = append(r, current.finish())
+			current = current.clone()
+		}
+
+		wildcard := "_" + strconv.Itoa(len(current.params))
+		var constraint *regexp.Regexp
+		switch regex {
+		case "*":
+			if i != len(segments)-1 {
+				slog.Fatal("route '" + path + "': catch-all parameter must be the last segment")
+			}
+			wildcard += "..."
+		case "":
+		default:
+			var err error
+			if constraint, err = regexp.Compile("^(?:" + regex + ")$"); err != nil {
+				slog.Fatal("route '" + path + "': invalid parameter regex: " + err.Error())
+			}
+		}
+		current.pattern += "/{" + wildcard + "}"
+		current.params = append(current.params, name)
+		current.constraints = append(current.constraints, constraint)
+	}
+	return append(r, current.finish())
+}
+
+// finish 返回完成的模式，ServeMux中以/结尾的模式会匹配所有子路径，因此加上{$}
+func (x *muxPath) finish() *muxPath {
+	r := x.clone()
+	switch {
+	case r.pattern == "":
+		r.pattern = "/{$}"
+	case strings.HasSuffix(r.pattern, "/"):
+		r.pattern += "{$}"
+	}
+	return r
+}
+
+func (x *muxPath) clone() *muxPath {
+	return &muxPath{
+		pattern:     x.pattern,
+		params:      slices.Clone(x.params),
+		constraints: slices.Clone(x.constraints),
+	}
+}
+
+// bind 检查正则约束，通过时以原名称设置路由参数
+func (x *muxPath) bind(r *http.Request) bool {
+	values := make([]string, len(x.params))
+	for i, constraint := range x.constraints {
+		values[i] = r.PathValue("_" + strconv.Itoa(i))
+		if constraint != nil && !constraint.MatchString(values[i]) {
+			return false
+		}
+	}
+	for i, name := range x.params {
+		r.SetPathValue(name, values[i])
+	}
+	return true
+}
+
+// handler 正则约束不匹配时返回404
+func (x *muxPath) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !x.bind(r) {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}